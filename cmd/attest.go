@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/rancherlabs/slsactl/pkg/attestation"
+)
+
+const attestf = `usage:
+    %[1]s attest <IMAGE> --sbom <FILE> --key <KEY_FILE>
+    %[1]s attest <IMAGE> --provenance <FILE> --key <KEY_FILE>
+    %[1]s attest --source referrer --platform linux/arm64 --sbom sbom.spdx.json --key cosign.key rancher/foo:v1
+
+<FILE> is an SPDX or CycloneDX JSON document for --sbom, and a SLSA v1 or v0.2
+predicate for --provenance, or - to read it from stdin. <KEY_FILE> is a cosign
+key pair, encrypted with the password set by COSIGN_PASSWORD, or an unencrypted
+PKCS #8, PKCS #1 or EC private key in PEM.
+`
+
+// passwordEnv holds the password of encrypted keys, as for cosign.
+const passwordEnv = "COSIGN_PASSWORD"
+
+var errAttestKind = errors.New("exactly one of --sbom or --provenance must be set")
+
+func attestCmd(args []string) error {
+	var sbomPath, provenancePath, keyPath string
+	var opts attestation.AttestOptions
+	f := flag.NewFlagSet("", flag.ContinueOnError)
+	f.StringVar(&sbomPath, "sbom", "", "The SPDX or CycloneDX JSON SBOM to attest, or - for stdin.")
+	f.StringVar(&provenancePath, "provenance", "", "The SLSA provenance predicate to attest, or - for stdin.")
+	f.StringVar(&keyPath, "key", "", "The private key signing the attestation.")
+	f.StringVar(&opts.Source, "source", attestation.SourceCosign, "Where the attestation is attached. Supported values are cosign (default), to the .att tag as cosign attest does, and referrer, through the OCI referrers API.")
+	f.StringVar(&opts.Platform, "platform", "", "The platform image attested within multi-platform images. Defaults to the image index.")
+
+	// Flags may follow the image, as in: attest rancher/foo:v1 --sbom sbom.json.
+	pos, err := parseInterspersed(f, args)
+	if err != nil {
+		return err
+	}
+	if len(pos) != 1 || keyPath == "" {
+		return showAttestUsage()
+	}
+
+	kind, path := attestation.KindSBOM, sbomPath
+	switch {
+	case sbomPath != "" && provenancePath != "", sbomPath == "" && provenancePath == "":
+		return errAttestKind
+	case provenancePath != "":
+		kind, path = attestation.KindProvenance, provenancePath
+	}
+
+	predicate, err := readPredicate(path)
+	if err != nil {
+		return err
+	}
+	predicateType, err := attestation.PredicateType(kind, predicate)
+	if err != nil {
+		return fmt.Errorf("cannot attest %s: %w", path, err)
+	}
+
+	key, err := os.ReadFile(keyPath)
+	if err != nil {
+		return fmt.Errorf("failed to read key: %w", err)
+	}
+	signer, err := attestation.LoadSigner(key, []byte(os.Getenv(passwordEnv)))
+	if err != nil {
+		return err
+	}
+
+	digest, err := attestation.Attest(context.Background(), pos[0], predicateType, predicate, signer, opts)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("attested %s with %s\n", digest, predicateType)
+	return nil
+}
+
+// readPredicate returns the content of path, or of stdin when path is -.
+func readPredicate(path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
+
+func showAttestUsage() error {
+	fmt.Printf(attestf, exeName())
+	return errUsage
+}
@@ -1,26 +1,41 @@
 package cmd
 
 import (
+	"context"
 	"encoding/json"
-	"errors"
 	"flag"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
+	"path/filepath"
 	"strings"
 
-	"github.com/google/go-containerregistry/pkg/name"
-	v1 "github.com/google/go-containerregistry/pkg/v1"
-	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/rancherlabs/slsactl/internal/sbom"
+	"github.com/rancherlabs/slsactl/internal/vex"
+	"github.com/rancherlabs/slsactl/pkg/attestation"
 )
 
 const (
 	downloadf = `usage:
     %[1]s download provenance <IMAGE>
     %[1]s download sbom <IMAGE>
+    %[1]s download vex <IMAGE>
+    %[1]s download all --output-dir <DIR> <IMAGE>
+
+<IMAGE> may also be a local image: oci:<DIR>[:<REF>], docker-archive:<TAR>[:<REF>]
+or docker-daemon:<REF>. For sbom, it may also be a directory, such as a root
+filesystem, a file or archive, or an OCI layout tarball: dir:<DIR>, file:<FILE>
+or oci-archive:<TAR>.
 `
 	provenanceValue = "provenance"
 	sbomValue       = "sbom"
+	vexValue        = "vex"
+	allValue        = "all"
+
+	// intotoFormat outputs the attestations as signed, in the in-toto
+	// JSON Lines format.
+	intotoFormat = "intoto"
 )
 
 func downloadCmd(args []string) error {
@@ -31,277 +46,208 @@ func downloadCmd(args []string) error {
 	}
 
 	if len(f.Args()) < 2 {
-		showDownloadUsage()
+		return showDownloadUsage()
 	}
 
 	var format string
 	var platform string
+	var raw bool
 	img := f.Arg(f.NArg() - 1)
 	if f.Arg(0) == provenanceValue {
-		f.StringVar(&format, "format", "slsav0.2", "The format for the Provenance output. Supported values are slsav0.2 (default) and slsav1.")
-		f.StringVar(&platform, "platform", "linux/amd64", "The target platform for the container image. Most supported platforms are linux/amd64 and linux/arm64.")
+		f.StringVar(&format, "format", "slsav0.2", "The format for the Provenance output. Supported values are slsav0.2 (default), slsav1 and intoto.")
+		f.StringVar(&platform, "platform", "linux/amd64", "The target platform for the container image, such as linux/amd64, linux/arm/v7 or windows/amd64:10.0.20348. Use all for every platform.")
+		f.BoolVar(&raw, "raw", false, "Output the full in-toto statements, or their DSSE envelopes when signed. Same as --format intoto.")
 
 		err := f.Parse(args[1:])
 		if err != nil {
 			return err
 		}
 
+		if raw || format == intotoFormat {
+			return rawCmd(img, attestation.KindProvenance, platform)
+		}
 		return provenanceCmd(img, format, platform)
 	}
 
 	if f.Arg(0) == sbomValue {
-		f.StringVar(&format, "format", "spdxjson", "The format for the SBOM output. Supported values are spdxjson (default) and cyclonedxjson.")
-		f.StringVar(&platform, "platform", "linux/amd64", "The target platform for the container image. Most supported platforms are linux/amd64 and linux/arm64.")
+		f.StringVar(&format, "format", "spdxjson", "The format for the SBOM output. Supported values are spdxjson (default), spdxtagvalue, spdx3json, cyclonedxjson, cyclonedxxml, syftjson and intoto. Append @version to select a version, e.g. cyclonedxjson@1.5.")
+		f.StringVar(&platform, "platform", "linux/amd64", "The target platform for the container image, such as linux/amd64, linux/arm/v7 or windows/amd64:10.0.20348. Use all for every platform.")
+		f.BoolVar(&raw, "raw", false, "Output the full in-toto statements, or their DSSE envelopes when signed. Same as --format intoto.")
+
+		var enrich bool
+		f.BoolVar(&enrich, "enrich", false, "Add the image source repository, commit, workflow ref and build trigger to the spdxjson or cyclonedxjson SBOM, taken from the signing certificate or the provenance.")
+
+		// Used when the image has no SBOM attestation and it is generated on demand.
+		var opts sbom.Options
+		var catalogers, digests string
+		f.StringVar(&catalogers, "catalogers", "", "Comma-separated catalogers to add (+name), remove (-name) or select (name or tag) when generating the SBOM, such as +sbom-cataloger,-rpm.")
+		f.StringVar(&opts.Scope, "scope", "squashed", "The layers to catalog when generating the SBOM: squashed (default) or all-layers.")
+		f.StringVar(&opts.Files, "files", "owned-by-package", "The files whose metadata is included when generating the SBOM: none, owned-by-package (default) or all.")
+		f.StringVar(&digests, "file-digests", "sha256", "Comma-separated digest algorithms of the files included when generating the SBOM, such as sha1,sha256.")
+		generationFlags(f, &opts)
 
 		err := f.Parse(args[1:])
 		if err != nil {
 			return err
 		}
 
-		return sbomCmd(img, format, platform)
-	}
-
-	showDownloadUsage()
-	return nil
-}
-
-func showDownloadUsage() {
-	fmt.Printf(downloadf, exeName())
-	os.Exit(1)
-}
-
-func writeContent(img, format string, w io.Writer) error {
-	ref, err := name.ParseReference(img)
-	if err != nil {
-		return fmt.Errorf("failed to parse image reference: %w", err)
+		if raw || format == intotoFormat {
+			return rawCmd(img, attestation.KindSBOM, platform)
+		}
+		opts.Catalogers = splitList(catalogers)
+		opts.FileDigests = splitList(digests)
+		return sbomCmd(img, format, platform, enrich, opts)
 	}
 
-	desc, err := remote.Get(ref)
-	if err != nil {
-		return fmt.Errorf("failed to fetch image descriptor: %w", err)
-	}
+	if f.Arg(0) == vexValue {
+		f.BoolVar(&raw, "raw", false, "Output the full in-toto statements, or their DSSE envelopes when signed.")
 
-	var result any
-	if desc.MediaType.IsIndex() {
-		idx, err := desc.ImageIndex()
+		err := f.Parse(args[1:])
 		if err != nil {
-			return fmt.Errorf("failed to get image index: %w", err)
+			return err
 		}
 
-		result, err = extractFromIndex(idx, format)
-		if err != nil {
-			return fmt.Errorf("failed to extract from index: %w", err)
-		}
-	} else {
-		img, err := desc.Image()
-		if err != nil {
-			return fmt.Errorf("failed to get image: %w", err)
+		if raw {
+			return rawCmd(img, attestation.KindVEX, attestation.PlatformAll)
 		}
+		return vexDownloadCmd(img)
+	}
+
+	if f.Arg(0) == allValue {
+		var outputDir string
+		f.StringVar(&outputDir, "output-dir", ".", "The directory to write the attestations of every platform to.")
 
-		result, err = extractFromImage(img, format)
+		// Flags may follow the image, as in: all <IMAGE> --output-dir <DIR>.
+		pos, err := parseInterspersed(f, args[1:])
 		if err != nil {
-			return fmt.Errorf("failed to extract from image: %w", err)
+			return err
+		}
+		if len(pos) != 1 {
+			return showDownloadUsage()
 		}
-	}
-
-	data, err := json.Marshal(result)
-	if err != nil {
-		return fmt.Errorf("failed to marshal result: %w", err)
-	}
 
-	_, err = w.Write(data)
-	if err != nil {
-		return err
+		return downloadAllCmd(pos[0], outputDir)
 	}
 
-	_, err = fmt.Fprintln(w)
-	return err
+	return showDownloadUsage()
 }
 
-//nolint:gocognit
-func extractFromIndex(idx v1.ImageIndex, attestationType string) (any, error) {
-	manifest, err := idx.IndexManifest()
+// rawCmd prints the attestations of kind for platform as in-toto JSON Lines,
+// keeping the subject digests and signatures of each statement.
+func rawCmd(img, kind, platform string) error {
+	atts, err := attestation.List(context.Background(), img, attestation.Options{})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get index manifest: %w", err)
+		return err
 	}
 
-	result := make(map[string]any)
-	for _, desc := range manifest.Manifests {
-		if isAttestationManifest(desc) {
-			attestation, platform, err := extractAttestation(idx, desc, attestationType)
-			if err != nil {
-				continue
-			}
-			if attestation != nil && platform != "" {
-				result[platform] = attestation
-			}
-		}
+	matched := attestation.Filter(atts, kind)
+	if len(matched) == 0 {
+		return fmt.Errorf("%w: %s", attestation.ErrNoAttestations, kind)
 	}
 
-	// Check for single attestation without platform.
-	for _, desc := range manifest.Manifests {
-		if isAttestationManifest(desc) {
-			attestation, _, err := extractAttestation(idx, desc, attestationType)
-			if err == nil && attestation != nil {
-				if singleAttest, ok := attestation.(map[string]any); ok {
-					if slsa, ok := singleAttest["SLSA"]; ok && attestationType == provenanceValue {
-						result["SLSA"] = slsa
-					} else if spdx, ok := singleAttest["SPDX"]; ok && attestationType == sbomValue {
-						result["SPDX"] = spdx
-					}
-				}
-			}
+	if !strings.EqualFold(platform, attestation.PlatformAll) {
+		var ok bool
+		matched, ok = attestation.ByPlatform(matched).Lookup(platform)
+		if !ok {
+			return fmt.Errorf("platform not supported: %q", platform)
 		}
 	}
 
-	if len(result) == 0 {
-		return nil, fmt.Errorf("no %s attestations found", attestationType)
-	}
-
-	return result, nil
+	return attestation.WriteInToto(os.Stdout, matched)
 }
 
-// extractFromImage extracts provenance or SBOM from a single-platform image.
-func extractFromImage(img v1.Image, format string) (any, error) {
-	config, err := img.ConfigFile()
+// vexDownloadCmd prints the OpenVEX attestations of img, merged into a
+// single document when there are several.
+func vexDownloadCmd(img string) error {
+	docs, err := vex.ForImage(img)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get image config: %w", err)
+		return err
 	}
-
-	manifest, err := img.Manifest()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get manifest: %w", err)
+	if len(docs) == 0 {
+		return fmt.Errorf("%w: %s", attestation.ErrNoAttestations, attestation.KindVEX)
 	}
 
-	// BuildKit may store attestation references in annotations.
-	if manifest.Annotations != nil {
-		for key, value := range manifest.Annotations {
-			if strings.Contains(key, provenanceValue) && format == provenanceValue {
-				var result any
-				err := json.Unmarshal([]byte(value), &result)
-				if err == nil {
-					return result, nil
-				}
-			}
-			if strings.Contains(key, sbomValue) && format == sbomValue {
-				var result any
-				err := json.Unmarshal([]byte(value), &result)
-				if err == nil {
-					return result, nil
-				}
-			}
-		}
+	doc := docs[0]
+	if len(docs) > 1 {
+		doc = vex.Merge(docs[0].Author, docs)
 	}
-
-	return nil, fmt.Errorf("no attestation data found in image (config: %+v)", config.Config.Labels)
+	return printOutput(os.Stdout, doc)
 }
 
-func isAttestationManifest(desc v1.Descriptor) bool {
-	if desc.MediaType == "application/vnd.oci.image.manifest.v1+json" ||
-		desc.MediaType == "application/vnd.docker.distribution.manifest.v2+json" {
-		if desc.Annotations != nil {
-			if refType, ok := desc.Annotations["vnd.docker.reference.type"]; ok {
-				return refType == "attestation-manifest"
-			}
-		}
-	}
-	return false
-}
-
-func extractAttestation(idx v1.ImageIndex, desc v1.Descriptor, attestationType string) (any, string, error) {
-	img, err := idx.Image(desc.Digest)
+func downloadAllCmd(img, outputDir string) error {
+	m, err := attestation.Export(context.Background(), img, outputDir, attestation.Options{})
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to get attestation image: %w", err)
+		return fmt.Errorf("failed to download attestations: %w", err)
 	}
 
-	layers, err := img.Layers()
-	if err != nil {
-		return nil, "", fmt.Errorf("failed to get layers: %w", err)
+	for _, f := range m.Files {
+		fmt.Println(filepath.Join(outputDir, filepath.FromSlash(f.Path)))
 	}
+	fmt.Println(filepath.Join(outputDir, attestation.ManifestFile))
 
-	if len(layers) == 0 {
-		return nil, "", errors.New("no layers in attestation manifest")
-	}
+	return nil
+}
 
-	// The "vnd.docker.reference.digest" annotation contains the digest of the attested manifest.
-	platform := ""
-	if desc.Annotations != nil {
-		if refDigest, ok := desc.Annotations["vnd.docker.reference.digest"]; ok {
-			var err error
-			platform, err = getDigestsPlatform(idx, refDigest)
-			if err != nil && desc.Platform != nil {
-				platform = fmt.Sprintf("%s/%s", desc.Platform.OS, desc.Platform.Architecture)
-			}
+// splitList splits a comma-separated flag value, dropping empty items.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
 		}
 	}
+	return items
+}
 
-	if platform == "" && desc.Platform != nil {
-		platform = fmt.Sprintf("%s/%s", desc.Platform.OS, desc.Platform.Architecture)
-	}
-
-	for _, layer := range layers {
-		layerReader, err := layer.Uncompressed()
-		if err != nil {
-			continue
-		}
-
-		var attestation map[string]any
-		err = json.NewDecoder(layerReader).Decode(&attestation)
+// parseInterspersed parses the flags in args wherever they are, returning
+// the remaining positional arguments.
+func parseInterspersed(f *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		err := f.Parse(args)
 		if err != nil {
-			layerReader.Close()
-			continue
+			return nil, err
 		}
-		layerReader.Close()
-
-		predicateType, ok := attestation["predicateType"].(string)
-		if !ok {
-			continue
+		if f.NArg() == 0 {
+			return positional, nil
 		}
 
-		var result any
-		if attestationType == provenanceValue && isProvenanceType(predicateType) {
-			if predicate, ok := attestation["predicate"]; ok {
-				result = map[string]any{"SLSA": predicate}
-				return result, platform, nil
-			}
-		} else if attestationType == sbomValue && isSBOMType(predicateType) {
-			if predicate, ok := attestation["predicate"]; ok {
-				result = map[string]any{"SPDX": predicate}
-				return result, platform, nil
-			}
-		}
+		positional = append(positional, f.Arg(0))
+		args = f.Args()[1:]
 	}
+}
 
-	return nil, "", fmt.Errorf("no matching %s attestation found in layers", attestationType)
+func showDownloadUsage() error {
+	fmt.Printf(downloadf, exeName())
+	return errUsage
 }
 
-func getDigestsPlatform(idx v1.ImageIndex, digestStr string) (string, error) {
-	manifest, err := idx.IndexManifest()
+func writeContent(img, format string, w io.Writer) error {
+	atts, err := attestation.List(context.Background(), img, attestation.Options{})
 	if err != nil {
-		return "", fmt.Errorf("failed to get index manifest: %w", err)
+		return err
 	}
 
-	digest, err := v1.NewHash(digestStr)
-	if err != nil {
-		return "", fmt.Errorf("failed to parse digest: %w", err)
+	for _, a := range attestation.Filter(atts, format) {
+		slog.Debug("found attestation", "platform", a.Platform,
+			"predicateType", a.PredicateType, "source", a.Source)
 	}
 
-	for _, desc := range manifest.Manifests {
-		if desc.Digest == digest && desc.Platform != nil {
-			return fmt.Sprintf("%s/%s", desc.Platform.OS, desc.Platform.Architecture), nil
-		}
+	result, err := attestation.Document(atts, format)
+	if err != nil {
+		return fmt.Errorf("failed to extract attestations: %w", err)
 	}
 
-	return "", fmt.Errorf("manifest not found for digest: %s", digestStr)
-}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to marshal result: %w", err)
+	}
 
-func isProvenanceType(predicateType string) bool {
-	return strings.Contains(predicateType, "slsa.dev/provenance") ||
-		strings.Contains(predicateType, "slsaprovenance")
-}
+	_, err = w.Write(data)
+	if err != nil {
+		return err
+	}
 
-func isSBOMType(predicateType string) bool {
-	return strings.Contains(predicateType, "spdx") ||
-		strings.Contains(predicateType, "cyclonedx") ||
-		strings.Contains(predicateType, sbomValue)
+	_, err = fmt.Fprintln(w)
+	return err
 }
@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/rancherlabs/slsactl/internal/license"
+	"github.com/rancherlabs/slsactl/internal/sbom"
+)
+
+const licensef = `usage:
+    %[1]s license report --policy <POLICY_FILE> <IMAGE>
+    %[1]s license report --policy <POLICY_FILE> --output json <SBOM_FILE>
+
+<POLICY_FILE> lists the licenses allowed, denied or needing review, in YAML or JSON:
+
+    allow: [MIT, Apache-2.0, BSD-3-Clause]
+    review: [LGPL-2.1-only]
+    deny: [AGPL-3.0-only]
+    default: review
+`
+
+// errPolicyRequired indicates that no license policy was set.
+var errPolicyRequired = errors.New("a license policy must be set with --policy")
+
+func licenseCmd(args []string) error {
+	if len(args) < 1 || args[0] != "report" {
+		return showLicenseUsage()
+	}
+
+	var policyPath, output string
+	var opts sbom.Options
+	f := flag.NewFlagSet("", flag.ContinueOnError)
+	f.StringVar(&policyPath, "policy", "", "The license policy file, listing the licenses allowed, denied or needing review.")
+	f.StringVar(&output, "output", license.OutputTable, "The output format. Supported values are table (default) and json.")
+	f.StringVar(&opts.Platform, "platform", "linux/amd64", "The target platform for the container image, such as linux/amd64 or windows/amd64:10.0.20348.")
+	generationFlags(f, &opts)
+
+	// Flags may follow the image, as in: report <IMAGE> --policy <FILE>.
+	pos, err := parseInterspersed(f, args[1:])
+	if err != nil {
+		return err
+	}
+	if len(pos) != 1 {
+		return showLicenseUsage()
+	}
+	if policyPath == "" {
+		return errPolicyRequired
+	}
+	if err := license.ValidateOutput(output); err != nil {
+		return err
+	}
+
+	policy, err := license.LoadPolicy(policyPath)
+	if err != nil {
+		return err
+	}
+
+	target := pos[0]
+	doc, err := sbomDocument(target, sbom.FormatSyftJSON, opts)
+	if err != nil {
+		return err
+	}
+	pkgs, err := license.Packages(bytes.NewReader(doc))
+	if err != nil {
+		return err
+	}
+
+	var report license.Report
+	report.Evaluate(target, pkgs, policy)
+	err = report.Write(os.Stdout, output)
+	if err != nil {
+		return err
+	}
+	return report.Err()
+}
+
+func showLicenseUsage() error {
+	fmt.Printf(licensef, exeName())
+	return errUsage
+}
@@ -1,61 +1,287 @@
 package cmd
 
 import (
+	"errors"
 	"flag"
 	"fmt"
-	"os"
 	"strings"
 
+	"github.com/rancherlabs/slsactl/internal/imagelist"
+	"github.com/rancherlabs/slsactl/internal/license"
 	"github.com/rancherlabs/slsactl/internal/product"
+	"github.com/rancherlabs/slsactl/internal/sbom"
+	"github.com/rancherlabs/slsactl/internal/vulns"
 )
 
 const productf = `usage:
     %[1]s product verify --registry <src_registry> rancher-prime:v2.12.2
     %[1]s product copy --registry <src_registry> --images-list-base-url <base_url> rancher-prime:v2.12.2 <target_registry>
+    %[1]s product copy --dry-run --registry <src_registry> rancher-prime:v2.12.2 <target_registry>
+    %[1]s product copy --on-conflict fail --registry <src_registry> rancher-prime:v2.12.2 <target_registry>
     %[1]s product download --registry <src_registry> rancher-prime:v2.12.2
+    %[1]s product sbom --format cyclonedxjson --registry <src_registry> rancher-prime:v2.12.2
+    %[1]s product sbom-validate --format cyclonedxjson --registry <src_registry> rancher-prime:v2.12.2
+    %[1]s product vulns --db <OSV_DUMP> --output json --registry <src_registry> rancher-prime:v2.12.2
+    %[1]s product license --policy <POLICY_FILE> --registry <src_registry> rancher-prime:v2.12.2
+    %[1]s product search --registry <src_registry> rancher-prime:v2.12.2 --package openssl --version '<3.0.14'
+    %[1]s product search --registry <src_registry> rancher-prime:v2.12.2 --package pkg:maven/org.apache.logging.log4j/log4j-core
+    %[1]s product vex --registry <src_registry> rancher-prime:v2.12.2
+    %[1]s product sync --prune --registry <src_registry> rancher-prime:v2.12.1 rancher-prime:v2.12.2 <target_registry>
+    %[1]s product sync --bandwidth-limit 50MiB --registry-concurrency <target_registry>=2 --registry <src_registry> rancher-prime:v2.12.2 <target_registry>
 `
 
 func productCmd(args []string) error {
 	var registry string
 	var imagesListBaseURL string
+	var dryRun bool
+	var onConflict string
+	var prune bool
+	var bandwidthLimit string
+	var concurrency int
+	var registryConcurrency string
+	var progress string
+	var format string
+	var dbPath string
+	var output string
+	var policyPath string
+	var pkg string
+	var pkgVersion string
+	var gen sbom.Options
+	var vexPaths string
+	var author string
 	f := flag.NewFlagSet("", flag.ContinueOnError)
-	f.StringVar(&registry, "registry", "", "The registry used to fetch images and artefacts.")
+	f.StringVar(&registry, "registry", "", "The registry used to fetch images and artefacts. For download, local images may be read with oci:<dir>, docker-archive:<tar> or docker-daemon:.")
 	f.StringVar(&imagesListBaseURL, "images-list-base-url", "", "The base url for the images list artefact.")
-	err := f.Parse(args[1:])
+	f.BoolVar(&dryRun, "dry-run", false, "Plan the copy or sync without writing anything to the target registry.")
+	f.BoolVar(&prune, "prune", false, "Remove target tags no longer referenced by any of the synced versions.")
+	f.StringVar(&bandwidthLimit, "bandwidth-limit", "", "The maximum bytes per second downloaded, and uploaded, across all copies (e.g. 512K, 50MiB). Defaults to no limit.")
+	f.IntVar(&concurrency, "concurrency", imagelist.DefaultConcurrency, "The maximum concurrent blob transfers per registry.")
+	f.StringVar(&registryConcurrency, "registry-concurrency", "", "Per registry overrides of --concurrency, as comma separated <registry>=<limit> pairs.")
+	f.StringVar(&progress, "progress", "spinner", "How to report copy progress. Supported values are spinner (default) and json, which writes events to stderr.")
+	f.StringVar(&onConflict, "on-conflict", "skip", "How to handle target tags pointing to a different digest. Supported values are skip (default), fail and overwrite.")
+	f.StringVar(&format, "format", "spdxjson", "The format of the product SBOM, or of the SBOMs validated by sbom-validate. Supported values are spdxjson (default) and cyclonedxjson.")
+	f.StringVar(&dbPath, "db", "", "The local vulnerability database for vulns: a directory of OSV JSON files, or a zip archive of them.")
+	f.StringVar(&output, "output", vulns.OutputTable, "The output format of vulns, license, search and sbom-validate. Supported values are table (default), json and, for vulns, sarif.")
+	f.StringVar(&policyPath, "policy", "", "The license policy file for license, listing the licenses allowed, denied or needing review.")
+	f.StringVar(&pkg, "package", "", "The package to search for, by name or package URL, such as openssl or pkg:maven/org.apache.logging.log4j/log4j-core.")
+	f.StringVar(&pkgVersion, "version", "", "The versions of the package to search for, as comma separated comparisons such as '>=2.0.0, <2.17.1'.")
+	f.StringVar(&gen.Platform, "platform", "", "The platform of the images to search. Defaults to linux/amd64, and to windows/amd64 for Windows images.")
+	generationFlags(f, &gen)
+
+	f.StringVar(&vexPaths, "vex", "", "Comma-separated OpenVEX documents applied by vulns to all images, besides the OpenVEX attestations of each image.")
+	f.StringVar(&author, "author", "", "The author of the OpenVEX document merged by vex. Defaults to the author of the first document found.")
+
+	// Flags may follow the product, as in: search rancher-prime:v2.12.2 --package openssl.
+	pos, err := parseInterspersed(f, args[1:])
 	if err != nil {
 		return err
 	}
 
-	if len(f.Args()) < 1 {
-		showProductUsage()
+	if len(pos) < 1 {
+		return showProductUsage()
 	}
 
-	arg := f.Arg(0)
-	nameVer := strings.Split(arg, ":")
-	if len(nameVer) != 2 {
-		return fmt.Errorf("invalid name version %q: format expected <name>:<version>", arg)
+	nameVer, err := parseNameVersion(pos[0])
+	if err != nil {
+		return err
 	}
 
 	switch args[0] {
 	case "verify":
 		return product.Verify(registry, nameVer[0], nameVer[1], true, true)
 	case "copy":
-		if f.NArg() != 2 {
-			showProductUsage()
+		if len(pos) != 2 {
+			return showProductUsage()
+		}
+
+		policy, err := imagelist.ParseConflictPolicy(onConflict)
+		if err != nil {
+			return err
 		}
 
-		targetRegistry := f.Arg(1)
-		return product.Copy(registry, nameVer[0], nameVer[1], targetRegistry, imagesListBaseURL)
+		transfer, err := transferOptions(bandwidthLimit, concurrency, registryConcurrency)
+		if err != nil {
+			return err
+		}
+
+		jsonProgress, err := parseProgress(progress)
+		if err != nil {
+			return err
+		}
+
+		targetRegistry := pos[1]
+		return product.Copy(registry, nameVer[0], nameVer[1], targetRegistry, product.CopyOptions{
+			ImagesListBaseURL: imagesListBaseURL,
+			DryRun:            dryRun,
+			OnConflict:        policy,
+			Transfer:          transfer,
+			JSONProgress:      jsonProgress,
+		})
 	case "download":
 		return product.Download(registry, nameVer[0], nameVer[1])
-	default:
-		showProductUsage()
+	case "sbom":
+		return product.SBOM(registry, nameVer[0], nameVer[1], product.SBOMOptions{
+			ImagesListBaseURL: imagesListBaseURL,
+			Format:            format,
+			Generate:          sbom.Options{CacheDir: gen.CacheDir},
+		})
+	case "sbom-validate":
+		if output != "table" && output != "json" {
+			return fmt.Errorf("unsupported output format %q: supported values are table or json", output)
+		}
+
+		return product.ValidateSBOMs(registry, nameVer[0], nameVer[1], product.ValidateSBOMsOptions{
+			ImagesListBaseURL: imagesListBaseURL,
+			Format:            format,
+			JSON:              output == "json",
+			Generate:          sbom.Options{CacheDir: gen.CacheDir},
+		})
+	case "vulns":
+		if dbPath == "" {
+			return errDatabaseRequired
+		}
+
+		db, err := vulns.Load(dbPath)
+		if err != nil {
+			return err
+		}
+
+		docs, err := loadVEX(splitList(vexPaths))
+		if err != nil {
+			return err
+		}
+
+		return product.Vulns(registry, nameVer[0], nameVer[1], db, product.VulnsOptions{
+			ImagesListBaseURL: imagesListBaseURL,
+			Output:            output,
+			VEX:               docs,
+			Generate:          sbom.Options{CacheDir: gen.CacheDir},
+		})
+	case "vex":
+		return product.VEX(registry, nameVer[0], nameVer[1], product.VEXOptions{
+			ImagesListBaseURL: imagesListBaseURL,
+			Author:            author,
+		})
+	case "license":
+		if policyPath == "" {
+			return errPolicyRequired
+		}
+
+		policy, err := license.LoadPolicy(policyPath)
+		if err != nil {
+			return err
+		}
+
+		return product.License(registry, nameVer[0], nameVer[1], policy, product.LicenseOptions{
+			ImagesListBaseURL: imagesListBaseURL,
+			Output:            output,
+			Generate:          sbom.Options{CacheDir: gen.CacheDir},
+		})
+	case "search":
+		if pkg == "" {
+			return errors.New("a package must be set with --package")
+		}
+		if output != "table" && output != "json" {
+			return fmt.Errorf("unsupported output format %q: supported values are table or json", output)
+		}
+
+		q, err := sbom.NewQuery(pkg, pkgVersion)
+		if err != nil {
+			return err
+		}
+
+		return product.Search(registry, nameVer[0], nameVer[1], q, product.SearchOptions{
+			ImagesListBaseURL: imagesListBaseURL,
+			JSON:              output == "json",
+			Generate:          gen,
+		})
+	case "sync":
+		if len(pos) < 2 {
+			return showProductUsage()
+		}
+
+		policy, err := imagelist.ParseConflictPolicy(onConflict)
+		if err != nil {
+			return err
+		}
+
+		transfer, err := transferOptions(bandwidthLimit, concurrency, registryConcurrency)
+		if err != nil {
+			return err
+		}
+
+		jsonProgress, err := parseProgress(progress)
+		if err != nil {
+			return err
+		}
+
+		var versions []string
+		for _, arg := range pos[:len(pos)-1] {
+			nv, err := parseNameVersion(arg)
+			if err != nil {
+				return err
+			}
+			if nv[0] != nameVer[0] {
+				return fmt.Errorf("cannot sync different products: %q and %q", nameVer[0], nv[0])
+			}
+			versions = append(versions, nv[1])
+		}
+
+		targetRegistry := pos[len(pos)-1]
+		return product.Sync(registry, nameVer[0], versions, targetRegistry, product.SyncOptions{
+			ImagesListBaseURL: imagesListBaseURL,
+			DryRun:            dryRun,
+			Prune:             prune,
+			OnConflict:        policy,
+			Transfer:          transfer,
+			JSONProgress:      jsonProgress,
+		})
 	}
 
-	return nil
+	return showProductUsage()
+}
+
+func parseNameVersion(arg string) ([]string, error) {
+	nameVer := strings.Split(arg, ":")
+	if len(nameVer) != 2 {
+		return nil, fmt.Errorf("invalid name version %q: format expected <name>:<version>", arg)
+	}
+	return nameVer, nil
 }
 
-func showProductUsage() {
+func showProductUsage() error {
 	fmt.Printf(productf, exeName())
-	os.Exit(1)
+	return errUsage
+}
+
+func transferOptions(bandwidthLimit string, concurrency int, registryConcurrency string) (imagelist.TransferOptions, error) {
+	opts := imagelist.TransferOptions{
+		Concurrency: concurrency,
+	}
+
+	if bandwidthLimit != "" {
+		limit, err := imagelist.ParseByteSize(bandwidthLimit)
+		if err != nil {
+			return opts, err
+		}
+		opts.BandwidthLimit = limit
+	}
+
+	limits, err := imagelist.ParseRegistryConcurrency(registryConcurrency)
+	if err != nil {
+		return opts, err
+	}
+	opts.RegistryConcurrency = limits
+
+	return opts, nil
+}
+
+func parseProgress(progress string) (bool, error) {
+	switch progress {
+	case "spinner":
+		return false, nil
+	case "json":
+		return true, nil
+	}
+	return false, fmt.Errorf("invalid progress %q: supported values are spinner or json", progress)
 }
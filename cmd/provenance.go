@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
 	"strings"
 
@@ -13,6 +14,7 @@ import (
 	v1 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v1"
 	cosign "github.com/rancherlabs/slsactl/internal/cosign"
 	"github.com/rancherlabs/slsactl/internal/provenance"
+	"github.com/rancherlabs/slsactl/pkg/attestation"
 	"github.com/rancherlabs/slsactl/pkg/verify"
 )
 
@@ -23,107 +25,189 @@ var (
 	buildKitV1Full = "https://github.com/moby/buildkit/blob/master/docs/attestations/slsa-definitions.md"
 )
 
-func checkBuildKitV1Spec(img, platform string) (error, *v1.ProvenancePredicate, *v02.ProvenancePredicate) { //nolint
-	var data bytes.Buffer
-	err := writeContent(img, "provenance", &data)
+// fetchProvenance returns the provenance of img by platform. Images built by
+// BuildKit with SLSA v1 support return v1Full, others return v02.
+func fetchProvenance(img string) (v1Full provenance.SLSAV1Provenance, v02Prov provenance.BuildKitProvenance02, err error) {
+	atts, err := attestation.List(context.Background(), img, attestation.Options{})
 	if err != nil {
-		return err, nil, nil
+		return nil, nil, err
 	}
 
-	if strings.Contains(data.String(), buildKitV1Full) {
-		var buildKit provenance.SLSAV1Provenance
-		err = json.Unmarshal(data.Bytes(), &buildKit)
+	predicates := map[string]json.RawMessage{}
+	var isV1Full bool
+	for platform, found := range attestation.ByPlatform(attestation.Filter(atts, attestation.KindProvenance)) {
+		for _, a := range found {
+			slog.Debug("found attestation", "platform", a.Platform,
+				"predicateType", a.PredicateType, "source", a.Source)
+
+			predicate, err := a.Predicate()
+			if err != nil {
+				continue
+			}
+			predicates[platform] = predicate
+			isV1Full = isV1Full || bytes.Contains(predicate, []byte(buildKitV1Full))
+			break
+		}
+	}
+	if len(predicates) == 0 {
+		return nil, nil, fmt.Errorf("failed to extract attestations: %w: %s",
+			attestation.ErrNoAttestations, attestation.KindProvenance)
+	}
+
+	if isV1Full {
+		v1Full, err = decodePredicates[v1.ProvenancePredicate](predicates)
 		if err != nil {
-			return fmt.Errorf("cannot parse v1.0 provenance: %w", err), nil, nil
+			return nil, nil, fmt.Errorf("cannot parse v1.0 provenance: %w", err)
 		}
+		return v1Full, nil, nil
+	}
 
-		var predicate *v1.ProvenancePredicate //nolint
+	v02Prov, err = decodePredicates[v02.ProvenancePredicate](predicates)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot parse v0.2 provenance: %w", err)
+	}
+	return nil, v02Prov, nil
+}
 
-		if strings.EqualFold(platform, "linux/amd64") && buildKit.LinuxAmd64 != nil {
-			predicate = &buildKit.LinuxAmd64.SLSA
-		} else if strings.EqualFold(platform, "linux/arm64") && buildKit.LinuxArm64 != nil {
-			predicate = &buildKit.LinuxArm64.SLSA
-		} else if buildKit.SLSA != nil {
-			predicate = buildKit.SLSA
-		} else {
-			return fmt.Errorf("platform not supported: %q", platform), nil, nil
+// decodePredicates decodes predicates, keyed by platform.
+func decodePredicates[T any](predicates map[string]json.RawMessage) (attestation.Platforms[T], error) {
+	out := make(attestation.Platforms[T], len(predicates))
+	for platform, predicate := range predicates {
+		var v T
+		err := json.Unmarshal(predicate, &v)
+		if err != nil {
+			return nil, err
 		}
-
-		predicate.RunDetails.Builder.ID = cosign.BuilderID
-		return nil, predicate, nil
+		out[platform] = v
 	}
+	return out, nil
+}
 
-	var buildKit provenance.BuildKitProvenance02
-	err = json.Unmarshal(data.Bytes(), &buildKit)
+func provenanceCmd(img, format, platform string) error {
+	v1Full, v02Prov, err := fetchProvenance(img)
 	if err != nil {
-		return fmt.Errorf("cannot parse v0.2 provenance: %w", err), nil, nil
+		return err
 	}
 
-	var predicate *v02.ProvenancePredicate
+	platforms := []string{platform}
+	if strings.EqualFold(platform, attestation.PlatformAll) {
+		platforms = v02Prov.Keys()
+		if v1Full != nil {
+			platforms = v1Full.Keys()
+		}
+		if len(platforms) == 0 {
+			platforms = []string{""}
+		}
+	}
 
-	if strings.EqualFold(platform, "linux/amd64") && buildKit.LinuxAmd64 != nil {
-		predicate = &buildKit.LinuxAmd64.SLSA
-	} else if strings.EqualFold(platform, "linux/arm64") && buildKit.LinuxArm64 != nil {
-		predicate = &buildKit.LinuxArm64.SLSA
-	} else if buildKit.SLSA != nil {
-		predicate = buildKit.SLSA
-	} else {
-		return fmt.Errorf("platform not supported: %q", platform), nil, nil
+	p := provenancePrinter{img: img, format: format}
+	outputs := make(map[string]any, len(platforms))
+	for _, pl := range platforms {
+		out, err := p.output(v1Full, v02Prov, pl)
+		if err != nil {
+			return err
+		}
+		outputs[platformKey(pl)] = out
+	}
+
+	if !strings.EqualFold(platform, attestation.PlatformAll) {
+		return printOutput(os.Stdout, outputs[platformKey(platform)])
 	}
+	return printOutput(os.Stdout, outputs)
+}
 
-	return nil, nil, predicate
+// platformKey returns the key used for platform when printing all of them.
+func platformKey(platform string) string {
+	if platform == "" {
+		return "unknown"
+	}
+	return platform
 }
 
-func provenanceCmd(img, format, platform string) error {
-	err, predicateV1Full, predicate := checkBuildKitV1Spec(img, platform)
-	if err != nil {
-		return err
+type provenancePrinter struct {
+	img    string
+	format string
+
+	verified bool
+	override *v1.ProvenancePredicate
+}
+
+// output returns the provenance of platform in the printer's format.
+func (p *provenancePrinter) output(v1Full provenance.SLSAV1Provenance, v02Prov provenance.BuildKitProvenance02, platform string) (any, error) {
+	var predicateV1Full *v1.ProvenancePredicate
+	var predicate *v02.ProvenancePredicate
+
+	if v1Full != nil {
+		pred, ok := v1Full.Lookup(platform)
+		if !ok {
+			return nil, fmt.Errorf("platform not supported: %q", platform)
+		}
+		pred.RunDetails.Builder.ID = cosign.BuilderID
+		predicateV1Full = &pred
+	} else {
+		pred, ok := v02Prov.Lookup(platform)
+		if !ok {
+			return nil, fmt.Errorf("platform not supported: %q", platform)
+		}
+		predicate = &pred
 	}
 
-	switch format {
+	switch p.format {
 	case "slsav0.2":
 		if predicate != nil && predicate.BuildType == buildKitV1 {
-			err = printOutput(os.Stdout, predicate)
-		} else {
-			err = printOutput(os.Stdout, predicateV1Full)
+			return predicate, nil
 		}
+		return predicateV1Full, nil
 	case "slsav1":
 		if predicate != nil && predicate.BuildType != buildKitV1 {
-			return fmt.Errorf("image builtType not supported: %q", predicate.BuildType)
+			return nil, fmt.Errorf("image builtType not supported: %q", predicate.BuildType)
 		} else if predicateV1Full != nil && predicateV1Full.BuildDefinition.BuildType != buildKitV1Full {
-			return fmt.Errorf("image builtType not supported: %q", predicateV1Full.BuildDefinition.BuildType)
+			return nil, fmt.Errorf("image builtType not supported: %q", predicateV1Full.BuildDefinition.BuildType)
 		}
 
-		// Avoid polluting the output in successful verifications.
-		sout := os.Stdout
-		serr := os.Stderr
-		os.Stdout = nil
-		os.Stderr = nil
-		err := verify.Verify(img)
-		os.Stdout = sout
-		os.Stderr = serr
-
+		err := p.verify()
 		if err != nil {
-			return fmt.Errorf("failed to verify %q: %w", img, err)
+			return nil, err
 		}
 
 		if predicate != nil {
-			override, err := cosign.GetCosignCertData(context.Background(), img)
-			if err != nil {
-				return err
+			if p.override == nil {
+				p.override, err = cosign.GetCosignCertData(context.Background(), p.img)
+				if err != nil {
+					return nil, err
+				}
 			}
 
-			provV1 := provenance.ConvertV02ToV1(*predicate, override)
-			err = printOutput(os.Stdout, provV1) //nolint
-		} else {
-			err = printOutput(os.Stdout, predicateV1Full) //nolint
+			return provenance.ConvertV02ToV1(*predicate, p.override), nil
 		}
+		return predicateV1Full, nil
 
 	default:
-		return fmt.Errorf("invalid format %q: supported values are slsav0.2 or slsav1", format)
+		return nil, fmt.Errorf("invalid format %q: supported values are slsav0.2 or slsav1", p.format)
 	}
+}
 
-	return err
+// verify verifies the image signature once for all platforms.
+func (p *provenancePrinter) verify() error {
+	if p.verified {
+		return nil
+	}
+
+	// Avoid polluting the output in successful verifications.
+	sout := os.Stdout
+	serr := os.Stderr
+	os.Stdout = nil
+	os.Stderr = nil
+	err := verify.Verify(p.img)
+	os.Stdout = sout
+	os.Stderr = serr
+
+	if err != nil {
+		return fmt.Errorf("failed to verify %q: %w", p.img, err)
+	}
+
+	p.verified = true
+	return nil
 }
 
 func printOutput(w io.Writer, v any) error {
@@ -1,21 +1,51 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/rancherlabs/slsactl/internal/landlock"
+	"github.com/rancherlabs/slsactl/internal/sbom"
+	"github.com/rancherlabs/slsactl/pkg/attestation"
 )
 
 type command func(args []string) error
 
+// exitError makes Exec exit with code, printing err when set, rather than
+// commands calling os.Exit before the temp dir of the run is removed.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	if e.err == nil {
+		return fmt.Sprintf("exit status %d", e.code)
+	}
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// errUsage is returned by commands once their usage is shown.
+var errUsage = &exitError{code: 1}
+
 var (
 	cmds = map[string]command{
 		"download": downloadCmd,
 		"version":  versionCmd,
 		"verify":   verifyCmd,
 		"product":  productCmd,
+		"license":  licenseCmd,
+		"sbom":     sbomToolsCmd,
+		"vulns":    vulnsCmd,
+		"vex":      vexCmd,
+		"attest":   attestCmd,
 	}
 
 	usagef = `usage: %[1]s <command>
@@ -25,13 +55,16 @@ Available commands:
   verify:     Verifies the container image's signature
   version:    Shows %[1]s version and build information
   product:    Handle product level requests
+  license:    Evaluates the licenses of image packages against a policy
+  sbom:       Compares and validates the SBOMs of container images
+  vulns:      Matches image or SBOM packages against a local vulnerability database
+  vex:        Validates OpenVEX documents and attestations
+  attest:     Signs and attaches SBOM and provenance attestations to container images
 
 `
 )
 
 func Exec(args []string) {
-	landlock.EnforceOrDie()
-
 	if len(args) < 2 {
 		showUsage()
 	}
@@ -42,13 +75,64 @@ func Exec(args []string) {
 		showUsage()
 	}
 
+	cleanup := landlock.EnforceOrDie(localPaths(args), writablePaths(args))
 	err := cmd(args[2:])
+	cleanup()
+
+	var exit *exitError
+	if errors.As(err, &exit) {
+		if exit.err != nil {
+			fmt.Println(exit.err)
+		}
+		os.Exit(exit.code)
+	}
 	if err != nil {
 		fmt.Printf("failed to run %s: %v\n", name, err)
 		os.Exit(2)
 	}
 }
 
+// localPaths returns the paths of the local images referenced by args,
+// such as oci:<dir> or docker-archive:<tar>, of the SBOM sources such as
+// dir:<dir> or file:<tar>, and of the local files args refer to, such as
+// SBOM files or vulnerability databases.
+func localPaths(args []string) []string {
+	var paths []string
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-") {
+			_, arg, _ = strings.Cut(arg, "=")
+		}
+
+		if l, ok := attestation.ParseLocalRef(arg); ok && l.Path != "" {
+			paths = append(paths, l.Path)
+		} else if _, path, ok := sbom.LocalSource(arg); ok {
+			paths = append(paths, path)
+		} else if _, err := os.Stat(arg); err == nil && arg != "" {
+			paths = append(paths, arg)
+		}
+	}
+	return paths
+}
+
+// writablePaths returns the directories set by args with --tmp-dir or
+// --cache-dir, which SBOM generation writes to.
+func writablePaths(args []string) []string {
+	var paths []string
+	for i, arg := range args {
+		flag, value, ok := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || (flag != "tmp-dir" && flag != "cache-dir") {
+			continue
+		}
+		if !ok && i+1 < len(args) {
+			value = args[i+1]
+		}
+		if value != "" {
+			paths = append(paths, value)
+		}
+	}
+	return paths
+}
+
 func showUsage() {
 	fmt.Printf(usagef, exeName())
 	os.Exit(1)
@@ -2,86 +2,351 @@ package cmd
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
 	"strings"
 
+	"github.com/anchore/syft/syft/format"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/rancherlabs/slsactl/internal/cosign"
 	"github.com/rancherlabs/slsactl/internal/sbom"
+	"github.com/rancherlabs/slsactl/pkg/attestation"
 )
 
-func sbomCmd(img, outformat, platform string) error {
-	var buf bytes.Buffer
-	err := writeContent(img, "sbom", &buf)
-	if err != nil {
-		return fmt.Errorf("cannot write SBOM content: %w", err)
+func sbomCmd(img, outformat, platform string, enrich bool, opts sbom.Options) error {
+	if err := sbom.ValidateFormat(outformat); err != nil {
+		return &exitError{code: 6, err: fmt.Errorf(
+			"invalid format %q for SBOM: supported values are %s, optionally followed by @version",
+			outformat, strings.Join(sbom.Formats(), ", "))}
 	}
 
-	switch outformat {
-	case "cyclonedxjson", "spdxjson":
-	default:
-		fmt.Printf(
-			"invalid format %q for SBOM: supported values are cyclonedxjson or spdxjson\n",
-			outformat)
-		os.Exit(6)
+	// Directories, files and OCI archives have no attestations.
+	var atts []attestation.Attestation
+	var err error
+	if _, _, local := sbom.LocalSource(img); !local {
+		atts, err = attestation.List(context.Background(), img, attestation.Options{})
+		if err != nil {
+			return fmt.Errorf("cannot write SBOM content: %w", err)
+		}
 	}
 
-	var data sbom.BuildKitSBOM
-	err = json.Unmarshal(buf.Bytes(), &data)
-	if err != nil {
-		fmt.Println("Error cannot unmarshal SBOM: %w\n", err)
-		os.Exit(6)
-	}
-
-	var spdx json.RawMessage
-	if strings.EqualFold(platform, "linux/amd64") && data.LinuxAmd64 != nil {
-		spdx = data.LinuxAmd64.SPDX
-	} else if strings.EqualFold(platform, "linux/arm64") && data.LinuxArm64 != nil {
-		spdx = data.LinuxArm64.SPDX
-	} else if strings.EqualFold(platform, "windows/amd64") && data.WindowsAmd64 != nil {
-		spdx = data.WindowsAmd64.SPDX
-	} else if data.SPDX != nil {
-		spdx = *data.SPDX
-	} else {
-		return fmt.Errorf("platform not supported: %q", platform)
+	sboms := attestation.Filter(atts, attestation.KindSBOM)
+	for _, a := range sboms {
+		slog.Debug("found attestation", "platform", a.Platform,
+			"predicateType", a.PredicateType, "source", a.Source)
 	}
 
-	if len(spdx) != 0 {
-		m, err := spdx.MarshalJSON()
-		if len(m) > 4 && err == nil {
-			buf.Reset()
-			buf.ReadFrom(bytes.NewBuffer(m))
+	data := attestation.ByPlatform(sboms)
+	if strings.EqualFold(platform, attestation.PlatformAll) {
+		if len(sboms) == 0 {
+			return fmt.Errorf("cannot write SBOM content: %w: %s", attestation.ErrNoAttestations, attestation.KindSBOM)
+		}
+		return printAllSBOMs(img, data, outformat, enrich)
+	}
+
+	var buf bytes.Buffer
+	if found, ok := data.Lookup(platform); ok {
+		doc, err := sbom.FromAttestations(found, outformat)
+		if err == nil {
+			buf.Write(doc)
 		}
 	}
 
 	if buf.Len() < 10 {
 		buf.Reset()
 		// The image does not contain a SBOM layer, generates SBOM on demand.
-		err = sbom.Generate(img, outformat, &buf)
+		slog.Info("generating SBOM", "platform", platform)
+		opts.Platform = platform
+		err = sbom.Generate(img, outformat, opts, &buf)
 		if err != nil {
-			fmt.Println("Error generating SBOM: %w\n", err)
-			os.Exit(7)
+			return &exitError{code: 7, err: fmt.Errorf("error generating SBOM: %w", err)}
 		}
-	} else {
-		// The SBOM layer exists, we can now convert it to a different format
-		// if asked by the user.
-		if outformat == "cyclonedxjson" {
-			err = sbom.ConvertToCyclonedxJson(&buf, os.Stdout)
-			if err != nil {
-				fmt.Printf("Error converting SBOM: %v\n", err)
-				os.Exit(8)
-			}
-			return nil
+	}
+
+	if enrich {
+		doc, err := enrichSBOM(img, platform, buf.Bytes())
+		if err != nil {
+			return err
 		}
+		buf.Reset()
+		buf.Write(doc)
 	}
 
 	_, err = io.Copy(os.Stdout, &buf)
 	if err != nil {
-		fmt.Printf("Error exporting SBOM to stdout: %v\n", err)
-		os.Exit(8)
+		return &exitError{code: 8, err: fmt.Errorf("error exporting SBOM to stdout: %w", err)}
 	}
 	fmt.Fprintln(os.Stdout)
 
 	return nil
 }
+
+// printAllSBOMs prints the SBOM of every platform as a single JSON object
+// keyed by platform. Formats other than JSON are embedded as strings.
+func printAllSBOMs(img string, data attestation.Platforms[[]attestation.Attestation], outformat string, enrich bool) error {
+	platforms := data.Keys()
+	if len(platforms) == 0 {
+		platforms = []string{""}
+	}
+
+	outputs := make(map[string]json.RawMessage, len(platforms))
+	for _, platform := range platforms {
+		doc, err := sbom.FromAttestations(data[platform], outformat)
+		if err != nil {
+			return fmt.Errorf("failed to get %s SBOM: %w", platformKey(platform), err)
+		}
+		if enrich {
+			doc, err = enrichSBOM(img, platform, doc)
+			if err != nil {
+				return err
+			}
+		}
+		if !json.Valid(doc) {
+			doc, err = json.Marshal(string(doc))
+			if err != nil {
+				return err
+			}
+		}
+		outputs[platformKey(platform)] = doc
+	}
+
+	return printOutput(os.Stdout, outputs)
+}
+
+const sbomf = `usage:
+    %[1]s sbom diff <IMAGE_A> <IMAGE_B>
+    %[1]s sbom diff --output json rancher/rancher:v2.12.1 rancher/rancher:v2.12.2
+    %[1]s sbom validate <IMAGE|FILE>...
+    %[1]s sbom validate --format cyclonedxjson rancher/rancher:v2.12.2 rancher.spdx.json
+
+<IMAGE_A> and <IMAGE_B> may also be SBOM files, or local images: oci:<DIR>[:<REF>],
+docker-archive:<TAR>[:<REF>] or docker-daemon:<REF>, or the directories, files and
+OCI layout tarballs to generate SBOMs for: dir:<DIR>, file:<FILE> or oci-archive:<TAR>.
+
+validate checks SPDX and CycloneDX JSON documents against their schema, and for
+the NTIA and BSI minimum elements: the supplier, name, version and unique
+identifier of each package, their dependency relationships, and the author and
+timestamp of the document. Images are validated using their SBOM attestation in
+--format, or a generated one when they have none.
+`
+
+// sbomToolsCmd handles the sbom command, which works on the SBOMs of images
+// rather than downloading them.
+func sbomToolsCmd(args []string) error {
+	if len(args) < 1 {
+		return showSBOMUsage()
+	}
+
+	switch args[0] {
+	case "diff":
+		return sbomDiffCmd(args[1:])
+	case "validate":
+		return sbomValidateCmd(args[1:])
+	}
+
+	return showSBOMUsage()
+}
+
+func sbomDiffCmd(args []string) error {
+	var output string
+	var opts sbom.Options
+	f := flag.NewFlagSet("", flag.ContinueOnError)
+	f.StringVar(&output, "output", "table", "The output format. Supported values are table (default) and json.")
+	f.StringVar(&opts.Platform, "platform", "linux/amd64", "The target platform for the container images, such as linux/amd64 or windows/amd64:10.0.20348.")
+	generationFlags(f, &opts)
+
+	// Flags may follow the images, as in: diff <IMAGE_A> <IMAGE_B> --output json.
+	pos, err := parseInterspersed(f, args)
+	if err != nil {
+		return err
+	}
+	if len(pos) != 2 {
+		return showSBOMUsage()
+	}
+	if output != "table" && output != "json" {
+		return fmt.Errorf("unsupported output format %q: supported values are table or json", output)
+	}
+
+	before, err := sbomDocument(pos[0], sbom.FormatSyftJSON, opts)
+	if err != nil {
+		return err
+	}
+	after, err := sbomDocument(pos[1], sbom.FormatSyftJSON, opts)
+	if err != nil {
+		return err
+	}
+
+	d, err := sbom.CompareSBOMs(before, after)
+	if err != nil {
+		return err
+	}
+
+	if output == "json" {
+		return d.WriteJSON(os.Stdout)
+	}
+	return d.WriteTable(os.Stdout)
+}
+
+func sbomValidateCmd(args []string) error {
+	var outformat, output string
+	var opts sbom.Options
+	f := flag.NewFlagSet("", flag.ContinueOnError)
+	f.StringVar(&outformat, "format", sbom.FormatSPDXJSON, "The SBOM format of images. Supported values are spdxjson (default) and cyclonedxjson.")
+	f.StringVar(&output, "output", "table", "The output format. Supported values are table (default) and json.")
+	f.StringVar(&opts.Platform, "platform", "linux/amd64", "The target platform for the container images, such as linux/amd64 or windows/amd64:10.0.20348.")
+	generationFlags(f, &opts)
+
+	pos, err := parseInterspersed(f, args)
+	if err != nil {
+		return err
+	}
+	if len(pos) == 0 {
+		return showSBOMUsage()
+	}
+	if outformat != sbom.FormatSPDXJSON && outformat != sbom.FormatCycloneDXJSON {
+		return fmt.Errorf("%w %q: supported values are %s or %s",
+			sbom.ErrUnsupportedFormat, outformat, sbom.FormatSPDXJSON, sbom.FormatCycloneDXJSON)
+	}
+	if output != "table" && output != "json" {
+		return fmt.Errorf("unsupported output format %q: supported values are table or json", output)
+	}
+
+	var validations []sbom.NamedValidation
+	var errs []error
+	for _, target := range pos {
+		nv := sbom.NamedValidation{Name: target}
+		doc, err := sbomDocument(target, outformat, opts)
+		if err == nil {
+			nv.Validation, err = sbom.Validate(doc)
+		}
+		if err == nil {
+			err = nv.Err()
+		}
+		if err != nil {
+			if nv.Validation == nil {
+				nv.Error = err.Error()
+			}
+			errs = append(errs, fmt.Errorf("%s: %w", target, err))
+		}
+		validations = append(validations, nv)
+	}
+
+	if output == "json" {
+		err = printOutput(os.Stdout, validations)
+	} else {
+		err = sbom.WriteValidations(os.Stdout, validations)
+	}
+	if err != nil {
+		return err
+	}
+	return errors.Join(errs...)
+}
+
+// sbomDocument returns the SBOM of target, which is either an SBOM file or
+// an image. SBOM files are returned as is, in any format known to Syft,
+// unless they hold the SBOM of each platform, as written by download sbom
+// --platform all. Images without SBOM attestations have theirs generated
+// in outformat.
+func sbomDocument(target, outformat string, opts sbom.Options) ([]byte, error) {
+	if info, err := os.Stat(target); err == nil && info.Mode().IsRegular() {
+		data, err := os.ReadFile(target)
+		if err != nil {
+			return nil, err
+		}
+
+		if id, _ := format.Identify(bytes.NewReader(data)); id != "" {
+			return data, nil
+		}
+
+		var platforms attestation.Platforms[json.RawMessage]
+		if json.Unmarshal(data, &platforms) != nil {
+			return nil, fmt.Errorf("%s: unknown SBOM format", target)
+		}
+		doc, ok := platforms.Lookup(opts.Platform)
+		if !ok {
+			return nil, fmt.Errorf("%s: platform not supported: %q", target, opts.Platform)
+		}
+
+		// Formats other than JSON are embedded as strings.
+		var embedded string
+		if json.Unmarshal(doc, &embedded) == nil {
+			return []byte(embedded), nil
+		}
+		return doc, nil
+	}
+
+	doc, generated, err := sbom.ForImage(target, outformat, opts)
+	if err != nil {
+		return nil, fmt.Errorf("cannot get SBOM of %s: %w", target, err)
+	}
+	if generated {
+		slog.Info("generated SBOM", "image", target, "platform", opts.Platform)
+	}
+	return doc, nil
+}
+
+// enrichSBOM returns doc with the source metadata of img for platform,
+// taken from the certificate signing the image and, for the fields it
+// lacks, from the image provenance.
+func enrichSBOM(img, platform string, doc []byte) ([]byte, error) {
+	var src sbom.Source
+	// Local images and sources have no signatures to read the certificate from.
+	_, image := attestation.ParseLocalRef(img)
+	_, _, fs := sbom.LocalSource(img)
+	if !image && !fs {
+		ref, err := name.ParseReference(img)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse image reference: %w", err)
+		}
+
+		data, err := cosign.GetCertData(context.Background(), ref.Name())
+		if err != nil {
+			slog.Debug("no signing certificate data found", "image", img, "error", err)
+		} else {
+			src = sbom.Source{
+				RepositoryURI: data.RepositoryURI,
+				Commit:        data.Commit,
+				Ref:           data.Ref,
+				WorkflowRef:   data.WorkflowRef,
+				Trigger:       data.Trigger,
+			}
+		}
+	}
+
+	predicate, err := attestation.Fetch(context.Background(), img, attestation.KindProvenance, platform, attestation.Options{})
+	if err != nil {
+		slog.Debug("no provenance found", "image", img, "platform", platform, "error", err)
+	} else if p, err := sbom.SourceFromProvenance(predicate); err == nil {
+		src = src.Merge(p)
+	}
+
+	enriched, err := sbom.Enrich(doc, src)
+	if err != nil {
+		return nil, fmt.Errorf("cannot enrich SBOM of %s: %w", img, err)
+	}
+	return enriched, nil
+}
+
+// generationFlags registers the flags configuring where the SBOMs of images
+// without SBOM attestations are generated.
+func generationFlags(f *flag.FlagSet, opts *sbom.Options) {
+	f.Func("tmp-dir", "The directory for the temporary files of SBOM generation. Defaults to $TMPDIR, or /tmp.", func(dir string) error {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return err
+		}
+		// Syft and Stereoscope create their temporary files in os.TempDir().
+		return os.Setenv("TMPDIR", dir)
+	})
+	f.StringVar(&opts.CacheDir, "cache-dir", "", "The directory caching image layers across SBOM generations, so that images sharing base layers download them once. Disabled by default.")
+}
+
+func showSBOMUsage() error {
+	fmt.Printf(sbomf, exeName())
+	return errUsage
+}
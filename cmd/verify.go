@@ -3,13 +3,13 @@ package cmd
 import (
 	"flag"
 	"fmt"
-	"os"
 
 	"github.com/rancherlabs/slsactl/pkg/verify"
 )
 
 const verifyf = `usage:
     %[1]s verify <IMAGE>
+    %[1]s verify oci:<DIR>:<IMAGE>
 `
 
 func verifyCmd(args []string) error {
@@ -20,7 +20,7 @@ func verifyCmd(args []string) error {
 	}
 
 	if len(f.Args()) != 1 {
-		showVerifyUsage()
+		return showVerifyUsage()
 	}
 
 	err = verify.Verify(f.Arg(0))
@@ -30,7 +30,7 @@ func verifyCmd(args []string) error {
 	return err
 }
 
-func showVerifyUsage() {
+func showVerifyUsage() error {
 	fmt.Printf(verifyf, exeName())
-	os.Exit(1)
+	return errUsage
 }
@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/rancherlabs/slsactl/internal/vex"
+	"github.com/rancherlabs/slsactl/pkg/attestation"
+)
+
+const vexf = `usage:
+    %[1]s vex validate <FILE|IMAGE>...
+
+<FILE> is an OpenVEX document, and <IMAGE> an image whose OpenVEX attestations
+are validated.
+`
+
+// errInvalidVEX indicates that some OpenVEX documents are not valid.
+var errInvalidVEX = errors.New("invalid OpenVEX documents found")
+
+func vexCmd(args []string) error {
+	if len(args) < 1 || args[0] != "validate" {
+		return showVEXUsage()
+	}
+
+	f := flag.NewFlagSet("", flag.ContinueOnError)
+	err := f.Parse(args[1:])
+	if err != nil {
+		return err
+	}
+	if f.NArg() < 1 {
+		return showVEXUsage()
+	}
+
+	var invalid int
+	for _, target := range f.Args() {
+		docs, err := vexDocuments(target)
+		if err != nil {
+			return err
+		}
+		if len(docs) == 0 {
+			return fmt.Errorf("%s: %w: %s", target, attestation.ErrNoAttestations, attestation.KindVEX)
+		}
+
+		for _, d := range docs {
+			_, err := vex.Parse(d.data)
+			if err != nil {
+				invalid++
+				fmt.Printf("%s: %v\n", d.name, err)
+				continue
+			}
+			fmt.Printf("%s: valid\n", d.name)
+		}
+	}
+
+	if invalid > 0 {
+		return fmt.Errorf("%w: %d", errInvalidVEX, invalid)
+	}
+	return nil
+}
+
+// vexDocument is an OpenVEX document named after where it was found.
+type vexDocument struct {
+	name string
+	data []byte
+}
+
+// vexDocuments returns the OpenVEX documents of target, a file or an image,
+// in the order they were found.
+func vexDocuments(target string) ([]vexDocument, error) {
+	if info, err := os.Stat(target); err == nil && info.Mode().IsRegular() {
+		data, err := os.ReadFile(target)
+		if err != nil {
+			return nil, err
+		}
+		return []vexDocument{{name: target, data: data}}, nil
+	}
+
+	atts, err := attestation.List(context.Background(), target, attestation.Options{})
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []vexDocument
+	for i, a := range attestation.Filter(atts, attestation.KindVEX) {
+		predicate, err := a.Predicate()
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, vexDocument{name: fmt.Sprintf("%s#%d (%s)", target, i, a.Source), data: predicate})
+	}
+	return docs, nil
+}
+
+// loadVEX loads the OpenVEX documents at paths.
+func loadVEX(paths []string) ([]*vex.Document, error) {
+	var docs []*vex.Document
+	for _, path := range paths {
+		d, err := vex.Load(path)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, d)
+	}
+	return docs, nil
+}
+
+func showVEXUsage() error {
+	fmt.Printf(vexf, exeName())
+	return errUsage
+}
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/rancherlabs/slsactl/internal/sbom"
+	"github.com/rancherlabs/slsactl/internal/vex"
+	"github.com/rancherlabs/slsactl/internal/vulns"
+)
+
+const vulnsf = `usage:
+    %[1]s vulns --db <OSV_DUMP> <IMAGE>
+    %[1]s vulns --db <OSV_DUMP> --output sarif <SBOM_FILE>
+    %[1]s vulns --db <OSV_DUMP> --vex <OPENVEX_FILE> <IMAGE>
+
+<OSV_DUMP> is a directory of OSV JSON files, or a zip archive of them.
+Grype databases are not supported.
+
+The OpenVEX attestations of <IMAGE> are applied, along with the documents set
+with --vex: vulnerabilities not affecting the image, or fixed, are suppressed.
+`
+
+// errDatabaseRequired indicates that no vulnerability database was set.
+var errDatabaseRequired = errors.New("a vulnerability database must be set with --db")
+
+func vulnsCmd(args []string) error {
+	var dbPath, output, vexPaths string
+	var opts sbom.Options
+	f := flag.NewFlagSet("", flag.ContinueOnError)
+	f.StringVar(&dbPath, "db", "", "The local vulnerability database: a directory of OSV JSON files, or a zip archive of them as published by osv.dev.")
+	f.StringVar(&output, "output", vulns.OutputTable, "The output format. Supported values are table (default), json and sarif.")
+	f.StringVar(&opts.Platform, "platform", "linux/amd64", "The target platform for the container image, such as linux/amd64 or windows/amd64:10.0.20348.")
+	f.StringVar(&vexPaths, "vex", "", "Comma-separated OpenVEX documents to apply, besides the OpenVEX attestations of the image.")
+	generationFlags(f, &opts)
+
+	// Flags may follow the image, as in: vulns <IMAGE> --db <DIR>.
+	pos, err := parseInterspersed(f, args)
+	if err != nil {
+		return err
+	}
+	if len(pos) != 1 {
+		return showVulnsUsage()
+	}
+	if dbPath == "" {
+		return errDatabaseRequired
+	}
+	if err := vulns.ValidateOutput(output); err != nil {
+		return err
+	}
+
+	db, err := vulns.Load(dbPath)
+	if err != nil {
+		return err
+	}
+
+	docs, err := loadVEX(splitList(vexPaths))
+	if err != nil {
+		return err
+	}
+
+	target := pos[0]
+	pkgs, err := targetPackages(target, opts)
+	if err != nil {
+		return err
+	}
+
+	_, _, local := sbom.LocalSource(target)
+	if info, err := os.Stat(target); !local && (err != nil || !info.Mode().IsRegular()) {
+		attached, err := vex.ForImage(target)
+		if err != nil {
+			return fmt.Errorf("cannot get VEX of %s: %w", target, err)
+		}
+		docs = append(docs, attached...)
+	}
+
+	img := vulns.NewImageReport(target, pkgs, db)
+	img.ApplyVEX(docs)
+
+	var report vulns.Report
+	report.Add(img)
+	return report.Write(os.Stdout, output)
+}
+
+// targetPackages returns the packages of target, which is either an SBOM
+// file or an image.
+func targetPackages(target string, opts sbom.Options) ([]vulns.Package, error) {
+	doc, err := sbomDocument(target, sbom.FormatSyftJSON, opts)
+	if err != nil {
+		return nil, err
+	}
+	return vulns.Packages(bytes.NewReader(doc))
+}
+
+func showVulnsUsage() error {
+	fmt.Printf(vulnsf, exeName())
+	return errUsage
+}
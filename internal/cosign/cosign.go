@@ -21,7 +21,29 @@ const (
 	BuilderID = "https://github.com/rancherlabs/slsactl/tree/main/buildtypes/buildkit-gha/v1"
 )
 
-func GetCosignCertData(ctx context.Context, img string) (*v1.ProvenancePredicate, error) { //nolint
+// CertData holds the source metadata of the Fulcio certificate signing an
+// image, as set for GitHub Actions workflows.
+type CertData struct {
+	// RepositoryURI is the source repository, such as
+	// https://github.com/rancher/cis-operator.
+	RepositoryURI string
+	// Commit is the source repository commit digest.
+	Commit string
+	// Ref is the source repository ref, such as refs/tags/v1.0.15.
+	Ref string
+	// WorkflowRef is the workflow that signed the image, such as
+	// https://github.com/rancher/cis-operator/.github/workflows/release.yml@refs/tags/v1.0.15.
+	WorkflowRef string
+	// Trigger is the event that triggered the workflow, such as push.
+	Trigger string
+	// InvocationURI is the URI of the workflow run.
+	InvocationURI string
+}
+
+// GetCertData returns the source metadata of the certificate signing img,
+// found in its Sigstore bundles or, failing that, in its cosign signatures.
+// The signatures are not verified.
+func GetCertData(ctx context.Context, img string) (*CertData, error) {
 	ref, err := name.ParseReference(img, name.StrictValidation)
 	if err != nil {
 		return nil, fmt.Errorf("failed strict validation (image name should be fully qualified): %w", err)
@@ -66,36 +88,50 @@ func GetCosignCertData(ctx context.Context, img string) (*v1.ProvenancePredicate
 		return nil, errors.New("no matching signature or bundle found for image")
 	}
 
-	var inparams provenance.InternalParameters
-	var commitID, commitRef, repoURL string
-
+	var data CertData
 	for _, ext := range extensions {
 		switch {
 		case ext.Id.Equal(certificate.OIDSourceRepositoryDigest):
-			certificate.ParseDERString(ext.Value, &commitID)
+			certificate.ParseDERString(ext.Value, &data.Commit)
 		case ext.Id.Equal(certificate.OIDSourceRepositoryURI):
-			certificate.ParseDERString(ext.Value, &repoURL)
+			certificate.ParseDERString(ext.Value, &data.RepositoryURI)
 		case ext.Id.Equal(certificate.OIDSourceRepositoryRef):
-			certificate.ParseDERString(ext.Value, &commitRef)
+			certificate.ParseDERString(ext.Value, &data.Ref)
+		case ext.Id.Equal(certificate.OIDBuildConfigURI):
+			certificate.ParseDERString(ext.Value, &data.WorkflowRef)
 		case ext.Id.Equal(certificate.OIDBuildTrigger):
-			certificate.ParseDERString(ext.Value, &inparams.Trigger)
+			certificate.ParseDERString(ext.Value, &data.Trigger)
 		case ext.Id.Equal(certificate.OIDRunInvocationURI):
-			certificate.ParseDERString(ext.Value, &inparams.InvocationUri)
+			certificate.ParseDERString(ext.Value, &data.InvocationURI)
 		}
 	}
 
+	return &data, nil
+}
+
+func GetCosignCertData(ctx context.Context, img string) (*v1.ProvenancePredicate, error) { //nolint
+	data, err := GetCertData(ctx, img)
+	if err != nil {
+		return nil, err
+	}
+
+	inparams := provenance.InternalParameters{
+		Trigger:       data.Trigger,
+		InvocationUri: data.InvocationURI,
+	}
+
 	override := &v1.ProvenancePredicate{} //nolint
 	override.BuildDefinition.InternalParameters = inparams
 	deps := []v1.ResourceDescriptor{ //nolint
 		{
-			URI:    repoURL,
-			Digest: common.DigestSet{"gitCommit": commitID},
+			URI:    data.RepositoryURI,
+			Digest: common.DigestSet{"gitCommit": data.Commit},
 		},
 	}
 
-	if commitRef != "" {
+	if data.Ref != "" {
 		deps[0].Annotations = map[string]any{
-			"ref": commitRef,
+			"ref": data.Ref,
 		}
 	}
 
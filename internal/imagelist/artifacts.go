@@ -0,0 +1,173 @@
+package imagelist
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/rancherlabs/slsactl/pkg/attestation"
+)
+
+// ErrNoMatchingPlatform indicates none of the images of an index match the
+// requested platforms.
+var ErrNoMatchingPlatform = errors.New("no image matches the requested platforms")
+
+// BuildKit annotations linking attestation manifests to their image.
+const (
+	buildkitReferenceType   = "vnd.docker.reference.type"
+	buildkitReferenceDigest = "vnd.docker.reference.digest"
+)
+
+// copyPlatforms copies the images of src matching opts.Platforms into dst.
+// It returns the digests whose signatures must be copied: the source digest
+// when all of it is copied, or the digest of each platform image otherwise.
+func copyPlatforms(ctx context.Context, src, dst string, opts CopyOptions, report *CopyReport) ([]string, error) {
+	srcRef, err := name.ParseReference(src)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse source image reference: %w", err)
+	}
+
+	dstRef, err := name.ParseReference(dst)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse target image reference: %w", err)
+	}
+
+	desc, err := remote.Get(srcRef, opts.remoteOptions(ctx)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch descriptor for %q: %w", src, err)
+	}
+
+	copyAll := func() ([]string, error) {
+		report.ImageStatus, _, err = copyArtifact(ctx, src, dst, opts)
+		report.add(ArtifactImage, src, dst, desc.Digest.String(), report.ImageStatus)
+		return []string{desc.Digest.String()}, err
+	}
+
+	if !desc.MediaType.IsIndex() {
+		if desc.Platform != nil && !matchesPlatform(*desc.Platform, opts.Platforms) {
+			return nil, fmt.Errorf("%w: %s is %s", ErrNoMatchingPlatform, src, desc.Platform)
+		}
+		return copyAll()
+	}
+
+	idx, err := desc.ImageIndex()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index %q: %w", src, err)
+	}
+
+	m, err := idx.IndexManifest()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index manifest %q: %w", src, err)
+	}
+
+	keep := map[string]bool{}
+	var digests []string
+	for _, d := range m.Manifests {
+		if d.Platform != nil && matchesPlatform(*d.Platform, opts.Platforms) {
+			keep[d.Digest.String()] = true
+			digests = append(digests, d.Digest.String())
+		}
+	}
+	if len(digests) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNoMatchingPlatform, src)
+	}
+
+	// Attestation manifests are kept together with the image they describe.
+	for _, d := range m.Manifests {
+		if d.Annotations[buildkitReferenceType] == "attestation-manifest" &&
+			keep[d.Annotations[buildkitReferenceDigest]] {
+			keep[d.Digest.String()] = true
+		}
+	}
+
+	if len(keep) == len(m.Manifests) {
+		return copyAll()
+	}
+
+	filtered := mutate.RemoveManifests(idx, func(d v1.Descriptor) bool {
+		return !keep[d.Digest.String()]
+	})
+
+	digest, err := filtered.Digest()
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute filtered index digest: %w", err)
+	}
+
+	report.ImageStatus, err = putArtifact(ctx, src, dst, digest.String(), opts, func(bool) error {
+		return trackProgress(src, opts, func(progress ...remote.Option) error {
+			ropts := append(opts.remoteOptions(ctx), remote.WithJobs(opts.jobs()))
+			pusher, err := remote.NewPusher(append(ropts, progress...)...)
+			if err != nil {
+				return err
+			}
+			return pusher.Push(ctx, dstRef, filtered)
+		})
+	})
+	report.add(ArtifactImage, src, dst, digest.String(), report.ImageStatus)
+
+	return digests, err
+}
+
+func matchesPlatform(p v1.Platform, platforms []v1.Platform) bool {
+	return slices.ContainsFunc(platforms, p.Satisfies)
+}
+
+// copyAttestations copies the attestations, attached SBOMs and referrers of
+// the image digest from the repository of srcRef into dstRepo.
+func copyAttestations(ctx context.Context, srcRef name.Reference, dstRepo name.Repository, digest string, opts CopyOptions, report *CopyReport) error {
+	craneOpts := opts.craneOptions(ctx)
+	prefix := strings.Replace(digest, ":", "-", 1)
+
+	for _, tag := range []struct {
+		suffix string
+		kind   string
+	}{
+		{".att", ArtifactAttestation},
+		{".sbom", ArtifactSBOM},
+	} {
+		src := signatureSource(srcRef, prefix+tag.suffix)
+		_, err := crane.Digest(src, craneOpts...)
+		if err != nil {
+			if isNotFound(err) {
+				continue
+			}
+			return fmt.Errorf("failed to get digest for %q: %w", src, err)
+		}
+
+		dst := dstRepo.Tag(prefix + tag.suffix).String()
+		status, d, err := copyArtifact(ctx, src, dst, opts)
+		report.add(tag.kind, src, dst, d, status)
+		if err != nil {
+			return err
+		}
+	}
+
+	srcRepo, err := name.NewRepository(artifactRepository(srcRef))
+	if err != nil {
+		return fmt.Errorf("failed to parse source repository: %w", err)
+	}
+
+	referrers, err := attestation.Referrers(srcRepo.Digest(digest), opts.remoteOptions(ctx)...)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range referrers {
+		src := srcRepo.Digest(r.Digest.String()).String()
+		dst := dstRepo.Digest(r.Digest.String()).String()
+		status, d, err := copyArtifact(ctx, src, dst, opts)
+		report.add(ArtifactReferrer, src, dst, d, status)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
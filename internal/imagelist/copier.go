@@ -4,16 +4,103 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"strings"
 	"sync"
 
+	"github.com/google/go-containerregistry/pkg/authn"
 	"github.com/google/go-containerregistry/pkg/crane"
 	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
 )
 
-// ErrNoSignaturesFound indicates no signature was found for the image.
-var ErrNoSignaturesFound = errors.New("no signatures found")
+var (
+	// ErrNoSignaturesFound indicates no signature was found for the image.
+	ErrNoSignaturesFound = errors.New("no signatures found")
+	// ErrSkippedImage indicates the image was not processed by the copier.
+	ErrSkippedImage = errors.New("skipping non-mirrored image")
+	// ErrTagConflict indicates the destination tag points to a different digest.
+	ErrTagConflict = errors.New("destination tag points to a different digest")
+	// ErrInvalidConflictPolicy indicates an unsupported conflict policy.
+	ErrInvalidConflictPolicy = errors.New("invalid conflict policy")
+)
+
+// Status of a destination tag when compared to its source.
+const (
+	// StatusNew indicates the destination tag does not exist yet.
+	StatusNew = "new"
+	// StatusExists indicates the destination tag points to the source digest.
+	StatusExists = "exists"
+	// StatusConflict indicates the destination tag points to a different digest.
+	StatusConflict = "conflict"
+	// StatusCopied indicates the artifact was copied to the destination tag.
+	StatusCopied = "copied"
+	// StatusOverwritten indicates a conflicting destination tag was replaced.
+	StatusOverwritten = "overwritten"
+)
+
+// ConflictPolicy defines how to handle destination tags that point to a
+// different digest than their source.
+type ConflictPolicy string
+
+const (
+	// ConflictSkip leaves the destination tag untouched and reports the conflict.
+	ConflictSkip ConflictPolicy = "skip"
+	// ConflictFail reports the conflict as an error.
+	ConflictFail ConflictPolicy = "fail"
+	// ConflictOverwrite replaces the destination tag with the source artifact.
+	ConflictOverwrite ConflictPolicy = "overwrite"
+)
+
+// ParseConflictPolicy returns the ConflictPolicy for s.
+func ParseConflictPolicy(s string) (ConflictPolicy, error) {
+	switch p := ConflictPolicy(strings.ToLower(s)); p {
+	case ConflictSkip, ConflictFail, ConflictOverwrite:
+		return p, nil
+	}
+	return "", fmt.Errorf("%w %q: supported values are skip, fail or overwrite", ErrInvalidConflictPolicy, s)
+}
+
+// Kinds of artefacts copied alongside an image.
+const (
+	ArtifactImage       = "image"
+	ArtifactSignature   = "signature"
+	ArtifactAttestation = "attestation"
+	ArtifactSBOM        = "sbom"
+	ArtifactReferrer    = "referrer"
+)
+
+// CopyReport describes the outcome of copying an image and its signature.
+type CopyReport struct {
+	SourceDigest string
+	// ImageStatus is empty when the image itself was not copied.
+	ImageStatus     string
+	SignatureTag    string
+	SignatureStatus string
+	// Artifacts lists every artefact handled, in the order they were copied.
+	Artifacts []CopiedArtifact
+}
+
+// CopiedArtifact describes a single artefact handled by a copy.
+type CopiedArtifact struct {
+	Kind   string
+	Source string
+	Target string
+	Digest string
+	Status string
+}
+
+func (r *CopyReport) add(kind, src, dst, digest, status string) {
+	r.Artifacts = append(r.Artifacts, CopiedArtifact{
+		Kind:   kind,
+		Source: src,
+		Target: dst,
+		Digest: digest,
+		Status: status,
+	})
+}
 
 var externalImages = map[string]string{
 	"sig-storage/snapshot-controller":                        "registry.k8s.io/sig-storage/snapshot-controller",
@@ -46,6 +133,14 @@ type imageCopier struct {
 	m            sync.Mutex
 	mirroredOnly bool
 	copyImages   bool
+	onConflict   ConflictPolicy
+	transfer     TransferOptions
+	transport    http.RoundTripper
+	progress     func(ProgressEvent)
+
+	// Overall progress across all the artefacts copied.
+	overallComplete int64
+	overallTotal    int64
 }
 
 func (i *imageCopier) Copy(srcImg, dstRegistry string) Entry {
@@ -54,25 +149,16 @@ func (i *imageCopier) Copy(srcImg, dstRegistry string) Entry {
 	}
 
 	if i.mirroredOnly && !strings.Contains(srcImg, "mirrored") {
-		entry.Error = errors.New("skipping non-mirrored image: " + srcImg)
-		return entry
-	}
-
-	ref, err := name.ParseReference(srcImg, name.WeakValidation)
-	if err != nil {
-		entry.Error = err
+		entry.Error = fmt.Errorf("%w: %s", ErrSkippedImage, srcImg)
 		return entry
 	}
 
-	reg, err := name.NewRegistry(dstRegistry)
+	dst, err := targetImage(srcImg, dstRegistry)
 	if err != nil {
 		entry.Error = err
 		return entry
 	}
 
-	repo := reg.Repo(ref.Context().RepositoryStr())
-	dst := repo.Tag(ref.Identifier()).String()
-
 	ctx := context.TODO()
 
 	// Reset stdout/stderr to avoid verbose output from cosign.
@@ -82,11 +168,21 @@ func (i *imageCopier) Copy(srcImg, dstRegistry string) Entry {
 	os.Stdout = nil
 	os.Stderr = nil
 
-	err = CopySignature(ctx, srcImg, dst, i.copyImages)
+	report, err := CopySignatureWithOptions(ctx, srcImg, dst, CopyOptions{
+		CopyImage:  i.copyImages,
+		OnConflict: i.onConflict,
+		Transport:  i.transport,
+		Jobs:       i.transfer.jobs(srcImg, dst),
+		Progress:   i.progressFunc(srcImg),
+	})
 	if err != nil {
 		entry.Error = err
 	}
 	entry.Signed = (err == nil)
+	entry.SourceDigest = report.SourceDigest
+	entry.Status = report.ImageStatus
+	entry.SignatureTag = report.SignatureTag
+	entry.SignatureStatus = report.SignatureStatus
 
 	os.Stdout = stdout
 	os.Stderr = stderr
@@ -95,77 +191,342 @@ func (i *imageCopier) Copy(srcImg, dstRegistry string) Entry {
 	return entry
 }
 
+// progressFunc returns a CopyOptions.Progress func that reports the bytes of
+// img, or nil when progress is not being tracked.
+func (i *imageCopier) progressFunc(img string) func(string, v1.Update) {
+	if i.progress == nil {
+		return nil
+	}
+
+	var artifact string
+	var complete, total int64
+	return func(a string, u v1.Update) {
+		// Updates are cumulative within each artifact copy.
+		if a != artifact {
+			artifact = a
+			complete, total = 0, 0
+		}
+
+		i.overallComplete += u.Complete - complete
+		i.overallTotal += u.Total - total
+		complete, total = u.Complete, u.Total
+
+		i.progress(ProgressEvent{
+			Image:           img,
+			Artifact:        artifact,
+			Complete:        complete,
+			Total:           total,
+			OverallComplete: i.overallComplete,
+			OverallTotal:    i.overallTotal,
+		})
+	}
+}
+
+// targetImage returns the reference srcImg will have once copied into dstRegistry.
+func targetImage(srcImg, dstRegistry string) (string, error) {
+	ref, err := name.ParseReference(srcImg, name.WeakValidation)
+	if err != nil {
+		return "", err
+	}
+
+	reg, err := name.NewRegistry(dstRegistry)
+	if err != nil {
+		return "", err
+	}
+
+	repo := reg.Repo(ref.Context().RepositoryStr())
+	return repo.Tag(ref.Identifier()).String(), nil
+}
+
 func signatureSource(srcRef name.Reference, tag string) string {
+	// Fully qualified reference: <registry>/<repository>:<signature_tag>
+	return fmt.Sprintf("%s:%s", artifactRepository(srcRef), tag)
+}
+
+// artifactRepository returns the repository holding the signatures and
+// attestations of srcRef, which for some mirrored images is the upstream one.
+func artifactRepository(srcRef name.Reference) string {
 	repo := srcRef.Context().RepositoryStr()
 	if upstream, found := externalImages[repo]; found {
-		return fmt.Sprintf("%s:%s", upstream, tag)
+		return upstream
 	}
-
-	// Fully qualified reference: <registry>/<repository>:<signature_tag>
-	return fmt.Sprintf("%s:%s", srcRef.Context().Name(), tag)
+	return srcRef.Context().Name()
 }
 
 // CopySignature copies a container image with its cosign signature from source to target registry.
 // Supports both legacy (.sig suffix) and new OCI artifact signature formats. Source and target
-// tags must match since signatures are bound to content digests. Uses NoClobber to prevent
-// overwriting existing tags.
+// tags must match since signatures are bound to content digests. Existing tags that point to a
+// different digest are left untouched, use CopySignatureWithOptions to detect or replace them.
 func CopySignature(ctx context.Context, srcImgRef, dstImgRef string, copyImage bool) error {
-	digest, err := crane.Digest(srcImgRef)
+	_, err := CopySignatureWithOptions(ctx, srcImgRef, dstImgRef, CopyOptions{CopyImage: copyImage})
+	return err
+}
+
+// CopyOptions tunes how CopySignatureWithOptions copies artefacts.
+type CopyOptions struct {
+	// CopyImage copies the image alongside its signature.
+	CopyImage bool
+	// OnConflict defines how to handle destination tags that point to a
+	// different digest. Defaults to ConflictSkip.
+	OnConflict ConflictPolicy
+	// Transport overrides the HTTP transport used for registry requests.
+	Transport http.RoundTripper
+	// Jobs limits the concurrent blob transfers of each copy.
+	// Defaults to DefaultConcurrency.
+	Jobs int
+	// Progress is called as the bytes of artifact are written to the target.
+	Progress func(artifact string, update v1.Update)
+	// Keychain used to authenticate against registries.
+	// Defaults to authn.DefaultKeychain.
+	Keychain authn.Keychain
+	// Platforms restricts the image copy to the matching platforms of an
+	// index. As the copied index differs from its source, the signatures of
+	// each platform image are copied instead of the index signature.
+	Platforms []v1.Platform
+	// Attestations copies the cosign attestations (.att), attached SBOMs
+	// (.sbom) and OCI referrers of the image.
+	Attestations bool
+}
+
+func (o CopyOptions) craneOptions(ctx context.Context) []crane.Option {
+	opts := []crane.Option{crane.WithContext(ctx)}
+	if o.Transport != nil {
+		opts = append(opts, crane.WithTransport(o.Transport))
+	}
+	if o.Keychain != nil {
+		opts = append(opts, crane.WithAuthFromKeychain(o.Keychain))
+	}
+	return opts
+}
+
+func (o CopyOptions) remoteOptions(ctx context.Context) []remote.Option {
+	return crane.GetOptions(o.craneOptions(ctx)...).Remote
+}
+
+// CopySignatureWithOptions works as CopySignature, applying opts to the copy. The
+// returned report is never nil and holds the status of each destination tag.
+func CopySignatureWithOptions(ctx context.Context, srcImgRef, dstImgRef string, opts CopyOptions) (*CopyReport, error) {
+	report := &CopyReport{}
+	craneOpts := opts.craneOptions(ctx)
+
+	digest, err := crane.Digest(srcImgRef, craneOpts...)
 	if err != nil {
-		return fmt.Errorf("failed to get signed image digest for %q: %w", srcImgRef, err)
+		return report, fmt.Errorf("failed to get signed image digest for %q: %w", srcImgRef, err)
 	}
+	report.SourceDigest = digest
 
 	sourceRef, err := name.ParseReference(srcImgRef)
 	if err != nil {
-		return fmt.Errorf("failed to parse source image reference: %w", err)
+		return report, fmt.Errorf("failed to parse source image reference: %w", err)
 	}
 
 	targetRef, err := name.ParseReference(dstImgRef)
 	if err != nil {
-		return fmt.Errorf("failed to parse target image reference: %w", err)
+		return report, fmt.Errorf("failed to parse target image reference: %w", err)
 	}
 
 	if sourceRef.Identifier() != targetRef.Identifier() {
-		return fmt.Errorf("source tag can't be different from target tag (signatures are bound to content, not tags); source tag: %s | target tag: %s", sourceRef.Identifier(), targetRef.Identifier())
+		return report, fmt.Errorf("source tag can't be different from target tag (signatures are bound to content, not tags); source tag: %s | target tag: %s", sourceRef.Identifier(), targetRef.Identifier())
 	}
 
+	// Digests whose signatures and attestations must be copied.
+	digests := []string{digest}
+
 	// copy image only after all safety checks but before checking signatures
-	if copyImage {
-		err := copyArtifact(ctx, srcImgRef, dstImgRef)
+	if opts.CopyImage {
+		if len(opts.Platforms) > 0 {
+			digests, err = copyPlatforms(ctx, srcImgRef, dstImgRef, opts, report)
+		} else {
+			report.ImageStatus, _, err = copyArtifact(ctx, srcImgRef, dstImgRef, opts)
+			report.add(ArtifactImage, srcImgRef, dstImgRef, digest, report.ImageStatus)
+		}
+		if err != nil {
+			return report, err
+		}
+	}
+
+	var signed bool
+	for _, d := range digests {
+		sourceSigRef, signatureTag, err := findSignature(sourceRef, d, craneOpts...)
+		if err != nil {
+			// Platform images of a filtered index are not always signed.
+			if errors.Is(err, ErrNoSignaturesFound) && len(digests) > 1 {
+				continue
+			}
+			return report, err
+		}
+
+		dstSigRef := fmt.Sprintf("%s:%s", targetRef.Context().Name(), signatureTag)
+		status, sigDigest, err := copyArtifact(ctx, sourceSigRef, dstSigRef, opts)
+		report.add(ArtifactSignature, sourceSigRef, dstSigRef, sigDigest, status)
+		if !signed {
+			report.SignatureTag = signatureTag
+			report.SignatureStatus = status
+			signed = true
+		}
 		if err != nil {
-			return err
+			return report, err
 		}
 	}
 
+	if !signed {
+		return report, fmt.Errorf("%w: none of the platform images are signed", ErrNoSignaturesFound)
+	}
+
+	if opts.Attestations {
+		for _, d := range digests {
+			err = copyAttestations(ctx, sourceRef, targetRef.Context(), d, opts, report)
+			if err != nil {
+				return report, err
+			}
+		}
+	}
+
+	return report, nil
+}
+
+// findSignature returns the fully qualified reference and the tag of the cosign
+// signature for the image digest. The legacy format (.sig suffix) takes precedence.
+func findSignature(sourceRef name.Reference, digest string, opts ...crane.Option) (string, string, error) {
 	hex := strings.TrimPrefix(digest, "sha256:")
 	signatureTag := fmt.Sprintf("sha256-%s.sig", hex)
 	sourceSigRef := signatureSource(sourceRef, signatureTag)
 
 	// check old format first (with .sig suffix)
-	_, err = crane.Manifest(sourceSigRef, crane.WithContext(ctx))
+	_, err := crane.Manifest(sourceSigRef, opts...)
 	if err != nil {
 		// try one last time for the new format (no .sig suffix)
 		signatureTag = strings.TrimSuffix(signatureTag, ".sig")
 		sourceSigRef = strings.TrimSuffix(sourceSigRef, ".sig")
-		_, err = crane.Manifest(sourceSigRef, crane.WithContext(ctx))
+		_, err = crane.Manifest(sourceSigRef, opts...)
 		if err != nil {
-			return fmt.Errorf("%w: %w", ErrNoSignaturesFound, err)
+			return "", "", fmt.Errorf("%w: %w", ErrNoSignaturesFound, err)
 		}
 	}
 
-	dstSigRef := fmt.Sprintf("%s:%s", targetRef.Context().Name(), signatureTag)
-	return copyArtifact(ctx, sourceSigRef, dstSigRef)
+	return sourceSigRef, signatureTag, nil
+}
+
+// copyArtifact copies src into dst unless dst already points to the same digest.
+// When dst points to a different digest, opts.OnConflict defines the outcome.
+// It returns the status of dst and the digest of src.
+func copyArtifact(ctx context.Context, src, dst string, opts CopyOptions) (string, string, error) {
+	digest, err := crane.Digest(src, opts.craneOptions(ctx)...)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get digest for %q: %w", src, err)
+	}
+
+	status, err := putArtifact(ctx, src, dst, digest, opts, func(overwrite bool) error {
+		if overwrite {
+			return transfer(ctx, src, dst, opts)
+		}
+		return transfer(ctx, src, dst, opts, crane.WithNoClobber(true)) // ensures won't be overwritten.
+	})
+	return status, digest, err
 }
 
-func copyArtifact(ctx context.Context, src, dst string) error {
-	err := crane.Copy(src, dst,
-		crane.WithContext(ctx),
-		crane.WithNoClobber(true)) // ensures won't be overwritten.
+// putArtifact writes the artifact with the given digest into dst through
+// write, honouring opts.OnConflict when dst points to a different digest.
+func putArtifact(ctx context.Context, src, dst, digest string, opts CopyOptions, write func(overwrite bool) error) (string, error) {
+	craneOpts := opts.craneOptions(ctx)
 
-	if err != nil && !strings.Contains(err.Error(), "refusing to clobber existing tag") {
-		return fmt.Errorf("failed to copy from %q to %q: %w",
+	status, dstDigest, err := tagStatus(dst, digest, craneOpts...)
+	if err != nil {
+		return "", err
+	}
+
+	switch status {
+	case StatusExists:
+		return status, nil
+	case StatusConflict:
+		return resolveConflict(src, dst, digest, dstDigest, opts, write)
+	}
+
+	err = write(false)
+	if err != nil {
+		if errors.Is(err, crane.ErrRefusingToClobberExistingTag) {
+			// The tag was created after the status check, compare it again.
+			status, dstDigest, err = tagStatus(dst, digest, craneOpts...)
+			if err != nil || status == StatusExists {
+				return status, err
+			}
+			return resolveConflict(src, dst, digest, dstDigest, opts, write)
+		}
+
+		return "", fmt.Errorf("failed to copy from %q to %q: %w",
 			src, dst, err)
 	}
 
-	return nil
+	return StatusCopied, nil
+}
+
+func resolveConflict(src, dst, srcDigest, dstDigest string, opts CopyOptions, write func(overwrite bool) error) (string, error) {
+	switch opts.OnConflict {
+	case ConflictOverwrite:
+		err := write(true)
+		if err != nil {
+			return StatusConflict, fmt.Errorf("failed to overwrite %q with %q: %w",
+				dst, src, err)
+		}
+		return StatusOverwritten, nil
+	case ConflictFail:
+		return StatusConflict, fmt.Errorf("%w: %q is %s but %q is %s",
+			ErrTagConflict, dst, dstDigest, src, srcDigest)
+	default:
+		return StatusConflict, nil
+	}
+}
+
+// transfer runs crane.Copy from src to dst, reporting the bytes written
+// through opts.Progress.
+func transfer(ctx context.Context, src, dst string, opts CopyOptions, extra ...crane.Option) error {
+	craneOpts := append(opts.craneOptions(ctx), extra...)
+	craneOpts = append(craneOpts, crane.WithJobs(opts.jobs()))
+
+	return trackProgress(src, opts, func(progress ...remote.Option) error {
+		craneOpts = append(craneOpts, func(o *crane.Options) {
+			o.Remote = append(o.Remote, progress...)
+		})
+		return crane.Copy(src, dst, craneOpts...)
+	})
+}
+
+func (o CopyOptions) jobs() int {
+	if o.Jobs <= 0 {
+		return DefaultConcurrency
+	}
+	return o.Jobs
+}
+
+// trackProgress runs write with the remote options needed to report the
+// bytes of artifact through opts.Progress.
+func trackProgress(artifact string, opts CopyOptions, write func(...remote.Option) error) error {
+	if opts.Progress == nil {
+		return write()
+	}
+
+	var last v1.Update
+	updates := make(chan v1.Update, 16)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for u := range updates {
+			if u.Error == nil {
+				last = u
+				opts.Progress(artifact, u)
+			}
+		}
+	}()
+
+	err := write(remote.WithProgress(updates))
+	close(updates)
+	<-done
+
+	// Reads returning data alongside io.EOF are not accounted for upstream,
+	// so a successful copy may not report all its bytes as complete.
+	if err == nil && last.Complete < last.Total {
+		opts.Progress(artifact, v1.Update{Total: last.Total, Complete: last.Total})
+	}
+
+	return err
 }
@@ -1,8 +1,11 @@
 package imagelist
 
 import (
+	"context"
+	"strings"
 	"testing"
 
+	"github.com/google/go-containerregistry/pkg/crane"
 	"github.com/google/go-containerregistry/pkg/name"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -82,3 +85,96 @@ func TestOverrideSignatureSource(t *testing.T) {
 		})
 	}
 }
+
+func TestCopySignatureWithOptions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		policy     ConflictPolicy
+		conflict   bool
+		wantStatus string
+		wantErr    error
+	}{
+		{
+			name:       "new tag is copied",
+			policy:     ConflictSkip,
+			wantStatus: StatusCopied,
+		},
+		{
+			name:       "conflict is skipped",
+			policy:     ConflictSkip,
+			conflict:   true,
+			wantStatus: StatusConflict,
+		},
+		{
+			name:       "conflict fails",
+			policy:     ConflictFail,
+			conflict:   true,
+			wantStatus: StatusConflict,
+			wantErr:    ErrTagConflict,
+		},
+		{
+			name:       "conflict is overwritten",
+			policy:     ConflictOverwrite,
+			conflict:   true,
+			wantStatus: StatusOverwritten,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			srcRegistry := newTestRegistry(t)
+			dstRegistry := newTestRegistry(t)
+
+			src := srcRegistry + "/rancher/mirrored-foo:v1"
+			digest := pushRandom(t, src)
+			pushRandom(t, srcRegistry+"/rancher/mirrored-foo:"+strings.Replace(digest, ":", "-", 1)+".sig")
+
+			dst, err := targetImage(src, dstRegistry)
+			require.NoError(t, err)
+
+			if tc.conflict {
+				pushRandom(t, dst)
+			}
+
+			report, err := CopySignatureWithOptions(context.Background(), src, dst, CopyOptions{CopyImage: true, OnConflict: tc.policy})
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, StatusCopied, report.SignatureStatus)
+			}
+			assert.Equal(t, tc.wantStatus, report.ImageStatus)
+
+			got, err := crane.Digest(dst)
+			require.NoError(t, err)
+			if tc.wantStatus == StatusConflict {
+				assert.NotEqual(t, digest, got)
+			} else {
+				assert.Equal(t, digest, got)
+			}
+
+			// Copying again is a no-op.
+			if tc.wantErr == nil {
+				report, err = CopySignatureWithOptions(context.Background(), src, dst, CopyOptions{CopyImage: true, OnConflict: tc.policy})
+				require.NoError(t, err)
+				assert.Equal(t, StatusExists, report.SignatureStatus)
+			}
+		})
+	}
+}
+
+func TestParseConflictPolicy(t *testing.T) {
+	t.Parallel()
+
+	for _, s := range []string{"skip", "fail", "overwrite", "FAIL"} {
+		_, err := ParseConflictPolicy(s)
+		require.NoError(t, err, s)
+	}
+
+	_, err := ParseConflictPolicy("ignore")
+	require.ErrorIs(t, err, ErrInvalidConflictPolicy)
+}
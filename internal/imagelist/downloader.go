@@ -1,22 +1,16 @@
 package imagelist
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
-	"errors"
-	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"sync"
 
 	"github.com/google/go-containerregistry/pkg/name"
-	v1 "github.com/google/go-containerregistry/pkg/v1"
-	"github.com/google/go-containerregistry/pkg/v1/remote"
-)
-
-const (
-	provenanceType = "provenance"
-	sbomType       = "sbom"
+	"github.com/rancherlabs/slsactl/pkg/attestation"
 )
 
 type imageDownloader struct {
@@ -31,40 +25,16 @@ func (d *imageDownloader) Download(img, outputDir string) Entry {
 		Image: img,
 	}
 
-	ref, err := name.ParseReference(img)
-	if err != nil {
-		entry.Error = fmt.Errorf("failed to parse image reference: %w", err)
-		return entry
-	}
-
-	desc, err := remote.Get(ref)
+	atts, err := attestation.List(context.TODO(), img, attestation.Options{})
 	if err != nil {
-		entry.Error = fmt.Errorf("failed to fetch image descriptor: %w", err)
+		entry.Error = err
 		return entry
 	}
 
 	imgName := sanitizeImageName(img)
 
-	var sbomData, provData any
-	if desc.MediaType.IsIndex() {
-		idx, err := desc.ImageIndex()
-		if err != nil {
-			entry.Error = fmt.Errorf("failed to get image index: %w", err)
-			return entry
-		}
-
-		sbomData, _ = extractFromIndex(idx, sbomType)
-		provData, _ = extractFromIndex(idx, provenanceType)
-	} else {
-		image, err := desc.Image()
-		if err != nil {
-			entry.Error = fmt.Errorf("failed to get image: %w", err)
-			return entry
-		}
-
-		sbomData, _ = extractFromImage(image, sbomType)
-		provData, _ = extractFromImage(image, provenanceType)
-	}
+	sbomData, _ := attestation.Document(atts, attestation.KindSBOM)
+	provData, _ := attestation.Document(atts, attestation.KindProvenance)
 
 	if sbomData != nil {
 		sbomFile := filepath.Join(outputDir, imgName+"_sbom.json")
@@ -82,8 +52,19 @@ func (d *imageDownloader) Download(img, outputDir string) Entry {
 		}
 	}
 
+	// The predicates above drop the statement subjects, which tie each
+	// attestation to the image digest it describes, so keep the full
+	// statements alongside.
+	if len(atts) > 0 {
+		statementsFile := filepath.Join(outputDir, imgName+"_attestations.intoto.jsonl")
+		err := saveInToto(statementsFile, atts)
+		if err == nil {
+			entry.StatementsFile = statementsFile
+		}
+	}
+
 	if entry.SBOMFile == "" && entry.ProvFile == "" {
-		entry.Error = errors.New("no attestations found")
+		entry.Error = attestation.ErrNoAttestations
 	}
 
 	return entry
@@ -124,178 +105,11 @@ func saveJSON(filename string, data any) error {
 	return os.WriteFile(filename, jsonData, 0o600)
 }
 
-//nolint:gocognit
-func extractFromIndex(idx v1.ImageIndex, attestationType string) (any, error) {
-	manifest, err := idx.IndexManifest()
+func saveInToto(filename string, atts []attestation.Attestation) error {
+	var buf bytes.Buffer
+	err := attestation.WriteInToto(&buf, atts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get index manifest: %w", err)
-	}
-
-	result := make(map[string]any)
-	for _, desc := range manifest.Manifests {
-		if isAttestationManifest(desc) {
-			attestation, platform, err := extractAttestation(idx, desc, attestationType)
-			if err == nil {
-				if attestation != nil && platform != "" {
-					result[platform] = attestation
-				}
-			}
-		}
-	}
-
-	// Check for single attestation without platform
-	for _, desc := range manifest.Manifests {
-		if isAttestationManifest(desc) {
-			attestation, _, err := extractAttestation(idx, desc, attestationType)
-			if err == nil && attestation != nil {
-				if singleAttest, ok := attestation.(map[string]any); ok {
-					if slsa, ok := singleAttest["SLSA"]; ok && attestationType == provenanceType {
-						result["SLSA"] = slsa
-					} else if spdx, ok := singleAttest["SPDX"]; ok && attestationType == sbomType {
-						result["SPDX"] = spdx
-					}
-				}
-			}
-		}
-	}
-
-	if len(result) == 0 {
-		return nil, fmt.Errorf("no %s attestations found", attestationType)
-	}
-
-	return result, nil
-}
-
-func extractFromImage(img v1.Image, format string) (any, error) {
-	manifest, err := img.Manifest()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get manifest: %w", err)
-	}
-
-	if manifest.Annotations != nil {
-		for key, value := range manifest.Annotations {
-			if strings.Contains(key, provenanceType) && format == provenanceType {
-				var result any
-				err := json.Unmarshal([]byte(value), &result)
-				if err == nil {
-					return result, nil
-				}
-			}
-			if strings.Contains(key, sbomType) && format == sbomType {
-				var result any
-				err := json.Unmarshal([]byte(value), &result)
-				if err == nil {
-					return result, nil
-				}
-			}
-		}
-	}
-
-	return nil, errors.New("no attestation data found in image")
-}
-
-func isAttestationManifest(desc v1.Descriptor) bool {
-	if desc.MediaType == "application/vnd.oci.image.manifest.v1+json" ||
-		desc.MediaType == "application/vnd.docker.distribution.manifest.v2+json" {
-		if desc.Annotations != nil {
-			if refType, ok := desc.Annotations["vnd.docker.reference.type"]; ok {
-				return refType == "attestation-manifest"
-			}
-		}
-	}
-	return false
-}
-
-func extractAttestation(idx v1.ImageIndex, desc v1.Descriptor, attestationType string) (any, string, error) {
-	img, err := idx.Image(desc.Digest)
-	if err != nil {
-		return nil, "", fmt.Errorf("failed to get attestation image: %w", err)
-	}
-
-	layers, err := img.Layers()
-	if err != nil {
-		return nil, "", fmt.Errorf("failed to get layers: %w", err)
-	}
-
-	if len(layers) == 0 {
-		return nil, "", errors.New("no layers in attestation manifest")
-	}
-
-	platform := ""
-	if desc.Annotations != nil {
-		if refDigest, ok := desc.Annotations["vnd.docker.reference.digest"]; ok {
-			var err error
-			platform, err = getDigestsPlatform(idx, refDigest)
-			if err != nil && desc.Platform != nil {
-				platform = fmt.Sprintf("%s/%s", desc.Platform.OS, desc.Platform.Architecture)
-			}
-		}
-	}
-
-	if platform == "" && desc.Platform != nil {
-		platform = fmt.Sprintf("%s/%s", desc.Platform.OS, desc.Platform.Architecture)
-	}
-
-	for _, layer := range layers {
-		layerReader, err := layer.Uncompressed()
-		if err != nil {
-			continue
-		}
-
-		var attestation map[string]any
-		err = json.NewDecoder(layerReader).Decode(&attestation)
-		if err != nil {
-			layerReader.Close()
-			continue
-		}
-		layerReader.Close()
-
-		predicateType, ok := attestation["predicateType"].(string)
-		if !ok {
-			continue
-		}
-
-		if attestationType == provenanceType && isProvenanceType(predicateType) {
-			if predicate, ok := attestation["predicate"]; ok {
-				return map[string]any{"SLSA": predicate}, platform, nil
-			}
-		} else if attestationType == sbomType && isSBOMType(predicateType) {
-			if predicate, ok := attestation["predicate"]; ok {
-				return map[string]any{"SPDX": predicate}, platform, nil
-			}
-		}
-	}
-
-	return nil, "", fmt.Errorf("no matching %s attestation found in layers", attestationType)
-}
-
-func getDigestsPlatform(idx v1.ImageIndex, digestStr string) (string, error) {
-	manifest, err := idx.IndexManifest()
-	if err != nil {
-		return "", fmt.Errorf("failed to get index manifest: %w", err)
-	}
-
-	digest, err := v1.NewHash(digestStr)
-	if err != nil {
-		return "", fmt.Errorf("failed to parse digest: %w", err)
-	}
-
-	for _, desc := range manifest.Manifests {
-		if desc.Digest == digest && desc.Platform != nil {
-			return fmt.Sprintf("%s/%s", desc.Platform.OS, desc.Platform.Architecture), nil
-		}
+		return err
 	}
-
-	return "", fmt.Errorf("manifest not found for digest: %s", digestStr)
-}
-
-func isProvenanceType(predicateType string) bool {
-	return strings.Contains(predicateType, "slsa.dev/provenance") ||
-		strings.Contains(predicateType, "slsaprovenance")
-}
-
-func isSBOMType(predicateType string) bool {
-	return strings.Contains(predicateType, "spdx") ||
-		strings.Contains(predicateType, "cyclonedx") ||
-		strings.Contains(predicateType, sbomType)
+	return os.WriteFile(filename, buf.Bytes(), 0o600)
 }
@@ -1,13 +1,62 @@
 package imagelist
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/rancherlabs/slsactl/pkg/attestation"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
+func TestDownloadKeepsSubjects(t *testing.T) {
+	t.Parallel()
+
+	ref := newTestRegistry(t) + "/rancher/foo:v1"
+	digest := pushRandom(t, ref)
+
+	statement := `{"subject":[{"name":"foo","digest":{"sha256":"` + strings.TrimPrefix(digest, "sha256:") + `"}}],` +
+		`"predicateType":"https://slsa.dev/provenance/v1","predicate":{"buildDefinition":{}}}`
+	env, err := json.Marshal(attestation.Envelope{
+		PayloadType: attestation.MediaTypeInToto,
+		Payload:     base64.StdEncoding.EncodeToString([]byte(statement)),
+	})
+	require.NoError(t, err)
+
+	att, err := mutate.AppendLayers(empty.Image, static.NewLayer(env, attestation.MediaTypeDSSE))
+	require.NoError(t, err)
+
+	r, err := name.ParseReference(ref)
+	require.NoError(t, err)
+	attTag := r.Context().Tag(strings.Replace(digest, ":", "-", 1) + ".att")
+	require.NoError(t, crane.Push(att, attTag.String()))
+
+	dir := t.TempDir()
+	got := (&imageDownloader{}).Download(ref, dir)
+	require.NoError(t, got.Error)
+	assert.NotEmpty(t, got.ProvFile)
+	require.NotEmpty(t, got.StatementsFile)
+
+	data, err := os.ReadFile(got.StatementsFile)
+	require.NoError(t, err)
+
+	var gotEnv attestation.Envelope
+	require.NoError(t, json.Unmarshal(data, &gotEnv))
+
+	payload, err := gotEnv.Statement()
+	require.NoError(t, err)
+	assert.JSONEq(t, statement, string(payload))
+}
+
 func TestSanitizeImageName_Valid(t *testing.T) {
 	t.Parallel()
 
@@ -6,10 +6,14 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"net/http"
 	"strings"
 
 	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/rancherlabs/slsactl/internal/sbom"
 	"github.com/rancherlabs/slsactl/internal/spinner"
+	"github.com/rancherlabs/slsactl/pkg/attestation"
 )
 
 var (
@@ -28,14 +32,24 @@ type ImageCopier interface {
 	Copy(img, targetRegistry string) Entry
 }
 
+type ImagePlanner interface {
+	Plan(img, targetRegistry string) Entry
+}
+
 type ImageDownloader interface {
 	Download(img, outputDir string) Entry
 }
 
+type ImageSBOMCollector interface {
+	Collect(img, outputDir string) Entry
+}
+
 type Result struct {
 	Product string  `json:"product,omitempty"`
 	Version string  `json:"version,omitempty"`
 	Entries []Entry `json:"entries,omitempty"`
+	// Pruned lists the target tags removed, or to be removed, by PruneTags.
+	Pruned []string `json:"pruned,omitempty"`
 }
 
 type Entry struct {
@@ -44,31 +58,157 @@ type Entry struct {
 	Signed   bool   `json:"signed,omitempty"`
 	SBOMFile string `json:"sbomFile,omitempty"`
 	ProvFile string `json:"provFile,omitempty"`
+	// StatementsFile holds the full in-toto statements, including their
+	// subject digests, as JSON Lines.
+	StatementsFile string `json:"statementsFile,omitempty"`
+	// SBOMGenerated is set when the SBOM was generated, as the image had
+	// no SBOM attestation.
+	SBOMGenerated bool `json:"sbomGenerated,omitempty"`
+	// Platform is the platform the SBOM was fetched or generated for.
+	Platform string `json:"platform,omitempty"`
+
+	// Copy details, set by Copy and Plan.
+	SourceDigest    string `json:"sourceDigest,omitempty"`
+	TargetDigest    string `json:"targetDigest,omitempty"`
+	Status          string `json:"status,omitempty"`
+	SignatureTag    string `json:"signatureTag,omitempty"`
+	SignatureStatus string `json:"signatureStatus,omitempty"`
+	MissingBytes    int64  `json:"missingBytes,omitempty"`
 }
 
 type Processor struct {
-	ip         ImageVerifier
-	copier     ImageCopier
-	downloader ImageDownloader
-	fetcher    Fetcher
-	registry   string
+	ip      ImageVerifier
+	copier  ImageCopier
+	planner ImagePlanner
+	// syncCopier and syncPlanner handle the images of Sync, which are
+	// copied alongside their signatures whether or not they are mirrored.
+	syncCopier  ImageCopier
+	syncPlanner ImagePlanner
+	downloader  ImageDownloader
+	collector   ImageSBOMCollector
+	fetcher     Fetcher
+	registry    string
+
+	progress chan<- ProgressEvent
+	status   *spinner.Spinner
+}
+
+// ProcessorOption configures optional settings of a Processor.
+type ProcessorOption func(*Processor)
+
+// WithConflictPolicy sets how Copy and Sync handle destination tags that
+// point to a different digest. Defaults to ConflictSkip.
+func WithConflictPolicy(policy ConflictPolicy) ProcessorOption {
+	return func(p *Processor) {
+		for _, c := range p.imageCopiers() {
+			c.onConflict = policy
+		}
+	}
 }
 
-func NewProcessor(registry string) *Processor {
-	if !strings.HasSuffix(registry, "/") {
+// WithSBOM sets the format of the SBOMs collected by SBOM, either spdxjson
+// or cyclonedxjson, and how they are generated for images without SBOM
+// attestations. Defaults to spdxjson for linux/amd64.
+func WithSBOM(outformat string, opts sbom.Options) ProcessorOption {
+	return func(p *Processor) {
+		if c, ok := p.collector.(*imageSBOMCollector); ok {
+			c.format = outformat
+			c.opts = opts
+		}
+	}
+}
+
+// WithTransfer sets how Copy and Sync transfer artefacts between registries.
+func WithTransfer(opts TransferOptions) ProcessorOption {
+	return func(p *Processor) {
+		p.progress = opts.Progress
+
+		var transport http.RoundTripper
+		if opts.BandwidthLimit > 0 {
+			transport = newRateLimitedTransport(remote.DefaultTransport, opts.BandwidthLimit)
+		}
+
+		for _, c := range p.imageCopiers() {
+			c.transfer = opts
+			c.transport = transport
+		}
+	}
+}
+
+// imageCopiers returns the copiers of p that can be configured.
+func (p *Processor) imageCopiers() []*imageCopier {
+	var copiers []*imageCopier
+	for _, ic := range []ImageCopier{p.copier, p.syncCopier} {
+		if c, ok := ic.(*imageCopier); ok {
+			copiers = append(copiers, c)
+		}
+	}
+	return copiers
+}
+
+func NewProcessor(registry string, opts ...ProcessorOption) *Processor {
+	// Images within OCI layouts and docker archives are referenced as
+	// <transport>:<path>:<image>, and as docker-daemon:<image> when
+	// no registry is set for the Docker daemon.
+	l, local := attestation.ParseLocalRef(registry)
+	switch {
+	case local && l.Path != "" && l.Reference == "":
+		registry = strings.TrimSuffix(registry, ":") + ":"
+	case registry == attestation.TransportDockerDaemon+":":
+		// Image names are used as they are.
+	case !strings.HasSuffix(registry, "/"):
 		registry = registry + "/"
 	}
 
 	copier := &imageCopier{
 		mirroredOnly: true,
+		onConflict:   ConflictSkip,
+	}
+	syncer := &imageCopier{
+		copyImages: true,
+		onConflict: ConflictSkip,
+	}
+
+	p := &Processor{
+		registry:    registry,
+		ip:          new(imageVerifier),
+		fetcher:     new(HttpFetcher),
+		copier:      copier,
+		planner:     copier,
+		syncCopier:  syncer,
+		syncPlanner: syncer,
+		downloader:  new(imageDownloader),
+		collector: &imageSBOMCollector{
+			format: sbom.FormatSPDXJSON,
+			opts:   sbom.Options{Platform: "linux/amd64"},
+		},
 	}
 
-	return &Processor{
-		registry:   registry,
-		ip:         new(imageVerifier),
-		fetcher:    new(HttpFetcher),
-		copier:     copier,
-		downloader: new(imageDownloader),
+	copier.progress = p.reportProgress
+	syncer.progress = p.reportProgress
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+// reportProgress shows the transfer progress in the current status and
+// forwards it to the progress channel, if one was set. Events are dropped
+// when the channel is not ready, so that slow consumers do not stall copies.
+func (p *Processor) reportProgress(e ProgressEvent) {
+	if p.status != nil {
+		p.status.UpdateStatus(fmt.Sprintf("%s (%s/%s, overall %s/%s)", e.Image,
+			FormatBytes(e.Complete), FormatBytes(e.Total),
+			FormatBytes(e.OverallComplete), FormatBytes(e.OverallTotal)))
+	}
+
+	if p.progress != nil {
+		select {
+		case p.progress <- e:
+		default:
+		}
 	}
 }
 
@@ -84,13 +224,39 @@ func (p *Processor) Copy(url, dstRegistry string) (*Result, error) {
 	})
 }
 
+// Plan resolves what Copy would do for each image without writing anything
+// to the target registry.
+func (p *Processor) Plan(url, dstRegistry string) (*Result, error) {
+	return p.process(url, "Plan copy", dstRegistry, func(img, dstRegistry string) Entry {
+		return p.planner.Plan(img, dstRegistry)
+	})
+}
+
 func (p *Processor) Download(url, outputDir string) (*Result, error) {
 	return p.process(url, "Download attestations", outputDir, func(img, outputDir string) Entry {
 		return p.downloader.Download(img, outputDir)
 	})
 }
 
+// SBOM writes the SBOM of each image to outputDir, as set in Entry.SBOMFile.
+func (p *Processor) SBOM(url, outputDir string) (*Result, error) {
+	return p.process(url, "Collect SBOMs", outputDir, func(img, outputDir string) Entry {
+		return p.collector.Collect(img, outputDir)
+	})
+}
+
 func (p *Processor) process(url, status, dstRegistry string, action func(string, string) Entry) (*Result, error) {
+	images, err := p.fetchImages(url)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.run(images, status, dstRegistry, action), nil
+}
+
+// fetchImages fetches the images list from url and returns its fully
+// qualified image references.
+func (p *Processor) fetchImages(url string) ([]string, error) {
 	url = strings.TrimSpace(url)
 	if len(url) == 0 {
 		return nil, ErrURLCannotBeEmpty
@@ -109,17 +275,13 @@ func (p *Processor) process(url, status, dstRegistry string, action func(string,
 	defer func() {
 		err := r.Close()
 		if err != nil {
-			slog.Error("error closing fetched reader from %q: %w", url, err)
+			slog.Error("error closing fetched reader", "url", url, "error", err)
 		}
 	}()
 
-	result := Result{}
+	var images []string
 
 	scanner := bufio.NewScanner(io.LimitReader(r, maxProcessingSizeInBytes))
-
-	s = spinner.New(status)
-	s.Start()
-
 	for scanner.Scan() {
 		image := strings.TrimSpace(scanner.Text())
 
@@ -138,22 +300,38 @@ func (p *Processor) process(url, status, dstRegistry string, action func(string,
 			image = p.registry + image
 		}
 
-		s.UpdateStatus(image)
-
-		entry := action(image, dstRegistry)
-
-		result.Entries = append(result.Entries, entry)
+		images = append(images, image)
 	}
 
 	err = scanner.Err()
-	s.Stop(err == nil && len(result.Entries) > 0)
 	if err != nil {
 		return nil, fmt.Errorf("error found scanning image list: %w", err)
 	}
 
-	if len(result.Entries) == 0 {
+	if len(images) == 0 {
 		return nil, ErrNoImagesFound
 	}
 
-	return &result, nil
+	return images, nil
+}
+
+func (p *Processor) run(images []string, status, dstRegistry string, action func(string, string) Entry) *Result {
+	result := Result{}
+
+	s := spinner.New(status)
+	s.Start()
+	p.status = s
+	defer func() { p.status = nil }()
+
+	for _, image := range images {
+		s.UpdateStatus(image)
+
+		entry := action(image, dstRegistry)
+
+		result.Entries = append(result.Entries, entry)
+	}
+
+	s.Stop(true)
+
+	return &result
 }
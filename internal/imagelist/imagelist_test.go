@@ -188,6 +188,23 @@ func TestProcess(t *testing.T) {
 	}
 }
 
+func TestNewProcessorLocalRegistry(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		"some.registry":                      "some.registry/",
+		"oci:/tmp/layout":                    "oci:/tmp/layout:",
+		"oci:/tmp/layout:":                   "oci:/tmp/layout:",
+		"docker-archive:images.tar":          "docker-archive:images.tar:",
+		"docker-daemon:":                     "docker-daemon:",
+		"docker-daemon:registry.rancher.com": "docker-daemon:registry.rancher.com/",
+	}
+
+	for registry, want := range tests {
+		assert.Equal(t, want, NewProcessor(registry).registry, registry)
+	}
+}
+
 type DepsMock struct {
 	mock.Mock
 }
@@ -207,3 +224,8 @@ func (m *DepsMock) Fetch(img string) (io.ReadCloser, error) {
 
 	return r, args.Error(1)
 }
+
+func (m *DepsMock) Copy(img, targetRegistry string) Entry {
+	args := m.Called(img, targetRegistry)
+	return args.Get(0).(Entry)
+}
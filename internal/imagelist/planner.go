@@ -0,0 +1,243 @@
+package imagelist
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/partial"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+func (i *imageCopier) Plan(srcImg, dstRegistry string) Entry {
+	entry := Entry{
+		Image: srcImg,
+	}
+
+	if i.mirroredOnly && !strings.Contains(srcImg, "mirrored") {
+		entry.Error = fmt.Errorf("%w: %s", ErrSkippedImage, srcImg)
+		return entry
+	}
+
+	dst, err := targetImage(srcImg, dstRegistry)
+	if err != nil {
+		entry.Error = err
+		return entry
+	}
+
+	err = planCopy(context.TODO(), srcImg, dst, CopyOptions{
+		CopyImage: i.copyImages,
+		Transport: i.transport,
+	}, &entry)
+	if err != nil {
+		entry.Error = err
+	}
+	entry.Signed = (entry.SignatureTag != "")
+
+	return entry
+}
+
+// planCopy resolves the source digest and signature of srcImgRef and
+// compares them with what already exists at dstImgRef, reaching registries
+// as CopySignatureWithOptions would with opts. The bytes that would be
+// transferred are accumulated into the entry.
+func planCopy(ctx context.Context, srcImgRef, dstImgRef string, opts CopyOptions, entry *Entry) error {
+	craneOpts := opts.craneOptions(ctx)
+
+	digest, err := crane.Digest(srcImgRef, craneOpts...)
+	if err != nil {
+		return fmt.Errorf("failed to get signed image digest for %q: %w", srcImgRef, err)
+	}
+	entry.SourceDigest = digest
+
+	sourceRef, err := name.ParseReference(srcImgRef)
+	if err != nil {
+		return fmt.Errorf("failed to parse source image reference: %w", err)
+	}
+
+	targetRef, err := name.ParseReference(dstImgRef)
+	if err != nil {
+		return fmt.Errorf("failed to parse target image reference: %w", err)
+	}
+
+	entry.Status, entry.TargetDigest, err = tagStatus(dstImgRef, digest, craneOpts...)
+	if err != nil {
+		return err
+	}
+
+	if opts.CopyImage && entry.Status == StatusNew {
+		n, err := missingBytes(sourceRef, targetRef.Context(), opts.remoteOptions(ctx)...)
+		if err != nil {
+			return err
+		}
+		entry.MissingBytes += n
+	}
+
+	sourceSigRef, signatureTag, err := findSignature(sourceRef, digest, craneOpts...)
+	if err != nil {
+		return err
+	}
+	entry.SignatureTag = signatureTag
+
+	sigDigest, err := crane.Digest(sourceSigRef, craneOpts...)
+	if err != nil {
+		return fmt.Errorf("failed to get signature digest for %q: %w", sourceSigRef, err)
+	}
+
+	dstSigRef := fmt.Sprintf("%s:%s", targetRef.Context().Name(), signatureTag)
+	entry.SignatureStatus, _, err = tagStatus(dstSigRef, sigDigest, craneOpts...)
+	if err != nil {
+		return err
+	}
+
+	if entry.SignatureStatus == StatusNew {
+		sigRef, err := name.ParseReference(sourceSigRef)
+		if err != nil {
+			return fmt.Errorf("failed to parse signature reference: %w", err)
+		}
+
+		n, err := missingBytes(sigRef, targetRef.Context(), opts.remoteOptions(ctx)...)
+		if err != nil {
+			return err
+		}
+		entry.MissingBytes += n
+	}
+
+	return nil
+}
+
+// tagStatus compares the digest dstRef points to with want.
+func tagStatus(dstRef, want string, opts ...crane.Option) (string, string, error) {
+	got, err := crane.Digest(dstRef, opts...)
+	if err != nil {
+		if isNotFound(err) {
+			return StatusNew, "", nil
+		}
+		return "", "", fmt.Errorf("failed to get digest for %q: %w", dstRef, err)
+	}
+
+	if got == want {
+		return StatusExists, got, nil
+	}
+	return StatusConflict, got, nil
+}
+
+func isNotFound(err error) bool {
+	var terr *transport.Error
+	if errors.As(err, &terr) {
+		return terr.StatusCode == http.StatusNotFound
+	}
+	return false
+}
+
+// missingBytes sums the size of the blobs referenced by src that are
+// not yet present in the dst repository.
+func missingBytes(src name.Reference, dst name.Repository, opts ...remote.Option) (int64, error) {
+	desc, err := remote.Get(src, opts...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch descriptor for %q: %w", src, err)
+	}
+
+	blobs, err := artifactBlobs(desc)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list blobs for %q: %w", src, err)
+	}
+
+	var total int64
+	for _, blob := range blobs {
+		l, err := remote.Layer(dst.Digest(blob.Digest.String()), opts...)
+		if err != nil {
+			return 0, err
+		}
+
+		found, err := partial.Exists(l)
+		if err != nil && !isNotFound(err) {
+			return 0, fmt.Errorf("failed to check blob %s: %w", blob.Digest, err)
+		}
+
+		if !found {
+			total += blob.Size
+		}
+	}
+
+	return total, nil
+}
+
+// artifactBlobs lists the distributable config and layer blobs of an image,
+// or of all images within an index.
+func artifactBlobs(desc *remote.Descriptor) ([]v1.Descriptor, error) {
+	seen := map[v1.Hash]struct{}{}
+	var blobs []v1.Descriptor
+
+	add := func(img v1.Image) error {
+		m, err := img.Manifest()
+		if err != nil {
+			return err
+		}
+
+		for _, d := range append([]v1.Descriptor{m.Config}, m.Layers...) {
+			if _, ok := seen[d.Digest]; ok || !d.MediaType.IsDistributable() {
+				continue
+			}
+			seen[d.Digest] = struct{}{}
+			blobs = append(blobs, d)
+		}
+		return nil
+	}
+
+	if !desc.MediaType.IsIndex() {
+		img, err := desc.Image()
+		if err != nil {
+			return nil, err
+		}
+		return blobs, add(img)
+	}
+
+	idx, err := desc.ImageIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	err = walkIndex(idx, add)
+	return blobs, err
+}
+
+func walkIndex(idx v1.ImageIndex, fn func(v1.Image) error) error {
+	m, err := idx.IndexManifest()
+	if err != nil {
+		return err
+	}
+
+	for _, d := range m.Manifests {
+		if d.MediaType.IsIndex() {
+			child, err := idx.ImageIndex(d.Digest)
+			if err != nil {
+				return err
+			}
+
+			err = walkIndex(child, fn)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		img, err := idx.Image(d.Digest)
+		if err != nil {
+			return err
+		}
+
+		err = fn(img)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,203 @@
+package imagelist
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlan(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		image       string
+		copyImages  bool
+		noSignature bool
+		setup       func(t *testing.T, src, dst string)
+		want        Entry
+		wantBytes   bool
+		wantErr     error
+	}{
+		{
+			name:  "new image and signature",
+			image: "rancher/mirrored-foo:v1",
+			want: Entry{
+				Signed:          true,
+				Status:          StatusNew,
+				SignatureStatus: StatusNew,
+			},
+			wantBytes: true,
+		},
+		{
+			name:       "new image and signature with image copy",
+			image:      "rancher/mirrored-foo:v1",
+			copyImages: true,
+			want: Entry{
+				Signed:          true,
+				Status:          StatusNew,
+				SignatureStatus: StatusNew,
+			},
+			wantBytes: true,
+		},
+		{
+			name:  "already copied",
+			image: "rancher/mirrored-foo:v1",
+			setup: func(t *testing.T, src, dst string) {
+				err := CopySignature(context.Background(), src, dst, true)
+				require.NoError(t, err)
+			},
+			want: Entry{
+				Signed:          true,
+				Status:          StatusExists,
+				SignatureStatus: StatusExists,
+			},
+		},
+		{
+			name:  "conflicting target tag",
+			image: "rancher/mirrored-foo:v1",
+			setup: func(t *testing.T, _, dst string) {
+				pushRandom(t, dst)
+			},
+			want: Entry{
+				Signed:          true,
+				Status:          StatusConflict,
+				SignatureStatus: StatusNew,
+			},
+			wantBytes: true,
+		},
+		{
+			name:        "missing signature",
+			image:       "rancher/mirrored-foo:v1",
+			noSignature: true,
+			wantErr:     ErrNoSignaturesFound,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			srcRegistry := newTestRegistry(t)
+			dstRegistry := newTestRegistry(t)
+
+			src := srcRegistry + "/" + tc.image
+			digest := pushRandom(t, src)
+			if !tc.noSignature {
+				pushRandom(t, srcRegistry+"/"+strings.Split(tc.image, ":")[0]+":"+
+					strings.Replace(digest, ":", "-", 1)+".sig")
+			}
+
+			dst, err := targetImage(src, dstRegistry)
+			require.NoError(t, err)
+
+			if tc.setup != nil {
+				tc.setup(t, src, dst)
+			}
+
+			sut := &imageCopier{mirroredOnly: true, copyImages: tc.copyImages}
+			got := sut.Plan(src, dstRegistry)
+
+			if tc.wantErr != nil {
+				require.ErrorIs(t, got.Error, tc.wantErr)
+				return
+			}
+
+			require.NoError(t, got.Error)
+			assert.Equal(t, digest, got.SourceDigest)
+			assert.Equal(t, tc.want.Signed, got.Signed)
+			assert.Equal(t, tc.want.Status, got.Status)
+			assert.Equal(t, tc.want.SignatureStatus, got.SignatureStatus)
+			assert.Equal(t, tc.wantBytes, got.MissingBytes > 0)
+
+			if got.Status == StatusConflict {
+				assert.NotEmpty(t, got.TargetDigest)
+				assert.NotEqual(t, got.SourceDigest, got.TargetDigest)
+			}
+		})
+	}
+}
+
+func TestPlanSkipsNonMirrored(t *testing.T) {
+	t.Parallel()
+
+	sut := &imageCopier{mirroredOnly: true}
+	got := sut.Plan("registry.example.com/rancher/rancher:v2.12.2", "127.0.0.1:5000")
+
+	require.Error(t, got.Error)
+	assert.Empty(t, got.Status)
+}
+
+func TestPlanUsesCopierTransport(t *testing.T) {
+	t.Parallel()
+
+	srcRegistry := newTestRegistry(t)
+	dstRegistry := newTestRegistry(t)
+
+	src := srcRegistry + "/rancher/mirrored-foo:v1"
+	digest := pushRandom(t, src)
+	pushRandom(t, srcRegistry+"/rancher/mirrored-foo:"+strings.Replace(digest, ":", "-", 1)+".sig")
+
+	rt := &countingTransport{base: http.DefaultTransport}
+	sut := &imageCopier{mirroredOnly: true, copyImages: true, transport: rt}
+	got := sut.Plan(src, dstRegistry)
+
+	require.NoError(t, got.Error)
+	assert.Positive(t, got.MissingBytes)
+
+	// Digests, signatures and the blobs missing in the target are all
+	// resolved through the copier transport.
+	assert.Greater(t, rt.blobs.Load(), int32(0))
+	assert.Greater(t, rt.manifests.Load(), int32(0))
+}
+
+type countingTransport struct {
+	base      http.RoundTripper
+	manifests atomic.Int32
+	blobs     atomic.Int32
+}
+
+func (c *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	switch {
+	case strings.Contains(req.URL.Path, "/manifests/"):
+		c.manifests.Add(1)
+	case strings.Contains(req.URL.Path, "/blobs/"):
+		c.blobs.Add(1)
+	}
+	return c.base.RoundTrip(req)
+}
+
+func newTestRegistry(t *testing.T) string {
+	t.Helper()
+
+	srv := httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", 0))))
+	t.Cleanup(srv.Close)
+
+	return strings.TrimPrefix(srv.URL, "http://")
+}
+
+func pushRandom(t *testing.T, ref string) string {
+	t.Helper()
+
+	img, err := random.Image(1024, 2)
+	require.NoError(t, err)
+
+	err = crane.Push(img, ref)
+	require.NoError(t, err)
+
+	digest, err := img.Digest()
+	require.NoError(t, err)
+
+	return digest.String()
+}
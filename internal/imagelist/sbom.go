@@ -0,0 +1,45 @@
+package imagelist
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/rancherlabs/slsactl/internal/sbom"
+)
+
+type imageSBOMCollector struct {
+	m sync.Mutex
+
+	format string
+	opts   sbom.Options
+}
+
+// Collect writes the SBOM of img to outputDir, generating it when img has
+// no SBOM attestation for the platform set in the options.
+func (c *imageSBOMCollector) Collect(img, outputDir string) Entry {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	entry := Entry{
+		Image:    img,
+		Platform: c.opts.Platform,
+	}
+
+	doc, generated, err := sbom.ForImage(img, c.format, c.opts)
+	if err != nil {
+		entry.Error = err
+		return entry
+	}
+	entry.SBOMGenerated = generated
+
+	sbomFile := filepath.Join(outputDir, sanitizeImageName(img)+"_sbom"+sbom.Extension(c.format))
+	err = os.WriteFile(sbomFile, doc, 0o600)
+	if err != nil {
+		entry.Error = err
+		return entry
+	}
+	entry.SBOMFile = sbomFile
+
+	return entry
+}
@@ -0,0 +1,56 @@
+package imagelist
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/rancherlabs/slsactl/internal/sbom"
+	"github.com/rancherlabs/slsactl/pkg/attestation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollectSBOM(t *testing.T) {
+	t.Parallel()
+
+	ref := newTestRegistry(t) + "/rancher/foo:v1"
+	digest := pushRandom(t, ref)
+
+	predicate := `{"spdxVersion":"SPDX-2.3","SPDXID":"SPDXRef-DOCUMENT","name":"foo"}`
+	statement := `{"subject":[{"name":"foo","digest":{"sha256":"` + strings.TrimPrefix(digest, "sha256:") + `"}}],` +
+		`"predicateType":"https://spdx.dev/Document","predicate":` + predicate + `}`
+	env, err := json.Marshal(attestation.Envelope{
+		PayloadType: attestation.MediaTypeInToto,
+		Payload:     base64.StdEncoding.EncodeToString([]byte(statement)),
+	})
+	require.NoError(t, err)
+
+	att, err := mutate.AppendLayers(empty.Image, static.NewLayer(env, attestation.MediaTypeDSSE))
+	require.NoError(t, err)
+
+	r, err := name.ParseReference(ref)
+	require.NoError(t, err)
+	attTag := r.Context().Tag(strings.Replace(digest, ":", "-", 1) + ".att")
+	require.NoError(t, crane.Push(att, attTag.String()))
+
+	c := &imageSBOMCollector{
+		format: sbom.FormatSPDXJSON,
+		opts:   sbom.Options{Platform: "linux/amd64"},
+	}
+	got := c.Collect(ref, t.TempDir())
+	require.NoError(t, got.Error)
+	assert.False(t, got.SBOMGenerated)
+	require.True(t, strings.HasSuffix(got.SBOMFile, "_sbom.spdx.json"), got.SBOMFile)
+
+	data, err := os.ReadFile(got.SBOMFile)
+	require.NoError(t, err)
+	assert.JSONEq(t, predicate, string(data))
+}
@@ -0,0 +1,278 @@
+package imagelist
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+// Images fetches the images list from url and returns its fully qualified
+// image references.
+func (p *Processor) Images(url string) ([]string, error) {
+	return p.fetchImages(url)
+}
+
+// Sync copies images and their signatures into dstRegistry, processing
+// duplicated images only once. Unlike Copy, images are copied whether or not
+// they are mirrored. Manifests, blobs and signatures already present in the
+// target are not copied again. When dryRun is set, the copy is only planned.
+func (p *Processor) Sync(images []string, dstRegistry string, dryRun bool) (*Result, error) {
+	seen := map[string]struct{}{}
+	var unique []string
+	for _, img := range images {
+		if _, ok := seen[img]; ok {
+			continue
+		}
+		seen[img] = struct{}{}
+		unique = append(unique, img)
+	}
+
+	if len(unique) == 0 {
+		return nil, ErrNoImagesFound
+	}
+
+	if dryRun {
+		return p.run(unique, "Plan sync", dstRegistry, p.syncPlanner.Plan), nil
+	}
+	return p.run(unique, "Sync images", dstRegistry, p.syncCopier.Copy), nil
+}
+
+type pruneState struct {
+	repo    name.Repository
+	tags    map[string]struct{}
+	digests map[string]struct{}
+	failed  bool
+}
+
+// PruneTags removes the tags from the dstRegistry repositories used by entries
+// that are not referenced by them, either directly or as one of their
+// signature or attestation tags. Repositories with entries that failed to
+// resolve are left untouched. When dryRun is set, tags are only listed.
+func PruneTags(ctx context.Context, dstRegistry string, entries []Entry, dryRun bool) ([]string, error) {
+	repos := map[string]*pruneState{}
+	for _, e := range entries {
+		if errors.Is(e.Error, ErrSkippedImage) {
+			continue
+		}
+
+		dst, err := targetImage(e.Image, dstRegistry)
+		if err != nil {
+			return nil, err
+		}
+
+		ref, err := name.ParseReference(dst)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse target image reference: %w", err)
+		}
+
+		st, ok := repos[ref.Context().Name()]
+		if !ok {
+			st = &pruneState{
+				repo:    ref.Context(),
+				tags:    map[string]struct{}{},
+				digests: map[string]struct{}{},
+			}
+			repos[ref.Context().Name()] = st
+		}
+
+		st.tags[ref.Identifier()] = struct{}{}
+		if e.SourceDigest == "" {
+			st.failed = true
+			continue
+		}
+		st.digests[e.SourceDigest] = struct{}{}
+	}
+
+	var pruned []string
+	for _, key := range slices.Sorted(maps.Keys(repos)) {
+		st := repos[key]
+		if st.failed {
+			slog.Warn("skipping prune of repository with unresolved images", "repository", key)
+			continue
+		}
+
+		p, err := pruneRepository(ctx, st, dryRun)
+		pruned = append(pruned, p...)
+		if err != nil {
+			return pruned, err
+		}
+	}
+
+	return pruned, nil
+}
+
+func pruneRepository(ctx context.Context, st *pruneState, dryRun bool) ([]string, error) {
+	tags, err := crane.ListTags(st.repo.Name(), crane.WithContext(ctx))
+	if err != nil {
+		if isNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to list tags for %q: %w", st.repo.Name(), err)
+	}
+
+	// Manifests reachable from the tags being kept, including the platform
+	// manifests of their indexes, must not be deleted by digest.
+	keptManifests := map[string]struct{}{}
+	for _, tag := range tags {
+		if _, ok := st.tags[tag]; !ok {
+			continue
+		}
+
+		err := addManifests(ctx, st.repo.Tag(tag), keptManifests)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// The signatures and attestations of the platform images are kept
+	// alongside their index.
+	maps.Copy(st.digests, keptManifests)
+
+	var candidates []string
+	for _, tag := range tags {
+		if _, ok := st.tags[tag]; ok {
+			continue
+		}
+		if !st.keep(tag) {
+			candidates = append(candidates, tag)
+			continue
+		}
+
+		err := addManifests(ctx, st.repo.Tag(tag), keptManifests)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var pruned []string
+	for _, tag := range candidates {
+		ref := st.repo.Tag(tag).String()
+		if dryRun {
+			pruned = append(pruned, ref)
+			continue
+		}
+
+		ok, err := deleteTag(ctx, st.repo, tag, keptManifests)
+		if err != nil {
+			return pruned, err
+		}
+		if ok {
+			pruned = append(pruned, ref)
+		}
+	}
+
+	return pruned, nil
+}
+
+// deleteTag removes tag from repo. Registries without support for tag
+// deletion require the manifest to be deleted instead, which is only done
+// when it is not reachable from any of the tags being kept.
+func deleteTag(ctx context.Context, repo name.Repository, tag string, keptManifests map[string]struct{}) (bool, error) {
+	ref := repo.Tag(tag).String()
+	digest, err := crane.Digest(ref, crane.WithContext(ctx))
+	if err != nil {
+		if isNotFound(err) {
+			// Already removed alongside a manifest shared with another tag.
+			return true, nil
+		}
+		return false, fmt.Errorf("failed to get digest for %q: %w", ref, err)
+	}
+
+	err = crane.Delete(ref, crane.WithContext(ctx))
+	if err == nil {
+		return true, nil
+	}
+
+	if _, ok := keptManifests[digest]; ok {
+		slog.Warn("cannot prune tag pointing to a manifest of a tracked tag", "tag", ref, "error", err)
+		return false, nil
+	}
+
+	err = crane.Delete(repo.Digest(digest).String(), crane.WithContext(ctx))
+	if err != nil {
+		return false, fmt.Errorf("failed to delete %q: %w", ref, err)
+	}
+
+	return true, nil
+}
+
+// addManifests adds the digest of the manifest ref points to into manifests,
+// along with the digests of all the manifests within it when it is an index.
+func addManifests(ctx context.Context, ref name.Reference, manifests map[string]struct{}) error {
+	desc, err := crane.Get(ref.String(), crane.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to fetch descriptor for %q: %w", ref, err)
+	}
+	manifests[desc.Digest.String()] = struct{}{}
+
+	if !desc.MediaType.IsIndex() {
+		return nil
+	}
+
+	idx, err := desc.ImageIndex()
+	if err != nil {
+		return fmt.Errorf("failed to get image index for %q: %w", ref, err)
+	}
+
+	err = walkManifests(idx, func(d v1.Descriptor) {
+		manifests[d.Digest.String()] = struct{}{}
+	})
+	if err != nil {
+		return fmt.Errorf("failed to walk image index for %q: %w", ref, err)
+	}
+	return nil
+}
+
+// walkManifests calls fn for the descriptors of all the manifests within
+// idx, including those of nested indexes.
+func walkManifests(idx v1.ImageIndex, fn func(v1.Descriptor)) error {
+	m, err := idx.IndexManifest()
+	if err != nil {
+		return err
+	}
+
+	for _, d := range m.Manifests {
+		fn(d)
+		if !d.MediaType.IsIndex() {
+			continue
+		}
+
+		child, err := idx.ImageIndex(d.Digest)
+		if err != nil {
+			return err
+		}
+
+		err = walkManifests(child, fn)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// keep checks whether tag is an image tag or a cosign tag (sha256-<hex>,
+// optionally suffixed by .sig, .att or .sbom) of a tracked digest, which
+// includes the platform manifests of tracked indexes.
+func (st *pruneState) keep(tag string) bool {
+	if _, ok := st.tags[tag]; ok {
+		return true
+	}
+
+	hex, ok := strings.CutPrefix(tag, "sha256-")
+	if !ok {
+		return false
+	}
+
+	hex, _, _ = strings.Cut(hex, ".")
+	_, ok = st.digests["sha256:"+hex]
+	return ok
+}
@@ -0,0 +1,234 @@
+package imagelist
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSyncDeduplicates(t *testing.T) {
+	t.Parallel()
+
+	m := new(DepsMock)
+	m.On("Copy", "some.registry/image:v1", "target").
+		Return(Entry{Image: "some.registry/image:v1"}).Once()
+	m.On("Copy", "some.registry/image:v2", "target").
+		Return(Entry{Image: "some.registry/image:v2"}).Once()
+
+	sut := NewProcessor("some.registry")
+	sut.syncCopier = m
+
+	got, err := sut.Sync([]string{
+		"some.registry/image:v1",
+		"some.registry/image:v2",
+		"some.registry/image:v1",
+	}, "target", false)
+
+	require.NoError(t, err)
+	assert.Len(t, got.Entries, 2)
+	m.AssertExpectations(t)
+
+	_, err = sut.Sync(nil, "target", false)
+	require.ErrorIs(t, err, ErrNoImagesFound)
+}
+
+func TestSyncCopiesImages(t *testing.T) {
+	t.Parallel()
+
+	srcRegistry := newTestRegistry(t)
+	dstRegistry := newTestRegistry(t)
+
+	// Sync is not restricted to mirrored images.
+	src := srcRegistry + "/rancher/foo:v1"
+	digest := pushRandom(t, src)
+	pushRandom(t, srcRegistry+"/rancher/foo:"+cosignTag(digest)+".sig")
+
+	sut := NewProcessor(srcRegistry)
+
+	plan, err := sut.Sync([]string{src}, dstRegistry, true)
+	require.NoError(t, err)
+	require.Len(t, plan.Entries, 1)
+	require.NoError(t, plan.Entries[0].Error)
+	assert.Equal(t, StatusNew, plan.Entries[0].Status)
+	assert.Positive(t, plan.Entries[0].MissingBytes)
+
+	got, err := sut.Sync([]string{src}, dstRegistry, false)
+	require.NoError(t, err)
+	require.Len(t, got.Entries, 1)
+	require.NoError(t, got.Entries[0].Error)
+	assert.Equal(t, StatusCopied, got.Entries[0].Status)
+	assert.Equal(t, StatusCopied, got.Entries[0].SignatureStatus)
+
+	dst, err := targetImage(src, dstRegistry)
+	require.NoError(t, err)
+	dstDigest, err := crane.Digest(dst)
+	require.NoError(t, err)
+	assert.Equal(t, digest, dstDigest)
+
+	got, err = sut.Sync([]string{src}, dstRegistry, false)
+	require.NoError(t, err)
+	assert.Equal(t, StatusExists, got.Entries[0].Status)
+	assert.Equal(t, StatusExists, got.Entries[0].SignatureStatus)
+}
+
+func TestPruneTags(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		dryRun       bool
+		noDigest     bool
+		noTagDelete  bool
+		wantPruned   []string
+		wantRemoved  bool
+		wantUntagged bool
+	}{
+		{
+			name:        "prune untracked tags",
+			wantPruned:  []string{"v0", "sha256-old.sig", "v1-amd64"},
+			wantRemoved: true,
+		},
+		{
+			name:       "dry run",
+			dryRun:     true,
+			wantPruned: []string{"v0", "sha256-old.sig", "v1-amd64"},
+		},
+		{
+			name:     "skip repositories with unresolved images",
+			noDigest: true,
+		},
+		{
+			// Platform manifests of the tracked index cannot be deleted
+			// by digest, so their tags are left behind.
+			name:         "registry without tag deletion",
+			noTagDelete:  true,
+			wantPruned:   []string{"v0", "sha256-old.sig"},
+			wantUntagged: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			dstRegistry := newTestRegistry(t)
+			if tc.noTagDelete {
+				dstRegistry = newTestRegistryWithoutTagDeletion(t)
+			}
+			repo := dstRegistry + "/rancher/mirrored-foo"
+
+			current, children := pushRandomIndex(t, repo+":v1")
+			pushRandom(t, repo+":"+cosignTag(current)+".sig")
+			pushRandom(t, repo+":"+cosignTag(children[0])+".sig")
+			pushRandom(t, repo+":"+cosignTag(children[1])+".att")
+			require.NoError(t, crane.Tag(repo+"@"+children[0], "v1-amd64"))
+			old := pushRandom(t, repo+":v0")
+			oldSig := cosignTag(old) + ".sig"
+			pushRandom(t, repo+":"+oldSig)
+
+			entry := Entry{Image: "registry.example.com/rancher/mirrored-foo:v1"}
+			if !tc.noDigest {
+				entry.SourceDigest = current
+			}
+
+			got, err := PruneTags(context.Background(), dstRegistry, []Entry{
+				entry,
+				{Image: "registry.example.com/rancher/rancher:v1", Error: ErrSkippedImage},
+			}, tc.dryRun)
+			require.NoError(t, err)
+
+			want := make([]string, 0, len(tc.wantPruned))
+			for _, tag := range tc.wantPruned {
+				if tag == "sha256-old.sig" {
+					tag = oldSig
+				}
+				want = append(want, repo+":"+tag)
+			}
+			assert.ElementsMatch(t, want, got)
+
+			// The tracked index remains complete.
+			for _, d := range append(children, current) {
+				_, err = crane.Manifest(repo + "@" + d)
+				require.NoError(t, err, d)
+			}
+
+			tags, err := crane.ListTags(repo)
+			require.NoError(t, err)
+			switch {
+			case tc.wantRemoved:
+				assert.ElementsMatch(t, []string{
+					"v1",
+					cosignTag(current) + ".sig",
+					cosignTag(children[0]) + ".sig",
+					cosignTag(children[1]) + ".att",
+				}, tags)
+			case tc.wantUntagged:
+				// Manifests deleted by digest are left tagged by the
+				// test registry.
+				assert.Contains(t, tags, "v1-amd64")
+			default:
+				assert.Len(t, tags, 7)
+			}
+		})
+	}
+}
+
+// newTestRegistryWithoutTagDeletion returns a registry that, as some
+// registries do, only supports deleting manifests by digest.
+func newTestRegistryWithoutTagDeletion(t *testing.T) string {
+	t.Helper()
+
+	reg := registry.New(registry.Logger(log.New(io.Discard, "", 0)))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete && !strings.Contains(r.URL.Path, "/manifests/sha256:") {
+			http.Error(w, "tag deletion is not supported", http.StatusMethodNotAllowed)
+			return
+		}
+		reg.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	return strings.TrimPrefix(srv.URL, "http://")
+}
+
+// pushRandomIndex pushes an index of two images to ref, returning the
+// digests of the index and of its images.
+func pushRandomIndex(t *testing.T, ref string) (string, []string) {
+	t.Helper()
+
+	idx, err := random.Index(1024, 1, 2)
+	require.NoError(t, err)
+
+	tag, err := name.NewTag(ref)
+	require.NoError(t, err)
+	require.NoError(t, remote.WriteIndex(tag, idx))
+
+	digest, err := idx.Digest()
+	require.NoError(t, err)
+
+	m, err := idx.IndexManifest()
+	require.NoError(t, err)
+
+	children := make([]string, 0, len(m.Manifests))
+	for _, d := range m.Manifests {
+		children = append(children, d.Digest.String())
+	}
+
+	return digest.String(), children
+}
+
+func cosignTag(digest string) string {
+	return strings.Replace(digest, ":", "-", 1)
+}
@@ -0,0 +1,194 @@
+package imagelist
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"golang.org/x/time/rate"
+)
+
+// DefaultConcurrency is the number of concurrent blob transfers per registry
+// used when no limit is set.
+const DefaultConcurrency = 4
+
+var (
+	// ErrInvalidByteSize indicates a byte size that cannot be parsed.
+	ErrInvalidByteSize = errors.New("invalid byte size")
+	// ErrInvalidConcurrency indicates a concurrency limit that cannot be parsed.
+	ErrInvalidConcurrency = errors.New("invalid concurrency")
+)
+
+// ProgressEvent reports the bytes written to the target registry while
+// copying images. Complete and Total refer to Artifact, while the overall
+// counters accumulate all the artefacts copied by the Processor so far.
+type ProgressEvent struct {
+	Image           string `json:"image"`
+	Artifact        string `json:"artifact"`
+	Complete        int64  `json:"complete"`
+	Total           int64  `json:"total"`
+	OverallComplete int64  `json:"overallComplete"`
+	OverallTotal    int64  `json:"overallTotal"`
+}
+
+// TransferOptions tunes how artefacts are transferred between registries.
+type TransferOptions struct {
+	// BandwidthLimit caps the bytes per second across all transfers, in
+	// each direction: downloads from and uploads to registries are limited
+	// separately, so a copy between registries runs at up to that rate.
+	// Zero means no limit.
+	BandwidthLimit int64
+	// Concurrency limits the concurrent blob transfers for registries not
+	// set in RegistryConcurrency. Defaults to DefaultConcurrency.
+	Concurrency int
+	// RegistryConcurrency limits the concurrent blob transfers per registry.
+	RegistryConcurrency map[string]int
+	// Progress receives byte-level updates. The channel is never closed by
+	// the Processor. Updates are dropped rather than stalling transfers when
+	// the channel is not ready to receive them, so it should be buffered.
+	Progress chan<- ProgressEvent
+}
+
+// jobs returns the concurrency limit for a copy between the registries of
+// src and dst, which is the lowest limit of both.
+func (t TransferOptions) jobs(src, dst string) int {
+	jobs := 0
+	for _, img := range []string{src, dst} {
+		limit := t.Concurrency
+		ref, err := name.ParseReference(img, name.WeakValidation)
+		if err == nil {
+			if n, ok := t.RegistryConcurrency[ref.Context().RegistryStr()]; ok {
+				limit = n
+			}
+		}
+
+		if limit <= 0 {
+			limit = DefaultConcurrency
+		}
+		if jobs == 0 || limit < jobs {
+			jobs = limit
+		}
+	}
+	return jobs
+}
+
+// ParseByteSize parses sizes such as 512K, 50MiB or 1G into bytes, using
+// binary units.
+func ParseByteSize(s string) (int64, error) {
+	v := strings.ToUpper(strings.TrimSpace(s))
+	v = strings.TrimSuffix(strings.TrimSuffix(v, "B"), "I")
+
+	multiplier := int64(1)
+	if n := len(v); n > 0 {
+		if i := strings.IndexByte("KMGT", v[n-1]); i >= 0 {
+			multiplier = 1 << (10 * (i + 1))
+			v = v[:n-1]
+		}
+	}
+
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%w %q: expected a positive number with an optional K, M, G or T suffix", ErrInvalidByteSize, s)
+	}
+
+	return n * multiplier, nil
+}
+
+// ParseRegistryConcurrency parses comma separated <registry>=<limit> pairs.
+func ParseRegistryConcurrency(s string) (map[string]int, error) {
+	limits := map[string]int{}
+	for pair := range strings.SplitSeq(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		registry, limit, ok := strings.Cut(pair, "=")
+		n, err := strconv.Atoi(limit)
+		if !ok || registry == "" || err != nil || n < 1 {
+			return nil, fmt.Errorf("%w %q: format expected <registry>=<limit>", ErrInvalidConcurrency, pair)
+		}
+
+		limits[registry] = n
+	}
+	return limits, nil
+}
+
+// newRateLimitedTransport returns a transport that caps the bytes per second
+// sent in requests and read from responses, shared across all connections.
+// Each direction has its own limit, so that copies which download and upload
+// every byte are not slowed down to half the rate.
+func newRateLimitedTransport(base http.RoundTripper, bytesPerSecond int64) http.RoundTripper {
+	burst := int(min(bytesPerSecond, 1<<20))
+	return &rateLimitedTransport{
+		base:    base,
+		egress:  rate.NewLimiter(rate.Limit(bytesPerSecond), burst),
+		ingress: rate.NewLimiter(rate.Limit(bytesPerSecond), burst),
+	}
+}
+
+type rateLimitedTransport struct {
+	base    http.RoundTripper
+	egress  *rate.Limiter
+	ingress *rate.Limiter
+}
+
+func (t *rateLimitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil && req.Body != http.NoBody {
+		req = req.Clone(req.Context())
+		req.Body = &rateLimitedReader{ctx: req.Context(), rc: req.Body, limiter: t.egress}
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Body = &rateLimitedReader{ctx: req.Context(), rc: resp.Body, limiter: t.ingress}
+	return resp, nil
+}
+
+type rateLimitedReader struct {
+	ctx     context.Context
+	rc      io.ReadCloser
+	limiter *rate.Limiter
+}
+
+func (r *rateLimitedReader) Read(p []byte) (int, error) {
+	if burst := r.limiter.Burst(); len(p) > burst {
+		p = p[:burst]
+	}
+
+	n, err := r.rc.Read(p)
+	if n > 0 {
+		werr := r.limiter.WaitN(r.ctx, n)
+		if werr != nil && err == nil {
+			err = werr
+		}
+	}
+	return n, err
+}
+
+func (r *rateLimitedReader) Close() error {
+	return r.rc.Close()
+}
+
+// FormatBytes returns a human-readable representation of n using binary units.
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
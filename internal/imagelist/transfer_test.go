@@ -0,0 +1,197 @@
+package imagelist
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseByteSize(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		input   string
+		want    int64
+		wantErr bool
+	}{
+		{input: "100", want: 100},
+		{input: "512K", want: 512 << 10},
+		{input: "50MiB", want: 50 << 20},
+		{input: "50mb", want: 50 << 20},
+		{input: "1G", want: 1 << 30},
+		{input: "", wantErr: true},
+		{input: "-1M", wantErr: true},
+		{input: "fast", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ParseByteSize(tc.input)
+			if tc.wantErr {
+				require.ErrorIs(t, err, ErrInvalidByteSize)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestParseRegistryConcurrency(t *testing.T) {
+	t.Parallel()
+
+	got, err := ParseRegistryConcurrency("registry.example.com=2, 127.0.0.1:5000=8")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"registry.example.com": 2, "127.0.0.1:5000": 8}, got)
+
+	got, err = ParseRegistryConcurrency("")
+	require.NoError(t, err)
+	assert.Empty(t, got)
+
+	for _, input := range []string{"registry.example.com", "registry.example.com=0", "=2"} {
+		_, err = ParseRegistryConcurrency(input)
+		require.ErrorIs(t, err, ErrInvalidConcurrency, input)
+	}
+}
+
+func TestTransferJobs(t *testing.T) {
+	t.Parallel()
+
+	opts := TransferOptions{
+		Concurrency: 6,
+		RegistryConcurrency: map[string]int{
+			"target.example.com": 2,
+		},
+	}
+
+	assert.Equal(t, 6, opts.jobs("source.example.com/rancher/foo:v1", "other.example.com/rancher/foo:v1"))
+	assert.Equal(t, 2, opts.jobs("source.example.com/rancher/foo:v1", "target.example.com/rancher/foo:v1"))
+	assert.Equal(t, DefaultConcurrency, TransferOptions{}.jobs("a.example.com/foo:v1", "b.example.com/foo:v1"))
+}
+
+func TestRateLimitedTransport(t *testing.T) {
+	t.Parallel()
+
+	payload := strings.Repeat("x", 4096)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, payload)
+	}))
+	t.Cleanup(srv.Close)
+
+	client := &http.Client{Transport: newRateLimitedTransport(http.DefaultTransport, 8192)}
+
+	start := time.Now()
+	for range 3 {
+		resp, err := client.Get(srv.URL)
+		require.NoError(t, err)
+
+		data, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+		assert.Equal(t, payload, string(data))
+	}
+
+	// The first 8KiB are served from the initial burst, the remaining 4KiB
+	// must wait for the limiter.
+	assert.GreaterOrEqual(t, time.Since(start), 400*time.Millisecond)
+}
+
+func TestRateLimitedTransportDirections(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		_, _ = w.Write(data)
+	}))
+	t.Cleanup(srv.Close)
+
+	client := &http.Client{Transport: newRateLimitedTransport(http.DefaultTransport, 8192)}
+	payload := strings.Repeat("x", 8192)
+
+	start := time.Now()
+	resp, err := client.Post(srv.URL, "text/plain", strings.NewReader(payload))
+	require.NoError(t, err)
+
+	data, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	assert.Len(t, data, len(payload))
+
+	// Uploads and downloads are limited separately, so both are served
+	// from their initial burst.
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
+
+func TestReportProgressDropsEvents(t *testing.T) {
+	t.Parallel()
+
+	progress := make(chan ProgressEvent, 1)
+	p := NewProcessor("some.registry", WithTransfer(TransferOptions{Progress: progress}))
+
+	// Nobody reads the channel, which must not stall the copy.
+	p.reportProgress(ProgressEvent{Image: "a"})
+	p.reportProgress(ProgressEvent{Image: "b"})
+
+	assert.Equal(t, "a", (<-progress).Image)
+	assert.Empty(t, progress)
+}
+
+func TestCopySignatureProgress(t *testing.T) {
+	t.Parallel()
+
+	srcRegistry := newTestRegistry(t)
+	dstRegistry := newTestRegistry(t)
+
+	src := srcRegistry + "/rancher/mirrored-foo:v1"
+	digest := pushRandom(t, src)
+	pushRandom(t, srcRegistry+"/rancher/mirrored-foo:"+strings.Replace(digest, ":", "-", 1)+".sig")
+
+	dst, err := targetImage(src, dstRegistry)
+	require.NoError(t, err)
+
+	var events []ProgressEvent
+	sut := &imageCopier{copyImages: true, onConflict: ConflictSkip}
+	sut.progress = func(e ProgressEvent) {
+		events = append(events, e)
+	}
+
+	report, err := CopySignatureWithOptions(context.Background(), src, dst, CopyOptions{
+		CopyImage: true,
+		Progress:  sut.progressFunc(src),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, StatusCopied, report.ImageStatus)
+
+	require.NotEmpty(t, events)
+	last := events[len(events)-1]
+	assert.Equal(t, src, last.Image)
+	assert.Equal(t, last.OverallTotal, last.OverallComplete)
+	assert.Positive(t, last.OverallTotal)
+
+	var artifacts []string
+	for _, e := range events {
+		assert.LessOrEqual(t, e.Complete, e.Total)
+		if len(artifacts) == 0 || artifacts[len(artifacts)-1] != e.Artifact {
+			artifacts = append(artifacts, e.Artifact)
+		}
+	}
+	assert.Len(t, artifacts, 2, "image and signature progress")
+}
+
+func TestFormatBytes(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "512 B", FormatBytes(512))
+	assert.Equal(t, "1.5 KiB", FormatBytes(1536))
+	assert.Equal(t, "2.0 GiB", FormatBytes(2<<30))
+}
@@ -15,9 +15,21 @@ import (
 
 const dirMode = 0o700
 
+// tempDirPrefix prefixes the temp dir of each run.
+const tempDirPrefix = "slsactl-"
+
 // EnforceOrDie checks whether or not to enforce the landlock policy, and if so,
 // apply it. Any error will result in os.Exit.
-func EnforceOrDie() {
+//
+// localPaths are granted read access, so that images in local OCI layouts
+// and docker archives can be read. writablePaths are created when missing
+// and granted write access, as needed by the temporary and cache
+// directories of SBOM generation.
+//
+// Rather than granting write access to os.TempDir(), the temporary files of
+// the run are held by a directory of its own, created in os.TempDir() and
+// exported as TMPDIR. The returned function removes it.
+func EnforceOrDie(localPaths, writablePaths []string) (cleanup func()) {
 	val, _ := os.LookupEnv("LANDLOCK_MODE")
 	cfg := landlock.V5
 
@@ -29,7 +41,7 @@ func EnforceOrDie() {
 		slog.Debug("landlock set to best effort")
 	default:
 		slog.Debug("landlock disabled")
-		return
+		return func() {}
 	}
 
 	home, err := os.UserHomeDir()
@@ -38,10 +50,31 @@ func EnforceOrDie() {
 		os.Exit(1)
 	}
 
+	parent := os.TempDir()
+	tmp, err := os.MkdirTemp(parent, tempDirPrefix)
+	if err != nil {
+		fmt.Printf("failed to create temp dir: %v", err)
+		os.Exit(1)
+	}
+	cleanup = func() {
+		if err := os.RemoveAll(tmp); err != nil {
+			slog.Warn("failed to remove temp dir", "path", tmp, "error", err)
+		}
+	}
+	// Syft and Stereoscope create their temporary files in os.TempDir().
+	err = os.Setenv("TMPDIR", tmp)
+	if err != nil {
+		fmt.Printf("failed to set TMPDIR: %v", err)
+		cleanup()
+		os.Exit(1)
+	}
+
 	rwDirs := []string{
 		filepath.Join(home, ".sigstore"),         // Sigstore TUF DB.
 		filepath.Join(home, ".docker", "buildx"), // Image artefacts handling.
+		tmp,                                      // SBOM generation.
 	}
+	rwDirs = append(rwDirs, writablePaths...)
 	ensureDirs(rwDirs)
 
 	cwd, err := os.Getwd()
@@ -54,6 +87,12 @@ func EnforceOrDie() {
 			"/etc/resolv.conf", // DNS resolution.
 		).IgnoreIfMissing(),
 		landlock.RWDirs(rwDirs...),
+		// Removal of the temp dir of the run, once its children are removed
+		// under the RWDirs rule above. Landlock cannot be lifted within the
+		// process, nor grant the removal of a single entry, so this covers
+		// every directory beneath parent. It is limited to empty ones, and
+		// the sticky bit of /tmp limits it to those owned by the user.
+		landlock.PathAccess(syscall.AccessFSRemoveDir, parent),
 		landlock.RODirs(
 			"/proc/self",
 			"/etc/ssl",                     // Root CA bundles to establish TLS.
@@ -62,11 +101,14 @@ func EnforceOrDie() {
 		).IgnoreIfMissing(),
 	}
 
+	rules = append(rules, localRules(localPaths)...)
+
 	if helper, ok := credentialHelper(home); ok {
 		if val, ok := os.LookupEnv("LANDLOCK_CREDENTIAL_HELPER"); ok && strings.EqualFold(val, "true") {
 			rules = append(rules, helper...)
 		} else {
 			fmt.Println("ERR: cannot use landlock with docker-credential helpers: disable landlock with LANDLOCK_MODE=off")
+			cleanup()
 			os.Exit(1)
 		}
 	}
@@ -75,9 +117,11 @@ func EnforceOrDie() {
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "failed to enforce landlock policies (requires Linux 5.13+): %v\n", err)
 		if val == "on" {
+			cleanup()
 			os.Exit(2)
 		}
 	}
+	return cleanup
 }
 
 func credentialHelper(home string) ([]landlock.Rule, bool) {
@@ -115,6 +159,31 @@ func credentialHelper(home string) ([]landlock.Rule, bool) {
 	return nil, false
 }
 
+func localRules(paths []string) []landlock.Rule {
+	var dirs, files []string
+	for _, path := range paths {
+		fi, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+
+		if fi.IsDir() {
+			dirs = append(dirs, path)
+		} else {
+			files = append(files, path)
+		}
+	}
+
+	var rules []landlock.Rule
+	if len(dirs) > 0 {
+		rules = append(rules, landlock.RODirs(dirs...))
+	}
+	if len(files) > 0 {
+		rules = append(rules, landlock.ROFiles(files...))
+	}
+	return rules
+}
+
 func execFile(path ...string) landlock.FSRule {
 	return landlock.PathAccess(syscall.AccessFSExecute|syscall.AccessFSReadFile, path...)
 }
@@ -0,0 +1,150 @@
+package license
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/github/go-spdx/v2/spdxexp"
+	"sigs.k8s.io/yaml"
+)
+
+// Statuses of a license expression against a policy.
+const (
+	StatusAllowed = "allowed"
+	StatusReview  = "review"
+	StatusDenied  = "denied"
+	// StatusUnknown is the status of packages without license.
+	StatusUnknown = "unknown"
+)
+
+// ErrInvalidPolicy indicates a policy file that could not be read.
+var ErrInvalidPolicy = errors.New("invalid license policy")
+
+// Policy lists the licenses that are allowed, denied or need review.
+// Licenses are SPDX identifiers, optionally with an exception, as in
+// Apache-2.0 WITH LLVM-exception.
+type Policy struct {
+	Allow  []string `json:"allow,omitempty"`
+	Deny   []string `json:"deny,omitempty"`
+	Review []string `json:"review,omitempty"`
+	// Default is the status of the licenses not listed: review (default),
+	// allowed or denied.
+	Default string `json:"default,omitempty"`
+}
+
+// LoadPolicy reads the policy at path, in YAML or JSON.
+func LoadPolicy(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPolicy, err)
+	}
+
+	var p Policy
+	err = yaml.UnmarshalStrict(data, &p)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidPolicy, err)
+	}
+
+	if p.Default == "" {
+		p.Default = StatusReview
+	}
+	if p.Default != StatusAllowed && p.Default != StatusReview && p.Default != StatusDenied {
+		return nil, fmt.Errorf("%w: default %q: supported values are %s, %s or %s",
+			ErrInvalidPolicy, p.Default, StatusReview, StatusAllowed, StatusDenied)
+	}
+
+	for _, list := range [][]string{p.Allow, p.Deny, p.Review} {
+		for i, l := range list {
+			list[i] = Normalize(l)
+		}
+	}
+	return &p, nil
+}
+
+// Normalize returns expression as a normalized SPDX license expression, as
+// in Apache-2.0 AND MIT for apache-2.0 and mit. Expressions that are not
+// valid SPDX expressions are returned trimmed.
+func Normalize(expression string) string {
+	expression = strings.TrimSpace(expression)
+	normalized, invalid := spdxexp.ValidateAndNormalizeLicensesWithOptions(
+		[]string{expression}, spdxexp.ValidateLicensesOptions{})
+	if len(invalid) > 0 || len(normalized) != 1 {
+		return expression
+	}
+	return normalized[0]
+}
+
+// Evaluate returns the status of the license expression, which is allowed
+// when the licenses allowed satisfy it, as in MIT OR GPL-3.0-only with MIT
+// allowed. Otherwise it needs review when the licenses allowed or needing
+// review satisfy it, and is denied when they do not. Expressions that are
+// not valid SPDX expressions need review, unless listed as is.
+func (p *Policy) Evaluate(expression string) string {
+	expression = Normalize(expression)
+	if expression == "" {
+		return StatusUnknown
+	}
+
+	licenses, err := spdxexp.ExtractLicenses(expression)
+	if err != nil {
+		if status := p.status(expression); status != "" {
+			return status
+		}
+		return StatusReview
+	}
+
+	var allowed, reviewed []string
+	for _, l := range licenses {
+		status := p.status(l)
+		if status == "" {
+			status = p.Default
+		}
+
+		switch status {
+		case StatusAllowed:
+			allowed = append(allowed, l)
+			reviewed = append(reviewed, l)
+		case StatusReview:
+			reviewed = append(reviewed, l)
+		}
+	}
+
+	switch {
+	case satisfies(expression, allowed):
+		return StatusAllowed
+	case satisfies(expression, reviewed):
+		return StatusReview
+	}
+	return StatusDenied
+}
+
+// status returns the status of license when it is listed by p. Licenses
+// denied take precedence.
+func (p *Policy) status(license string) string {
+	contains := func(list []string) bool {
+		return slices.ContainsFunc(list, func(l string) bool {
+			return strings.EqualFold(l, license)
+		})
+	}
+
+	switch {
+	case contains(p.Deny):
+		return StatusDenied
+	case contains(p.Review):
+		return StatusReview
+	case contains(p.Allow):
+		return StatusAllowed
+	}
+	return ""
+}
+
+func satisfies(expression string, licenses []string) bool {
+	if len(licenses) == 0 {
+		return false
+	}
+	ok, err := spdxexp.Satisfies(expression, licenses)
+	return err == nil && ok
+}
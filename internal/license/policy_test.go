@@ -0,0 +1,66 @@
+package license
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvaluate(t *testing.T) {
+	t.Parallel()
+
+	policy, err := LoadPolicy("testdata/policy.yaml")
+	require.NoError(t, err)
+	assert.Equal(t, StatusReview, policy.Default)
+	assert.Contains(t, policy.Allow, "Apache-2.0", "licenses are normalized")
+
+	tests := []struct {
+		expression string
+		want       string
+	}{
+		{expression: "MIT", want: StatusAllowed},
+		{expression: "mit", want: StatusAllowed},
+		{expression: "Apache-2.0 AND MIT", want: StatusAllowed},
+		{expression: "MIT OR GPL-3.0-only", want: StatusAllowed},
+		{expression: "MIT AND GPL-3.0-only", want: StatusDenied},
+		{expression: "LGPL-2.1-only OR AGPL-3.0-only", want: StatusReview},
+		{expression: "MIT AND LGPL-2.1-only", want: StatusReview},
+		{expression: "MPL-2.0", want: StatusReview},
+		{expression: "Some custom license text", want: StatusReview},
+		{expression: "", want: StatusUnknown},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.expression, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.want, policy.Evaluate(tc.expression))
+		})
+	}
+
+	strict := &Policy{Allow: []string{"MIT"}, Default: StatusDenied}
+	assert.Equal(t, StatusDenied, strict.Evaluate("MPL-2.0"))
+	assert.Equal(t, StatusAllowed, strict.Evaluate("MIT OR MPL-2.0"))
+}
+
+func TestLoadPolicyInvalid(t *testing.T) {
+	t.Parallel()
+
+	_, err := LoadPolicy("testdata/missing.yaml")
+	assert.ErrorIs(t, err, ErrInvalidPolicy)
+
+	fn := t.TempDir() + "/policy.yaml"
+	require.NoError(t, writeFile(fn, "allow: [MIT]\ndefault: maybe\n"))
+	_, err = LoadPolicy(fn)
+	assert.ErrorIs(t, err, ErrInvalidPolicy)
+
+	require.NoError(t, writeFile(fn, "allowed: [MIT]\n"))
+	_, err = LoadPolicy(fn)
+	assert.ErrorIs(t, err, ErrInvalidPolicy, "unknown fields are rejected")
+}
+
+func writeFile(fn, content string) error {
+	return os.WriteFile(fn, []byte(content), 0o600)
+}
@@ -0,0 +1,251 @@
+package license
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/anchore/syft/syft/format"
+)
+
+// Output formats of Write.
+const (
+	OutputJSON  = "json"
+	OutputTable = "table"
+)
+
+var (
+	// ErrUnsupportedOutput indicates an unknown output format.
+	ErrUnsupportedOutput = errors.New("unsupported output format")
+	// ErrDenied indicates that some packages have licenses denied by the
+	// policy.
+	ErrDenied = errors.New("licenses denied by policy")
+)
+
+// Package is a package listed by an SBOM.
+type Package struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Type    string `json:"type"`
+	PURL    string `json:"purl,omitempty"`
+	// License is the SPDX license expression of the package, joining with
+	// AND the licenses found for it.
+	License string `json:"license,omitempty"`
+	Status  string `json:"status,omitempty"`
+}
+
+// Packages returns the packages of the SBOM read from r, in any format
+// known to Syft, such as SPDX or CycloneDX.
+func Packages(r io.Reader) ([]Package, error) {
+	s, _, _, err := format.Decode(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode SBOM: %w", err)
+	}
+	if s == nil {
+		return nil, fmt.Errorf("failed to decode SBOM: unknown format")
+	}
+
+	var pkgs []Package
+	for _, p := range s.Artifacts.Packages.Sorted() {
+		var licenses []string
+		for _, l := range p.Licenses.ToSlice() {
+			l := Normalize(cmp.Or(l.SPDXExpression, l.Value))
+			if l != "" && !slices.Contains(licenses, l) {
+				licenses = append(licenses, l)
+			}
+		}
+
+		pkgs = append(pkgs, Package{
+			Name:    p.Name,
+			Version: p.Version,
+			Type:    string(p.Type),
+			PURL:    p.PURL,
+			License: joinLicenses(licenses),
+		})
+	}
+	return pkgs, nil
+}
+
+func joinLicenses(licenses []string) string {
+	if len(licenses) < 2 {
+		return strings.Join(licenses, "")
+	}
+
+	slices.Sort(licenses)
+	for i, l := range licenses {
+		if strings.Contains(l, " ") && !strings.Contains(l, " WITH ") {
+			licenses[i] = "(" + l + ")"
+		}
+	}
+	return strings.Join(licenses, " AND ")
+}
+
+// Report holds the license status of the packages in the images of a
+// product, or in a single image.
+type Report struct {
+	Product string        `json:"product,omitempty"`
+	Version string        `json:"version,omitempty"`
+	Images  []ImageReport `json:"images"`
+	// Licenses holds the status of each license expression found in the
+	// images, along with the number of packages using it.
+	Licenses []LicenseUsage `json:"licenses"`
+	// Summary counts the packages of all images by status.
+	Summary Summary `json:"summary"`
+}
+
+// ImageReport holds the license status of the packages in an image.
+type ImageReport struct {
+	Image string `json:"image"`
+	// Packages holds the packages whose licenses are not allowed.
+	Packages []Package `json:"packages,omitempty"`
+	Summary  Summary   `json:"summary"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// LicenseUsage is a license expression and the number of packages using it.
+type LicenseUsage struct {
+	License  string `json:"license"`
+	Status   string `json:"status"`
+	Packages int    `json:"packages"`
+}
+
+// Summary counts packages by license status.
+type Summary struct {
+	Total   int `json:"total"`
+	Allowed int `json:"allowed"`
+	Review  int `json:"review"`
+	Denied  int `json:"denied"`
+	Unknown int `json:"unknown"`
+}
+
+func (s *Summary) add(status string) {
+	s.Total++
+	switch status {
+	case StatusAllowed:
+		s.Allowed++
+	case StatusReview:
+		s.Review++
+	case StatusDenied:
+		s.Denied++
+	default:
+		s.Unknown++
+	}
+}
+
+// Evaluate evaluates the licenses of the packages of image against policy,
+// and adds the result to r.
+func (r *Report) Evaluate(image string, pkgs []Package, policy *Policy) {
+	img := ImageReport{Image: image}
+	for _, p := range pkgs {
+		p.Status = policy.Evaluate(p.License)
+		img.Summary.add(p.Status)
+		if p.Status != StatusAllowed {
+			img.Packages = append(img.Packages, p)
+		}
+
+		r.Summary.add(p.Status)
+		i := slices.IndexFunc(r.Licenses, func(u LicenseUsage) bool { return u.License == p.License })
+		if i < 0 {
+			r.Licenses = append(r.Licenses, LicenseUsage{License: p.License, Status: p.Status})
+			i = len(r.Licenses) - 1
+		}
+		r.Licenses[i].Packages++
+	}
+
+	slices.SortStableFunc(img.Packages, func(a, b Package) int {
+		return cmp.Or(statusOrder(a.Status)-statusOrder(b.Status), strings.Compare(a.Name, b.Name))
+	})
+	slices.SortFunc(r.Licenses, func(a, b LicenseUsage) int {
+		return cmp.Or(statusOrder(a.Status)-statusOrder(b.Status), strings.Compare(a.License, b.License))
+	})
+
+	r.Images = append(r.Images, img)
+}
+
+// AddError adds an image whose packages could not be read to r.
+func (r *Report) AddError(image string, err error) {
+	r.Images = append(r.Images, ImageReport{Image: image, Error: err.Error()})
+}
+
+// Err returns ErrDenied when some packages have licenses denied.
+func (r *Report) Err() error {
+	if r.Summary.Denied > 0 {
+		return fmt.Errorf("%w: %d packages", ErrDenied, r.Summary.Denied)
+	}
+	return nil
+}
+
+func statusOrder(status string) int {
+	return slices.Index([]string{StatusDenied, StatusReview, StatusUnknown, StatusAllowed}, status)
+}
+
+// ValidateOutput checks whether output is supported by Write.
+func ValidateOutput(output string) error {
+	if output != OutputJSON && output != OutputTable {
+		return fmt.Errorf("%w %q: supported values are %s or %s",
+			ErrUnsupportedOutput, output, OutputJSON, OutputTable)
+	}
+	return nil
+}
+
+// Write writes r to w in output, which is json or table.
+func (r *Report) Write(w io.Writer, output string) error {
+	if err := ValidateOutput(output); err != nil {
+		return err
+	}
+
+	if output == OutputJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	}
+	return r.writeTable(w)
+}
+
+func (r *Report) writeTable(w io.Writer) error {
+	tw := new(tabwriter.Writer)
+	tw.Init(w, 12, 12, 4, ' ', 0)
+
+	fmt.Fprintln(tw, "Image\tStatus\tPackage\tVersion\tLicense")
+	fmt.Fprintln(tw, "-----\t------\t-------\t-------\t-------")
+	for _, img := range r.Images {
+		if img.Error != "" {
+			fmt.Fprintf(tw, "%s\t%s\t\t\t\n", img.Image, "error: "+img.Error)
+		}
+		for _, p := range img.Packages {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", img.Image, p.Status, p.Name, p.Version, p.License)
+		}
+	}
+
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "License\tStatus\tPackages")
+	fmt.Fprintln(tw, "-------\t------\t--------")
+	for _, l := range r.Licenses {
+		fmt.Fprintf(tw, "%s\t%s\t%d\n", cmp.Or(l.License, "none"), l.Status, l.Packages)
+	}
+
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "Image\tTotal\tAllowed\tReview\tDenied\tUnknown")
+	fmt.Fprintln(tw, "-----\t-----\t-------\t------\t------\t-------")
+	for _, img := range r.Images {
+		writeSummaryRow(tw, img.Image, img.Summary)
+	}
+	if len(r.Images) > 1 {
+		name := "all images"
+		if r.Product != "" {
+			name = r.Product + ":" + r.Version
+		}
+		writeSummaryRow(tw, name, r.Summary)
+	}
+
+	return tw.Flush()
+}
+
+func writeSummaryRow(w io.Writer, name string, s Summary) {
+	fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%d\n", name, s.Total, s.Allowed, s.Review, s.Denied, s.Unknown)
+}
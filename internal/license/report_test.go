@@ -0,0 +1,69 @@
+package license
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPackages(t *testing.T) {
+	t.Parallel()
+
+	doc := `{
+  "bomFormat": "CycloneDX",
+  "specVersion": "1.6",
+  "version": 1,
+  "components": [
+    {"type": "library", "name": "a", "version": "1.0", "purl": "pkg:npm/a@1.0", "licenses": [{"license": {"id": "MIT"}}, {"license": {"id": "Apache-2.0"}}]},
+    {"type": "library", "name": "b", "version": "2.0", "purl": "pkg:npm/b@2.0", "licenses": [{"expression": "mit OR gpl-3.0-only"}]},
+    {"type": "library", "name": "c", "version": "3.0", "purl": "pkg:npm/c@3.0"}
+  ]
+}`
+
+	pkgs, err := Packages(strings.NewReader(doc))
+	require.NoError(t, err)
+	require.Len(t, pkgs, 3)
+	assert.Equal(t, "Apache-2.0 AND MIT", pkgs[0].License)
+	assert.Equal(t, "MIT OR GPL-3.0-only", pkgs[1].License)
+	assert.Empty(t, pkgs[2].License)
+}
+
+func TestReport(t *testing.T) {
+	t.Parallel()
+
+	policy, err := LoadPolicy("testdata/policy.yaml")
+	require.NoError(t, err)
+
+	var r Report
+	r.Evaluate("a", []Package{
+		{Name: "x", Version: "1", License: "MIT"},
+		{Name: "y", Version: "1", License: "GPL-3.0-only"},
+		{Name: "z", Version: "1"},
+	}, policy)
+	r.Evaluate("b", []Package{
+		{Name: "x", Version: "1", License: "MIT"},
+		{Name: "w", Version: "1", License: "LGPL-2.1-only"},
+	}, policy)
+	r.AddError("c", assert.AnError)
+
+	require.Len(t, r.Images, 3)
+	assert.Equal(t, Summary{Total: 3, Allowed: 1, Denied: 1, Unknown: 1}, r.Images[0].Summary)
+	require.Len(t, r.Images[0].Packages, 2, "allowed packages are not listed")
+	assert.Equal(t, "y", r.Images[0].Packages[0].Name, "denied packages first")
+	assert.Equal(t, Summary{Total: 5, Allowed: 2, Review: 1, Denied: 1, Unknown: 1}, r.Summary)
+	assert.Equal(t, []LicenseUsage{
+		{License: "GPL-3.0-only", Status: StatusDenied, Packages: 1},
+		{License: "LGPL-2.1-only", Status: StatusReview, Packages: 1},
+		{License: "", Status: StatusUnknown, Packages: 1},
+		{License: "MIT", Status: StatusAllowed, Packages: 2},
+	}, r.Licenses)
+	assert.ErrorIs(t, r.Err(), ErrDenied)
+
+	var buf bytes.Buffer
+	require.NoError(t, r.Write(&buf, OutputTable))
+	assert.Contains(t, buf.String(), "all images")
+	assert.ErrorIs(t, r.Write(&buf, "sarif"), ErrUnsupportedOutput)
+}
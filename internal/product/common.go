@@ -4,11 +4,14 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
 	"regexp"
 	"strings"
 
 	"github.com/rancherlabs/slsactl/internal/imagelist"
+	"github.com/rancherlabs/slsactl/internal/sbom"
 )
 
 var (
@@ -44,9 +47,10 @@ type productInfo struct {
 }
 
 type summary struct {
-	count  int
-	signed int
-	errors int
+	count     int
+	signed    int
+	errors    int
+	conflicts int
 }
 
 func product(name, version string) (*productInfo, error) {
@@ -67,6 +71,45 @@ func product(name, version string) (*productInfo, error) {
 	return &info, nil
 }
 
+// collectSBOMs writes the SBOM of each image of the product version to
+// outputDir in outformat. SBOMs missing are generated with gen, whose
+// platform defaults to linux/amd64, and to windows/amd64 for Windows images.
+func collectSBOMs(registry, version, imagesListBaseURL, outputDir string, info *productInfo,
+	outformat string, gen sbom.Options,
+) (*imagelist.Result, error) {
+	if imagesListBaseURL == "" {
+		imagesListBaseURL = info.defaultImagesBaseURL
+	}
+
+	linux := gen
+	if linux.Platform == "" {
+		linux.Platform = "linux/amd64"
+	}
+
+	p := imagelist.NewProcessor(registry, imagelist.WithSBOM(outformat, linux))
+	result, err := p.SBOM(fmt.Sprintf(info.imagesURL, imagesListBaseURL, version), outputDir)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(info.windowsImagesURL) > 0 {
+		windows := gen
+		if windows.Platform == "" {
+			windows.Platform = "windows/amd64"
+		}
+
+		p := imagelist.NewProcessor(registry, imagelist.WithSBOM(outformat, windows))
+		r2, err := p.SBOM(fmt.Sprintf(info.windowsImagesURL, imagesListBaseURL, version), outputDir)
+		if err == nil {
+			result.Entries = append(result.Entries, r2.Entries...)
+		} else {
+			slog.Error("failed to process windows images", "error", err)
+		}
+	}
+
+	return result, nil
+}
+
 func saveOutput(fn string, result *imagelist.Result) error {
 	data, err := json.MarshalIndent(result, "", "  ")
 	if err != nil {
@@ -86,11 +129,7 @@ func saveOutput(fn string, result *imagelist.Result) error {
 func resultSummary(result *imagelist.Result) map[string]*summary {
 	s := map[string]*summary{}
 	for _, entry := range result.Entries {
-		imgType := "rancher"
-		if strings.Contains(entry.Image, "rancher/mirrored") {
-			imgType = "third-party"
-		}
-
+		imgType := imageType(entry.Image)
 		if _, ok := s[imgType]; !ok {
 			s[imgType] = &summary{}
 		}
@@ -102,6 +141,36 @@ func resultSummary(result *imagelist.Result) map[string]*summary {
 		if entry.Error != nil {
 			s[imgType].errors++
 		}
+		if isConflict(entry) {
+			s[imgType].conflicts++
+		}
 	}
 	return s
 }
+
+func imageType(img string) string {
+	if strings.Contains(img, "rancher/mirrored") {
+		return "third-party"
+	}
+	return "rancher"
+}
+
+// jsonProgress writes the transfer progress events sent to the returned
+// channel as JSON lines into w, until stop is called.
+func jsonProgress(w io.Writer) (chan<- imagelist.ProgressEvent, func()) {
+	ch := make(chan imagelist.ProgressEvent, 64)
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		enc := json.NewEncoder(w)
+		for e := range ch {
+			_ = enc.Encode(e)
+		}
+	}()
+
+	return ch, func() {
+		close(ch)
+		<-done
+	}
+}
@@ -1,6 +1,7 @@
 package product
 
 import (
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
@@ -9,20 +10,58 @@ import (
 	"github.com/rancherlabs/slsactl/internal/imagelist"
 )
 
-func Copy(registry, name, version, targetRegistry, imagesListBaseURL string) error {
+// ErrConflictsFound indicates that destination tags pointing to a different
+// digest were found while using the fail conflict policy.
+var ErrConflictsFound = errors.New("destination tag conflicts found")
+
+// CopyOptions holds the optional settings for Copy.
+type CopyOptions struct {
+	// ImagesListBaseURL overrides the product's default images list location.
+	ImagesListBaseURL string
+	// DryRun plans the copy without writing to the target registry.
+	DryRun bool
+	// OnConflict defines how to handle destination tags that point to a
+	// different digest. Defaults to imagelist.ConflictSkip.
+	OnConflict imagelist.ConflictPolicy
+	// Transfer sets the bandwidth and concurrency limits of the copy.
+	Transfer imagelist.TransferOptions
+	// JSONProgress writes the transfer progress as JSON lines to stderr.
+	JSONProgress bool
+}
+
+func Copy(registry, name, version, targetRegistry string, opts CopyOptions) error {
 	info, err := product(name, version)
 	if err != nil {
 		return err
 	}
 
+	imagesListBaseURL := opts.ImagesListBaseURL
 	if imagesListBaseURL == "" {
 		imagesListBaseURL = info.defaultImagesBaseURL
 	}
 
-	fmt.Printf("Copying %s %s signatures to %q:\n\n", info.description, version, targetRegistry)
+	if opts.OnConflict == "" {
+		opts.OnConflict = imagelist.ConflictSkip
+	}
+
+	if opts.JSONProgress {
+		progress, stop := jsonProgress(os.Stderr)
+		defer stop()
+		opts.Transfer.Progress = progress
+	}
+
+	p := imagelist.NewProcessor(registry,
+		imagelist.WithConflictPolicy(opts.OnConflict),
+		imagelist.WithTransfer(opts.Transfer))
+	action := p.Copy
+	if opts.DryRun {
+		action = p.Plan
+		fmt.Printf("Planning copy of %s %s signatures to %q (dry-run):\n\n", info.description, version, targetRegistry)
+	} else {
+		fmt.Printf("Copying %s %s signatures to %q:\n\n", info.description, version, targetRegistry)
+	}
 
-	p := imagelist.NewProcessor(registry)
-	result, err := p.Copy(fmt.Sprintf(info.imagesURL, imagesListBaseURL, version), targetRegistry)
+	result, err := action(fmt.Sprintf(info.imagesURL, imagesListBaseURL, version), targetRegistry)
 	if err != nil {
 		return err
 	}
@@ -31,7 +70,7 @@ func Copy(registry, name, version, targetRegistry, imagesListBaseURL string) err
 	result.Version = version
 
 	if len(info.windowsImagesURL) > 0 {
-		r2, err := p.Copy(fmt.Sprintf(info.windowsImagesURL, imagesListBaseURL, version), targetRegistry)
+		r2, err := action(fmt.Sprintf(info.windowsImagesURL, imagesListBaseURL, version), targetRegistry)
 		if err == nil {
 			result.Entries = append(result.Entries, r2.Entries...)
 		} else {
@@ -39,13 +78,34 @@ func Copy(registry, name, version, targetRegistry, imagesListBaseURL string) err
 		}
 	}
 
+	if opts.DryRun {
+		err = printPlanSummary(result)
+		if err != nil {
+			return fmt.Errorf("failed to print summary: %w", err)
+		}
+
+		fn := fmt.Sprintf("%s_%s_copy_plan.json", result.Product, result.Version)
+		return saveOutput(fn, result)
+	}
+
 	err = printCopySummary(result)
 	if err != nil {
 		return fmt.Errorf("failed to print summary: %w", err)
 	}
 
 	fn := fmt.Sprintf("%s_%s_copy.json", result.Product, result.Version)
-	return saveOutput(fn, result)
+	err = saveOutput(fn, result)
+	if err != nil {
+		return err
+	}
+
+	if opts.OnConflict == imagelist.ConflictFail {
+		if n := conflicts(result); n > 0 {
+			return fmt.Errorf("%w: %d images", ErrConflictsFound, n)
+		}
+	}
+
+	return nil
 }
 
 func printCopySummary(result *imagelist.Result) error {
@@ -53,13 +113,81 @@ func printCopySummary(result *imagelist.Result) error {
 	w.Init(os.Stdout, 12, 12, 4, ' ', 0)
 
 	fmt.Print("\n\n ✨ COPY SUMMARY ✨ \n")
-	fmt.Fprintln(w, "Image Type\tImages Count\tSignatures")
-	fmt.Fprintln(w, "-----------\t------------\t------------")
+	fmt.Fprintln(w, "Image Type\tImages Count\tSignatures\tConflicts")
+	fmt.Fprintln(w, "-----------\t------------\t------------\t---------")
 
 	s := resultSummary(result)
 	for name, data := range s {
-		fmt.Fprintf(w, "%s\t%d \t%d\n", name, data.count, data.signed)
+		fmt.Fprintf(w, "%s\t%d \t%d\t%d\n", name, data.count, data.signed, data.conflicts)
 	}
 
 	return w.Flush()
 }
+
+func conflicts(result *imagelist.Result) int {
+	var n int
+	for _, entry := range result.Entries {
+		if isConflict(entry) {
+			n++
+		}
+	}
+	return n
+}
+
+func isConflict(entry imagelist.Entry) bool {
+	return entry.Status == imagelist.StatusConflict ||
+		entry.SignatureStatus == imagelist.StatusConflict
+}
+
+func printPlanSummary(result *imagelist.Result) error {
+	w := new(tabwriter.Writer)
+	w.Init(os.Stdout, 12, 12, 4, ' ', 0)
+
+	fmt.Print("\n\n ✨ COPY PLAN SUMMARY ✨ \n")
+	fmt.Fprintln(w, "Image Type\tImages Count\tSignatures\tNew\tExisting\tConflicts\tTo Transfer")
+	fmt.Fprintln(w, "-----------\t------------\t------------\t---\t--------\t---------\t-----------")
+
+	s := planSummary(result)
+	for name, data := range s {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%d\t%s\n", name, data.count, data.signed,
+			data.new, data.exists, data.conflicts, imagelist.FormatBytes(data.bytes))
+	}
+
+	return w.Flush()
+}
+
+type planStats struct {
+	count     int
+	signed    int
+	new       int
+	exists    int
+	conflicts int
+	bytes     int64
+}
+
+func planSummary(result *imagelist.Result) map[string]*planStats {
+	s := map[string]*planStats{}
+	for _, entry := range result.Entries {
+		imgType := imageType(entry.Image)
+		if _, ok := s[imgType]; !ok {
+			s[imgType] = &planStats{}
+		}
+
+		s[imgType].count++
+		if entry.Signed {
+			s[imgType].signed++
+		}
+
+		// An image is new when either its tag or its signature is missing.
+		switch {
+		case isConflict(entry):
+			s[imgType].conflicts++
+		case entry.Status == imagelist.StatusNew || entry.SignatureStatus == imagelist.StatusNew:
+			s[imgType].new++
+		case entry.Status == imagelist.StatusExists:
+			s[imgType].exists++
+		}
+		s[imgType].bytes += entry.MissingBytes
+	}
+	return s
+}
@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
-	"strings"
 	"text/tabwriter"
 
 	"github.com/rancherlabs/slsactl/internal/imagelist"
@@ -77,11 +76,7 @@ type downloadStats struct {
 func downloadSummary(result *imagelist.Result) map[string]*downloadStats {
 	s := map[string]*downloadStats{}
 	for _, entry := range result.Entries {
-		imgType := "rancher"
-		if strings.Contains(entry.Image, "rancher/mirrored") {
-			imgType = "third-party"
-		}
-
+		imgType := imageType(entry.Image)
 		if _, ok := s[imgType]; !ok {
 			s[imgType] = &downloadStats{}
 		}
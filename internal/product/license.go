@@ -0,0 +1,100 @@
+package product
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/rancherlabs/slsactl/internal/imagelist"
+	"github.com/rancherlabs/slsactl/internal/license"
+	"github.com/rancherlabs/slsactl/internal/sbom"
+)
+
+// LicenseOptions holds the optional settings for License.
+type LicenseOptions struct {
+	// ImagesListBaseURL overrides the product's default images list location.
+	ImagesListBaseURL string
+	// Output is the format of the report written to stdout: table
+	// (default) or json.
+	Output string
+	// Generate configures the generation of the SBOMs of images without
+	// SBOM attestations. Its platform defaults to linux/amd64, and to
+	// windows/amd64 for Windows images.
+	Generate sbom.Options
+}
+
+// License evaluates the licenses of the packages in the images of the
+// product version against policy. It returns license.ErrDenied when some
+// licenses are denied.
+func License(registry, name, version string, policy *license.Policy, opts LicenseOptions) error {
+	info, err := product(name, version)
+	if err != nil {
+		return err
+	}
+
+	if opts.Output == "" {
+		opts.Output = license.OutputTable
+	}
+	err = license.ValidateOutput(opts.Output)
+	if err != nil {
+		return err
+	}
+
+	outputDir := fmt.Sprintf("%s-%s", name, version)
+	err = os.MkdirAll(outputDir, 0o755)
+	if err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Evaluating the licenses of %s %s:\n\n", info.description, version)
+
+	result, err := collectSBOMs(registry, version, opts.ImagesListBaseURL, outputDir, info, sbom.FormatSyftJSON, opts.Generate)
+	if err != nil {
+		return err
+	}
+
+	report := license.Report{Product: name, Version: version}
+	for _, entry := range result.Entries {
+		pkgs, err := entryPackages(entry)
+		if err != nil {
+			report.AddError(entry.Image, err)
+			continue
+		}
+		report.Evaluate(entry.Image, pkgs, policy)
+	}
+
+	var buf bytes.Buffer
+	err = report.Write(&buf, license.OutputJSON)
+	if err != nil {
+		return err
+	}
+	fn := filepath.Join(outputDir, fmt.Sprintf("%s_%s_licenses.json", name, version))
+	err = os.WriteFile(fn, buf.Bytes(), 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	fmt.Fprintln(os.Stdout)
+	err = report.Write(os.Stdout, opts.Output)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "\nreport saved as %q\n", fn)
+
+	return report.Err()
+}
+
+func entryPackages(entry imagelist.Entry) ([]license.Package, error) {
+	if entry.Error != nil {
+		return nil, entry.Error
+	}
+
+	f, err := os.Open(entry.SBOMFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return license.Packages(f)
+}
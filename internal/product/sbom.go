@@ -0,0 +1,160 @@
+package product
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/rancherlabs/slsactl/internal/imagelist"
+	"github.com/rancherlabs/slsactl/internal/sbom"
+)
+
+// ErrIncompleteSBOM indicates that the SBOM of some images could not be
+// fetched nor generated, so the product SBOM does not cover them.
+var ErrIncompleteSBOM = errors.New("product SBOM is incomplete")
+
+// SBOMOptions holds the optional settings for SBOM.
+type SBOMOptions struct {
+	// ImagesListBaseURL overrides the product's default images list location.
+	ImagesListBaseURL string
+	// Format is the format of the product SBOM, either spdxjson (default)
+	// or cyclonedxjson.
+	Format string
+	// Generate configures the generation of the SBOMs of images without
+	// SBOM attestations. Its platform defaults to linux/amd64, and to
+	// windows/amd64 for Windows images.
+	Generate sbom.Options
+}
+
+// SBOM writes a single SBOM for the product version, merging the SBOMs of
+// all its images.
+func SBOM(registry, name, version string, opts SBOMOptions) error {
+	info, err := product(name, version)
+	if err != nil {
+		return err
+	}
+
+	if opts.Format == "" {
+		opts.Format = sbom.FormatSPDXJSON
+	}
+	if opts.Format != sbom.FormatSPDXJSON && opts.Format != sbom.FormatCycloneDXJSON {
+		return fmt.Errorf("%w: %q: supported values are %s or %s",
+			sbom.ErrUnsupportedFormat, opts.Format, sbom.FormatSPDXJSON, sbom.FormatCycloneDXJSON)
+	}
+
+	outputDir := fmt.Sprintf("%s-%s", name, version)
+	err = os.MkdirAll(outputDir, 0o755)
+	if err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	fmt.Printf("Collecting SBOMs for %s %s:\n\n", info.description, version)
+	fmt.Printf("Output directory: %s\n\n", outputDir)
+
+	result, err := collectSBOMs(registry, version, opts.ImagesListBaseURL, outputDir, info, opts.Format, opts.Generate)
+	if err != nil {
+		return err
+	}
+
+	result.Product = name
+	result.Version = version
+
+	err = printSBOMSummary(result)
+	if err != nil {
+		return fmt.Errorf("failed to print summary: %w", err)
+	}
+
+	var images []sbom.Image
+	var missing int
+	for _, entry := range result.Entries {
+		if entry.SBOMFile == "" {
+			slog.Error("no SBOM for image", "image", entry.Image, "error", entry.Error)
+			missing++
+			continue
+		}
+
+		data, err := os.ReadFile(entry.SBOMFile)
+		if err != nil {
+			return fmt.Errorf("failed to read SBOM of %s: %w", entry.Image, err)
+		}
+		images = append(images, sbom.Image{Name: entry.Image, SBOM: data})
+	}
+
+	fn := filepath.Join(outputDir, fmt.Sprintf("%s_%s_sbom%s", name, version, sbom.Extension(opts.Format)))
+	err = writeMergedSBOM(fn, name, version, images, opts.Format)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("\nproduct SBOM saved as %q\n", fn)
+
+	err = saveOutput(filepath.Join(outputDir, fmt.Sprintf("%s_%s_sboms.json", name, version)), result)
+	if err != nil {
+		return err
+	}
+
+	if missing > 0 {
+		return fmt.Errorf("%w: %d images without SBOM", ErrIncompleteSBOM, missing)
+	}
+	return nil
+}
+
+func writeMergedSBOM(fn, name, version string, images []sbom.Image, outformat string) error {
+	f, err := os.OpenFile(fn, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to create product SBOM: %w", err)
+	}
+	defer f.Close()
+
+	err = sbom.Merge(name, version, images, outformat, f)
+	if err != nil {
+		return fmt.Errorf("failed to merge SBOMs: %w", err)
+	}
+	return f.Close()
+}
+
+func printSBOMSummary(result *imagelist.Result) error {
+	w := new(tabwriter.Writer)
+	w.Init(os.Stdout, 12, 12, 4, ' ', 0)
+
+	fmt.Print("\n\n ✨ SBOM SUMMARY ✨ \n")
+	fmt.Fprintln(w, "Type\tCount\tAttested\tGenerated\tErrors")
+	fmt.Fprintln(w, "----\t-----\t--------\t---------\t------")
+
+	s := sbomSummary(result)
+	for name, data := range s {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\n", name, data.count, data.attested, data.generated, data.errors)
+	}
+
+	return w.Flush()
+}
+
+type sbomStats struct {
+	count     int
+	attested  int
+	generated int
+	errors    int
+}
+
+func sbomSummary(result *imagelist.Result) map[string]*sbomStats {
+	s := map[string]*sbomStats{}
+	for _, entry := range result.Entries {
+		imgType := imageType(entry.Image)
+		if _, ok := s[imgType]; !ok {
+			s[imgType] = &sbomStats{}
+		}
+
+		s[imgType].count++
+		switch {
+		case entry.SBOMFile == "":
+			s[imgType].errors++
+		case entry.SBOMGenerated:
+			s[imgType].generated++
+		default:
+			s[imgType].attested++
+		}
+	}
+	return s
+}
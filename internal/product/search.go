@@ -0,0 +1,116 @@
+package product
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/rancherlabs/slsactl/internal/imagelist"
+	"github.com/rancherlabs/slsactl/internal/sbom"
+)
+
+// SearchOptions holds the optional settings for Search.
+type SearchOptions struct {
+	// ImagesListBaseURL overrides the product's default images list location.
+	ImagesListBaseURL string
+	// JSON writes the results as JSON, instead of a table.
+	JSON bool
+	// Generate configures the generation of the SBOMs of images without
+	// SBOM attestations. Its platform is the platform of the images
+	// searched, defaulting to linux/amd64, and to windows/amd64 for Windows
+	// images.
+	Generate sbom.Options
+}
+
+// SearchResult is a package matching the query in a product image.
+type SearchResult struct {
+	Image    string `json:"image"`
+	Platform string `json:"platform"`
+	sbom.Found
+}
+
+// Search lists the images of the product version that hold packages
+// matching q. It returns ErrIncompleteSBOM when the SBOM of some images
+// could not be fetched nor generated, so they were not searched.
+func Search(registry, name, version string, q *sbom.Query, opts SearchOptions) error {
+	info, err := product(name, version)
+	if err != nil {
+		return err
+	}
+
+	outputDir := fmt.Sprintf("%s-%s", name, version)
+	err = os.MkdirAll(outputDir, 0o755)
+	if err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Searching %s %s for %s:\n\n", info.description, version, q.Name)
+
+	result, err := collectSBOMs(registry, version, opts.ImagesListBaseURL, outputDir, info,
+		sbom.FormatSyftJSON, opts.Generate)
+	if err != nil {
+		return err
+	}
+
+	results := []SearchResult{}
+	var missing int
+	for _, entry := range result.Entries {
+		found, err := searchEntry(entry, q)
+		if err != nil {
+			slog.Error("image not searched", "image", entry.Image, "error", err)
+			missing++
+			continue
+		}
+
+		for _, f := range found {
+			results = append(results, SearchResult{Image: entry.Image, Platform: entry.Platform, Found: f})
+		}
+	}
+
+	fmt.Fprintln(os.Stderr)
+	if opts.JSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(results)
+	} else {
+		err = printSearchResults(os.Stdout, results)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "\n%d packages found in %d images\n", len(results), len(result.Entries)-missing)
+	if missing > 0 {
+		return fmt.Errorf("%w: %d images not searched", ErrIncompleteSBOM, missing)
+	}
+	return nil
+}
+
+func searchEntry(entry imagelist.Entry, q *sbom.Query) ([]sbom.Found, error) {
+	if entry.Error != nil {
+		return nil, entry.Error
+	}
+
+	doc, err := os.ReadFile(entry.SBOMFile)
+	if err != nil {
+		return nil, err
+	}
+	return sbom.Search(doc, q)
+}
+
+func printSearchResults(out io.Writer, results []SearchResult) error {
+	w := new(tabwriter.Writer)
+	w.Init(out, 12, 12, 4, ' ', 0)
+
+	fmt.Fprintln(w, "Image\tPlatform\tPackage\tVersion\tType\tLocations")
+	fmt.Fprintln(w, "-----\t--------\t-------\t-------\t----\t---------")
+	for _, r := range results {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", r.Image, r.Platform, r.Name, r.Version, r.Type, strings.Join(r.Locations, ", "))
+	}
+
+	return w.Flush()
+}
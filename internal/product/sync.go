@@ -0,0 +1,195 @@
+package product
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/rancherlabs/slsactl/internal/imagelist"
+)
+
+// SyncOptions holds the optional settings for Sync.
+type SyncOptions struct {
+	// ImagesListBaseURL overrides the product's default images list location.
+	ImagesListBaseURL string
+	// DryRun plans the sync without writing to the target registry.
+	DryRun bool
+	// Prune removes target tags no longer referenced by any of the versions.
+	Prune bool
+	// OnConflict defines how to handle destination tags that point to a
+	// different digest. Defaults to imagelist.ConflictSkip.
+	OnConflict imagelist.ConflictPolicy
+	// Transfer sets the bandwidth and concurrency limits of the copy.
+	Transfer imagelist.TransferOptions
+	// JSONProgress writes the transfer progress as JSON lines to stderr.
+	JSONProgress bool
+}
+
+// Sync keeps targetRegistry in sync with the given versions of a product.
+// Only the manifests, blobs and signatures missing in the target are copied.
+func Sync(registry, name string, versions []string, targetRegistry string, opts SyncOptions) error {
+	if len(versions) == 0 {
+		return fmt.Errorf("%w: at least one version is required", ErrInvalidVersion)
+	}
+
+	var info *productInfo
+	for _, version := range versions {
+		var err error
+		info, err = product(name, version)
+		if err != nil {
+			return err
+		}
+	}
+
+	imagesListBaseURL := opts.ImagesListBaseURL
+	if imagesListBaseURL == "" {
+		imagesListBaseURL = info.defaultImagesBaseURL
+	}
+
+	if opts.OnConflict == "" {
+		opts.OnConflict = imagelist.ConflictSkip
+	}
+
+	fmt.Printf("Syncing %s %s images and signatures to %q:\n\n", info.description, strings.Join(versions, ", "), targetRegistry)
+
+	if opts.JSONProgress {
+		progress, stop := jsonProgress(os.Stderr)
+		defer stop()
+		opts.Transfer.Progress = progress
+	}
+
+	p := imagelist.NewProcessor(registry,
+		imagelist.WithConflictPolicy(opts.OnConflict),
+		imagelist.WithTransfer(opts.Transfer))
+
+	var images []string
+	for _, version := range versions {
+		imgs, err := p.Images(fmt.Sprintf(info.imagesURL, imagesListBaseURL, version))
+		if err != nil {
+			return err
+		}
+		images = append(images, imgs...)
+
+		if len(info.windowsImagesURL) > 0 {
+			imgs, err := p.Images(fmt.Sprintf(info.windowsImagesURL, imagesListBaseURL, version))
+			if err != nil {
+				// Pruning without the full list would remove images still in use.
+				if opts.Prune {
+					return fmt.Errorf("failed to process windows images: %w", err)
+				}
+				slog.Error("failed to process windows images", "version", version, "error", err)
+			}
+			images = append(images, imgs...)
+		}
+	}
+
+	result, err := p.Sync(images, targetRegistry, opts.DryRun)
+	if err != nil {
+		return err
+	}
+
+	result.Product = name
+	result.Version = strings.Join(versions, ",")
+
+	if opts.Prune {
+		result.Pruned, err = imagelist.PruneTags(context.TODO(), targetRegistry, result.Entries, opts.DryRun)
+		if err != nil {
+			slog.Error("failed to prune target registry", "error", err)
+		}
+	}
+
+	err = printSyncSummary(result, opts.DryRun)
+	if err != nil {
+		return fmt.Errorf("failed to print summary: %w", err)
+	}
+
+	fn := fmt.Sprintf("%s_sync.json", result.Product)
+	if opts.DryRun {
+		fn = fmt.Sprintf("%s_sync_plan.json", result.Product)
+	}
+
+	err = saveOutput(fn, result)
+	if err != nil {
+		return err
+	}
+
+	if opts.OnConflict == imagelist.ConflictFail {
+		if n := conflicts(result); n > 0 {
+			return fmt.Errorf("%w: %d images", ErrConflictsFound, n)
+		}
+	}
+
+	return nil
+}
+
+func printSyncSummary(result *imagelist.Result, dryRun bool) error {
+	w := new(tabwriter.Writer)
+	w.Init(os.Stdout, 12, 12, 4, ' ', 0)
+
+	if dryRun {
+		fmt.Print("\n\n ✨ SYNC PLAN SUMMARY ✨ \n")
+	} else {
+		fmt.Print("\n\n ✨ SYNC SUMMARY ✨ \n")
+	}
+	fmt.Fprintln(w, "Image Type\tImages Count\tSignatures\tUp to date\tConflicts\tErrors")
+	fmt.Fprintln(w, "-----------\t------------\t------------\t----------\t---------\t------")
+
+	s := syncSummary(result)
+	for name, data := range s {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%d\n", name, data.count, data.signed,
+			data.upToDate, data.conflicts, data.errors)
+	}
+
+	err := w.Flush()
+	if err != nil {
+		return err
+	}
+
+	if len(result.Pruned) > 0 {
+		verb := "Pruned"
+		if dryRun {
+			verb = "To prune"
+		}
+		fmt.Printf("\n%s: %d tags\n", verb, len(result.Pruned))
+	}
+
+	return nil
+}
+
+type syncStats struct {
+	count     int
+	signed    int
+	upToDate  int
+	conflicts int
+	errors    int
+}
+
+func syncSummary(result *imagelist.Result) map[string]*syncStats {
+	s := map[string]*syncStats{}
+	for _, entry := range result.Entries {
+		imgType := imageType(entry.Image)
+		if _, ok := s[imgType]; !ok {
+			s[imgType] = &syncStats{}
+		}
+
+		s[imgType].count++
+		if entry.Signed {
+			s[imgType].signed++
+		}
+		if isConflict(entry) {
+			s[imgType].conflicts++
+		}
+		if entry.Error != nil && !errors.Is(entry.Error, imagelist.ErrSkippedImage) {
+			s[imgType].errors++
+		}
+		if entry.Error == nil && entry.Status == imagelist.StatusExists &&
+			entry.SignatureStatus == imagelist.StatusExists {
+			s[imgType].upToDate++
+		}
+	}
+	return s
+}
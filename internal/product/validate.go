@@ -0,0 +1,119 @@
+package product
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/rancherlabs/slsactl/internal/imagelist"
+	"github.com/rancherlabs/slsactl/internal/sbom"
+)
+
+// ValidateSBOMsOptions holds the optional settings for ValidateSBOMs.
+type ValidateSBOMsOptions struct {
+	// ImagesListBaseURL overrides the product's default images list location.
+	ImagesListBaseURL string
+	// Format is the SBOM format validated: spdxjson (default) or
+	// cyclonedxjson.
+	Format string
+	// JSON writes the validations to stdout as JSON instead of a table.
+	JSON bool
+	// Generate configures the generation of the SBOMs of images without
+	// SBOM attestations. Its platform defaults to linux/amd64, and to
+	// windows/amd64 for Windows images.
+	Generate sbom.Options
+}
+
+// ValidateSBOMs validates the SBOM of each image of the product version
+// against its schema and the minimum elements, as sbom.Validate does. It
+// returns sbom.ErrInvalidSBOM or sbom.ErrIncompleteSBOM when the SBOM of
+// some images is not valid or complete.
+func ValidateSBOMs(registry, name, version string, opts ValidateSBOMsOptions) error {
+	info, err := product(name, version)
+	if err != nil {
+		return err
+	}
+
+	if opts.Format == "" {
+		opts.Format = sbom.FormatSPDXJSON
+	}
+	if opts.Format != sbom.FormatSPDXJSON && opts.Format != sbom.FormatCycloneDXJSON {
+		return fmt.Errorf("%w %q: supported values are %s or %s",
+			sbom.ErrUnsupportedFormat, opts.Format, sbom.FormatSPDXJSON, sbom.FormatCycloneDXJSON)
+	}
+
+	outputDir := fmt.Sprintf("%s-%s", name, version)
+	err = os.MkdirAll(outputDir, 0o755)
+	if err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Validating the SBOMs of %s %s:\n\n", info.description, version)
+
+	result, err := collectSBOMs(registry, version, opts.ImagesListBaseURL, outputDir, info, opts.Format, opts.Generate)
+	if err != nil {
+		return err
+	}
+
+	var validations []sbom.NamedValidation
+	var invalid, incomplete int
+	for _, entry := range result.Entries {
+		nv := sbom.NamedValidation{Name: entry.Image}
+		nv.Validation, err = validateEntry(entry)
+		switch {
+		case err != nil:
+			nv.Error = err.Error()
+			invalid++
+		case !nv.Valid():
+			invalid++
+		case !nv.Complete():
+			incomplete++
+		}
+		validations = append(validations, nv)
+	}
+
+	data, err := json.MarshalIndent(validations, "", "  ")
+	if err != nil {
+		return fmt.Errorf("fail to marshal JSON: %w", err)
+	}
+	fn := filepath.Join(outputDir, fmt.Sprintf("%s_%s_sbom_validation.json", name, version))
+	err = os.WriteFile(fn, data, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	fmt.Fprintln(os.Stdout)
+	if opts.JSON {
+		_, err = fmt.Fprintln(os.Stdout, string(data))
+	} else {
+		err = sbom.WriteValidations(os.Stdout, validations)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "\nreport saved as %q\n", fn)
+
+	var errs []error
+	if invalid > 0 {
+		errs = append(errs, fmt.Errorf("%w: %d images", sbom.ErrInvalidSBOM, invalid))
+	}
+	if incomplete > 0 {
+		errs = append(errs, fmt.Errorf("%w: %d images", sbom.ErrIncompleteSBOM, incomplete))
+	}
+	return errors.Join(errs...)
+}
+
+func validateEntry(entry imagelist.Entry) (*sbom.Validation, error) {
+	if entry.Error != nil {
+		return nil, entry.Error
+	}
+
+	data, err := os.ReadFile(entry.SBOMFile)
+	if err != nil {
+		return nil, err
+	}
+	return sbom.Validate(bytes.TrimSpace(data))
+}
@@ -0,0 +1,95 @@
+package product
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/rancherlabs/slsactl/internal/imagelist"
+	"github.com/rancherlabs/slsactl/internal/vex"
+)
+
+// VEXOptions holds the optional settings for VEX.
+type VEXOptions struct {
+	// ImagesListBaseURL overrides the product's default images list location.
+	ImagesListBaseURL string
+	// Author is the author of the merged document. Defaults to the author
+	// of the first document found.
+	Author string
+}
+
+// VEX writes a single OpenVEX document for the product version, merging the
+// OpenVEX attestations of all its images.
+func VEX(registry, name, version string, opts VEXOptions) error {
+	info, err := product(name, version)
+	if err != nil {
+		return err
+	}
+
+	imagesListBaseURL := opts.ImagesListBaseURL
+	if imagesListBaseURL == "" {
+		imagesListBaseURL = info.defaultImagesBaseURL
+	}
+
+	outputDir := fmt.Sprintf("%s-%s", name, version)
+	err = os.MkdirAll(outputDir, 0o755)
+	if err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	fmt.Printf("Collecting OpenVEX documents for %s %s:\n\n", info.description, version)
+
+	p := imagelist.NewProcessor(registry)
+	images, err := p.Images(fmt.Sprintf(info.imagesURL, imagesListBaseURL, version))
+	if err != nil {
+		return err
+	}
+	if len(info.windowsImagesURL) > 0 {
+		windows, err := p.Images(fmt.Sprintf(info.windowsImagesURL, imagesListBaseURL, version))
+		if err == nil {
+			images = append(images, windows...)
+		} else {
+			slog.Error("failed to process windows images", "error", err)
+		}
+	}
+
+	var docs []*vex.Document
+	var withVEX int
+	for _, img := range images {
+		found, err := vex.ForImage(img)
+		if err != nil {
+			slog.Error("failed to get VEX", "image", img, "error", err)
+			continue
+		}
+		if len(found) > 0 {
+			withVEX++
+		}
+		docs = append(docs, found...)
+	}
+
+	author := opts.Author
+	if author == "" && len(docs) > 0 {
+		author = docs[0].Author
+	}
+	if author == "" {
+		author = info.description
+	}
+
+	merged := vex.Merge(author, docs)
+	data, err := json.MarshalIndent(merged, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal VEX: %w", err)
+	}
+
+	fn := filepath.Join(outputDir, fmt.Sprintf("%s_%s.openvex.json", name, version))
+	err = os.WriteFile(fn, data, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	fmt.Printf("%d statements merged from %d of %d images\n", len(merged.Statements), withVEX, len(images))
+	fmt.Printf("\nOpenVEX document saved as %q\n", fn)
+	return nil
+}
@@ -0,0 +1,113 @@
+package product
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/rancherlabs/slsactl/internal/imagelist"
+	"github.com/rancherlabs/slsactl/internal/sbom"
+	"github.com/rancherlabs/slsactl/internal/vex"
+	"github.com/rancherlabs/slsactl/internal/vulns"
+)
+
+// VulnsOptions holds the optional settings for Vulns.
+type VulnsOptions struct {
+	// ImagesListBaseURL overrides the product's default images list location.
+	ImagesListBaseURL string
+	// Output is the format of the report written to stdout: table
+	// (default), json or sarif.
+	Output string
+	// VEX holds OpenVEX documents applied to all images, besides the
+	// OpenVEX attestations of each image.
+	VEX []*vex.Document
+	// Generate configures the generation of the SBOMs of images without
+	// SBOM attestations. Its platform defaults to linux/amd64, and to
+	// windows/amd64 for Windows images.
+	Generate sbom.Options
+}
+
+// Vulns reports the vulnerabilities of db found in the images of the
+// product version. The SBOM of each image is fetched, or generated, and
+// matched offline.
+func Vulns(registry, name, version string, db *vulns.Database, opts VulnsOptions) error {
+	info, err := product(name, version)
+	if err != nil {
+		return err
+	}
+
+	if opts.Output == "" {
+		opts.Output = vulns.OutputTable
+	}
+	err = vulns.ValidateOutput(opts.Output)
+	if err != nil {
+		return err
+	}
+
+	outputDir := fmt.Sprintf("%s-%s", name, version)
+	err = os.MkdirAll(outputDir, 0o755)
+	if err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Matching %s %s against %d vulnerabilities:\n\n", info.description, version, db.Len())
+
+	result, err := collectSBOMs(registry, version, opts.ImagesListBaseURL, outputDir, info, sbom.FormatSyftJSON, opts.Generate)
+	if err != nil {
+		return err
+	}
+
+	report := vulns.Report{Product: name, Version: version}
+	for _, entry := range result.Entries {
+		img := imageReport(entry, db)
+		if img.Error == "" {
+			attached, err := vex.ForImage(entry.Image)
+			if err != nil {
+				slog.Error("failed to get VEX", "image", entry.Image, "error", err)
+			}
+			img.ApplyVEX(append(slices.Clone(opts.VEX), attached...))
+		}
+		report.Add(img)
+	}
+
+	var buf bytes.Buffer
+	err = report.Write(&buf, vulns.OutputJSON)
+	if err != nil {
+		return err
+	}
+	fn := filepath.Join(outputDir, fmt.Sprintf("%s_%s_vulns.json", name, version))
+	err = os.WriteFile(fn, buf.Bytes(), 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	fmt.Fprintln(os.Stdout)
+	err = report.Write(os.Stdout, opts.Output)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "\nreport saved as %q\n", fn)
+
+	return nil
+}
+
+func imageReport(entry imagelist.Entry, db *vulns.Database) vulns.ImageReport {
+	if entry.Error != nil {
+		return vulns.ImageReport{Image: entry.Image, Error: entry.Error.Error()}
+	}
+
+	f, err := os.Open(entry.SBOMFile)
+	if err != nil {
+		return vulns.ImageReport{Image: entry.Image, Error: err.Error()}
+	}
+	defer f.Close()
+
+	pkgs, err := vulns.Packages(f)
+	if err != nil {
+		return vulns.ImageReport{Image: entry.Image, Error: err.Error()}
+	}
+	return vulns.NewImageReport(entry.Image, pkgs, db)
+}
@@ -4,6 +4,7 @@ package provenance
 import (
 	v02 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v0.2"
 	v1 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v1"
+	"github.com/rancherlabs/slsactl/pkg/attestation"
 )
 
 type InternalParameters struct {
@@ -12,25 +13,11 @@ type InternalParameters struct {
 	InvocationUri string `json:"invocationUri,omitempty"`
 }
 
-type BuildKitProvenance02 struct {
-	LinuxAmd64 *ArchProvenance          `json:"linux/amd64,omitempty"`
-	LinuxArm64 *ArchProvenance          `json:"linux/arm64,omitempty"`
-	SLSA       *v02.ProvenancePredicate `json:"SLSA,omitempty"`
-}
-
-type ArchProvenance struct {
-	SLSA v02.ProvenancePredicate `json:"SLSA"`
-}
+// BuildKitProvenance02 holds the SLSA v0.2 provenance of an image by platform.
+type BuildKitProvenance02 = attestation.Platforms[v02.ProvenancePredicate]
 
-type SLSAV1Provenance struct {
-	LinuxAmd64 *ArchProvenanceV1       `json:"linux/amd64,omitempty"`
-	LinuxArm64 *ArchProvenanceV1       `json:"linux/arm64,omitempty"`
-	SLSA       *v1.ProvenancePredicate `json:"SLSA"`
-}
-
-type ArchProvenanceV1 struct {
-	SLSA v1.ProvenancePredicate `json:"SLSA"`
-}
+// SLSAV1Provenance holds the SLSA v1 provenance of an image by platform.
+type SLSAV1Provenance = attestation.Platforms[v1.ProvenancePredicate]
 
 func ConvertV02ToV1(v02Prov v02.ProvenancePredicate, override *v1.ProvenancePredicate) v1.ProvenancePredicate {
 	prov := v1.ProvenancePredicate{
@@ -0,0 +1,71 @@
+package sbom
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/rancherlabs/slsactl/pkg/attestation"
+)
+
+// FromAttestations returns the SBOM of atts in outformat. The attestation in
+// the same standard is preferred and returned as is when outformat is its
+// default JSON format, otherwise it is converted.
+func FromAttestations(atts []attestation.Attestation, outformat string) ([]byte, error) {
+	if len(atts) == 0 {
+		return nil, fmt.Errorf("%w: %s", attestation.ErrNoAttestations, attestation.KindSBOM)
+	}
+
+	want := Standard(outformat)
+
+	chosen := atts[0]
+	for _, a := range atts {
+		if a.SBOMFormat() == want {
+			chosen = a
+			break
+		}
+	}
+
+	predicate, err := chosen.Predicate()
+	if err != nil {
+		return nil, err
+	}
+	if chosen.SBOMFormat() == want &&
+		(outformat == FormatSPDXJSON || outformat == FormatCycloneDXJSON) {
+		return predicate, nil
+	}
+
+	var buf bytes.Buffer
+	err = Convert(bytes.NewReader(predicate), &buf, outformat)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert %s SBOM: %w", chosen.SBOMFormat(), err)
+	}
+	return bytes.TrimSpace(buf.Bytes()), nil
+}
+
+// ForImage returns the SBOM of img in outformat for the platform set in
+// opts, from its attestations or, when it has none, generating it. The
+// SBOMs of LocalSource inputs, which have no attestations, are generated.
+func ForImage(img, outformat string, opts Options) (doc []byte, generated bool, err error) {
+	if _, _, local := LocalSource(img); !local {
+		atts, err := attestation.List(context.TODO(), img, attestation.Options{})
+		if err != nil {
+			return nil, false, err
+		}
+
+		sboms := attestation.ByPlatform(attestation.Filter(atts, attestation.KindSBOM))
+		if found, ok := sboms.Lookup(opts.Platform); ok {
+			doc, err = FromAttestations(found, outformat)
+			if err == nil && len(doc) > 0 {
+				return doc, false, nil
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	err = Generate(img, outformat, opts, &buf)
+	if err != nil {
+		return nil, false, err
+	}
+	return buf.Bytes(), true, nil
+}
@@ -0,0 +1,140 @@
+package sbom
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/cache"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+)
+
+// layerCache caches the layers of registry images in dir, so that images
+// sharing base layers download them once. Layers are written to temporary
+// files, renamed into place only when their content matches their digest,
+// so concurrent generations never read partial layers.
+type layerCache struct {
+	dir string
+}
+
+var _ cache.Cache = layerCache{}
+
+func (c layerCache) path(h v1.Hash) string {
+	return filepath.Join(c.dir, h.Algorithm+"-"+h.Hex)
+}
+
+func (c layerCache) Put(l v1.Layer) (v1.Layer, error) {
+	digest, err := l.Digest()
+	if err != nil {
+		return nil, err
+	}
+	diffID, err := l.DiffID()
+	if err != nil {
+		return nil, err
+	}
+	return &cachedLayer{Layer: l, cache: c, digest: digest, diffID: diffID}, nil
+}
+
+func (c layerCache) Get(h v1.Hash) (v1.Layer, error) {
+	l, err := tarball.LayerFromFile(c.path(h))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, cache.ErrNotFound
+	}
+	return l, err
+}
+
+func (c layerCache) Delete(h v1.Hash) error {
+	err := os.Remove(c.path(h))
+	if errors.Is(err, fs.ErrNotExist) {
+		return cache.ErrNotFound
+	}
+	return err
+}
+
+// cachedLayer is a layer whose content is added to the cache as it is read.
+type cachedLayer struct {
+	v1.Layer
+	cache          layerCache
+	digest, diffID v1.Hash
+}
+
+func (l *cachedLayer) Compressed() (io.ReadCloser, error) {
+	rc, err := l.Layer.Compressed()
+	if err != nil {
+		return nil, err
+	}
+	return l.cache.writer(rc, l.digest), nil
+}
+
+func (l *cachedLayer) Uncompressed() (io.ReadCloser, error) {
+	rc, err := l.Layer.Uncompressed()
+	if err != nil {
+		return nil, err
+	}
+	return l.cache.writer(rc, l.diffID), nil
+}
+
+// writer returns rc, copying what is read from it into the cache as h.
+// Caching is best effort: rc is returned as is when the cache cannot be
+// written.
+func (c layerCache) writer(rc io.ReadCloser, h v1.Hash) io.ReadCloser {
+	if h.Algorithm != "sha256" {
+		return rc
+	}
+
+	err := os.MkdirAll(c.dir, 0o700)
+	if err != nil {
+		slog.Debug("failed to create layer cache", "path", c.dir, "error", err)
+		return rc
+	}
+	f, err := os.CreateTemp(c.dir, h.Hex+"-*.tmp")
+	if err != nil {
+		slog.Debug("failed to create cached layer", "digest", h, "error", err)
+		return rc
+	}
+
+	return &cacheWriter{rc: rc, f: f, hash: sha256.New(), want: h, path: c.path(h)}
+}
+
+type cacheWriter struct {
+	rc     io.ReadCloser
+	f      *os.File
+	hash   hash.Hash
+	want   v1.Hash
+	path   string
+	failed bool
+}
+
+func (w *cacheWriter) Read(b []byte) (int, error) {
+	n, err := w.rc.Read(b)
+	if n > 0 && !w.failed {
+		w.hash.Write(b[:n])
+		if _, werr := w.f.Write(b[:n]); werr != nil {
+			w.failed = true
+		}
+	}
+	return n, err
+}
+
+// Close closes the layer, and adds it to the cache when it was read fully.
+func (w *cacheWriter) Close() error {
+	err := w.rc.Close()
+	ferr := w.f.Close()
+
+	if w.failed || ferr != nil || hex.EncodeToString(w.hash.Sum(nil)) != w.want.Hex {
+		_ = os.Remove(w.f.Name())
+		return err
+	}
+	if rerr := os.Rename(w.f.Name(), w.path); rerr != nil {
+		slog.Debug("failed to add layer to cache", "digest", w.want, "error", rerr)
+		_ = os.Remove(w.f.Name())
+	}
+	return err
+}
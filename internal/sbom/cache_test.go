@@ -0,0 +1,60 @@
+package sbom
+
+import (
+	"io"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/v1/cache"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLayerCache(t *testing.T) {
+	t.Parallel()
+
+	c := layerCache{dir: t.TempDir()}
+	layer, err := random.Layer(4096, types.OCILayer)
+	require.NoError(t, err)
+	diffID, err := layer.DiffID()
+	require.NoError(t, err)
+	digest, err := layer.Digest()
+	require.NoError(t, err)
+
+	cached, err := c.Put(layer)
+	require.NoError(t, err)
+
+	// Layers not read fully are not cached.
+	rc, err := cached.Uncompressed()
+	require.NoError(t, err)
+	_, err = io.CopyN(io.Discard, rc, 512)
+	require.NoError(t, err)
+	require.NoError(t, rc.Close())
+
+	_, err = c.Get(diffID)
+	require.ErrorIs(t, err, cache.ErrNotFound)
+
+	for _, open := range []func() (io.ReadCloser, error){cached.Uncompressed, cached.Compressed} {
+		rc, err := open()
+		require.NoError(t, err)
+		_, err = io.Copy(io.Discard, rc)
+		require.NoError(t, err)
+		require.NoError(t, rc.Close())
+	}
+
+	got, err := c.Get(diffID)
+	require.NoError(t, err)
+	gotDiffID, err := got.DiffID()
+	require.NoError(t, err)
+	assert.Equal(t, diffID, gotDiffID)
+
+	got, err = c.Get(digest)
+	require.NoError(t, err)
+	gotDigest, err := got.Digest()
+	require.NoError(t, err)
+	assert.Equal(t, digest, gotDigest)
+
+	require.NoError(t, c.Delete(diffID))
+	require.ErrorIs(t, c.Delete(diffID), cache.ErrNotFound)
+}
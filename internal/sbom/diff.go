@@ -0,0 +1,185 @@
+package sbom
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/anchore/syft/syft/format"
+)
+
+// Diff holds the packages that differ between two SBOMs.
+type Diff struct {
+	Added   []PackageDiff `json:"added"`
+	Removed []PackageDiff `json:"removed"`
+	// Changed holds the packages found in both SBOMs whose versions or
+	// licenses differ.
+	Changed []PackageDiff `json:"changed"`
+}
+
+// PackageDiff is a package that differs between two SBOMs. Packages are
+// identified by type and name, so a package may have several versions,
+// such as a Go module vendored by different binaries.
+type PackageDiff struct {
+	Name   string        `json:"name"`
+	Type   string        `json:"type"`
+	Before *PackageState `json:"before,omitempty"`
+	After  *PackageState `json:"after,omitempty"`
+}
+
+// PackageState is a package as found in one of the SBOMs.
+type PackageState struct {
+	Versions []string `json:"versions"`
+	Licenses []string `json:"licenses,omitempty"`
+}
+
+// VersionChanged reports whether the versions of d differ.
+func (d PackageDiff) VersionChanged() bool {
+	return d.Before != nil && d.After != nil && !slices.Equal(d.Before.Versions, d.After.Versions)
+}
+
+// LicenseChanged reports whether the licenses of d differ.
+func (d PackageDiff) LicenseChanged() bool {
+	return d.Before != nil && d.After != nil && !slices.Equal(d.Before.Licenses, d.After.Licenses)
+}
+
+type diffKey struct {
+	typ  string
+	name string
+}
+
+// CompareSBOMs returns the packages added, removed and changed from the SBOM
+// before to the SBOM after, in any format known to Syft.
+func CompareSBOMs(before, after []byte) (*Diff, error) {
+	a, err := diffPackages(before)
+	if err != nil {
+		return nil, err
+	}
+	b, err := diffPackages(after)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Diff{Added: []PackageDiff{}, Removed: []PackageDiff{}, Changed: []PackageDiff{}}
+	for _, k := range sortedKeys(a, b) {
+		pa, inA := a[k]
+		pb, inB := b[k]
+		pd := PackageDiff{Name: k.name, Type: k.typ}
+
+		switch {
+		case !inA:
+			pd.After = pb
+			d.Added = append(d.Added, pd)
+		case !inB:
+			pd.Before = pa
+			d.Removed = append(d.Removed, pd)
+		default:
+			pd.Before, pd.After = pa, pb
+			if pd.VersionChanged() || pd.LicenseChanged() {
+				d.Changed = append(d.Changed, pd)
+			}
+		}
+	}
+	return d, nil
+}
+
+func diffPackages(doc []byte) (map[diffKey]*PackageState, error) {
+	s, _, _, err := format.Decode(bytes.NewReader(doc))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode SBOM: %w", err)
+	}
+	if s == nil {
+		return nil, fmt.Errorf("failed to decode SBOM: unknown format")
+	}
+
+	pkgs := map[diffKey]*PackageState{}
+	for _, p := range s.Artifacts.Packages.Sorted() {
+		k := diffKey{typ: string(p.Type), name: p.Name}
+		state, ok := pkgs[k]
+		if !ok {
+			state = &PackageState{}
+			pkgs[k] = state
+		}
+
+		if p.Version != "" && !slices.Contains(state.Versions, p.Version) {
+			state.Versions = append(state.Versions, p.Version)
+		}
+		for _, l := range p.Licenses.ToSlice() {
+			name := cmp.Or(l.SPDXExpression, l.Value)
+			if name != "" && !slices.Contains(state.Licenses, name) {
+				state.Licenses = append(state.Licenses, name)
+			}
+		}
+	}
+
+	for _, state := range pkgs {
+		slices.Sort(state.Versions)
+		slices.Sort(state.Licenses)
+	}
+	return pkgs, nil
+}
+
+func sortedKeys(a, b map[diffKey]*PackageState) []diffKey {
+	keys := slices.Collect(maps.Keys(a))
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	slices.SortFunc(keys, func(x, y diffKey) int {
+		return cmp.Or(strings.Compare(x.name, y.name), strings.Compare(x.typ, y.typ))
+	})
+	return keys
+}
+
+// WriteJSON writes d to w as indented JSON.
+func (d *Diff) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(d)
+}
+
+// WriteTable writes d to w as a table, followed by the number of packages
+// in each category.
+func (d *Diff) WriteTable(w io.Writer) error {
+	tw := new(tabwriter.Writer)
+	tw.Init(w, 12, 12, 4, ' ', 0)
+
+	fmt.Fprintln(tw, "Change\tPackage\tType\tBefore\tAfter\tLicenses")
+	fmt.Fprintln(tw, "------\t-------\t----\t------\t-----\t--------")
+	for _, p := range d.Added {
+		fmt.Fprintf(tw, "added\t%s\t%s\t\t%s\t%s\n", p.Name, p.Type, versions(p.After), licenses(p.After))
+	}
+	for _, p := range d.Removed {
+		fmt.Fprintf(tw, "removed\t%s\t%s\t%s\t\t%s\n", p.Name, p.Type, versions(p.Before), licenses(p.Before))
+	}
+	for _, p := range d.Changed {
+		change := "version"
+		if !p.VersionChanged() {
+			change = "license"
+		}
+
+		lic := licenses(p.After)
+		if p.LicenseChanged() {
+			lic = cmp.Or(licenses(p.Before), "none") + " -> " + cmp.Or(licenses(p.After), "none")
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", change, p.Name, p.Type, versions(p.Before), versions(p.After), lic)
+	}
+
+	fmt.Fprintf(tw, "\n%d added, %d removed, %d changed\n", len(d.Added), len(d.Removed), len(d.Changed))
+	return tw.Flush()
+}
+
+func versions(s *PackageState) string {
+	return strings.Join(s.Versions, ", ")
+}
+
+func licenses(s *PackageState) string {
+	return strings.Join(s.Licenses, ", ")
+}
@@ -0,0 +1,73 @@
+package sbom
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func cdxDoc(components ...string) []byte {
+	return fmt.Appendf(nil, `{
+  "bomFormat": "CycloneDX",
+  "specVersion": "1.6",
+  "version": 1,
+  "components": [%s]
+}`, strings.Join(components, ","))
+}
+
+func cdxComponent(name, version, purl, license string) string {
+	licenses := ""
+	if license != "" {
+		licenses = fmt.Sprintf(`, "licenses": [{"license": {"id": %q}}]`, license)
+	}
+	return fmt.Sprintf(`{"type": "library", "name": %q, "version": %q, "purl": %q%s}`, name, version, purl, licenses)
+}
+
+func TestCompareSBOMs(t *testing.T) {
+	t.Parallel()
+
+	before := cdxDoc(
+		cdxComponent("libc6", "2.36-9", "pkg:deb/debian/libc6@2.36-9", "GPL-2.0-only"),
+		cdxComponent("bash", "5.2-1", "pkg:deb/debian/bash@5.2-1", "GPL-3.0-only"),
+		cdxComponent("golang.org/x/net", "v0.20.0", "pkg:golang/golang.org/x/net@v0.20.0", "BSD-3-Clause"),
+		cdxComponent("zlib1g", "1.2.13", "pkg:deb/debian/zlib1g@1.2.13", ""),
+	)
+	after := cdxDoc(
+		cdxComponent("libc6", "2.36-9+deb12u7", "pkg:deb/debian/libc6@2.36-9%2Bdeb12u7", "GPL-2.0-only"),
+		cdxComponent("golang.org/x/net", "v0.20.0", "pkg:golang/golang.org/x/net@v0.20.0", "BSD-3-Clause"),
+		cdxComponent("zlib1g", "1.2.13", "pkg:deb/debian/zlib1g@1.2.13", "Zlib"),
+		cdxComponent("curl", "7.88.1", "pkg:deb/debian/curl@7.88.1", "curl"),
+	)
+
+	d, err := CompareSBOMs(before, after)
+	require.NoError(t, err)
+
+	require.Len(t, d.Added, 1)
+	assert.Equal(t, "curl", d.Added[0].Name)
+	assert.Equal(t, "deb", d.Added[0].Type)
+	assert.Equal(t, []string{"7.88.1"}, d.Added[0].After.Versions)
+
+	require.Len(t, d.Removed, 1)
+	assert.Equal(t, "bash", d.Removed[0].Name)
+
+	require.Len(t, d.Changed, 2)
+	assert.Equal(t, "libc6", d.Changed[0].Name)
+	assert.True(t, d.Changed[0].VersionChanged())
+	assert.False(t, d.Changed[0].LicenseChanged())
+	assert.Equal(t, []string{"2.36-9+deb12u7"}, d.Changed[0].After.Versions)
+	assert.Equal(t, "zlib1g", d.Changed[1].Name)
+	assert.False(t, d.Changed[1].VersionChanged())
+	assert.True(t, d.Changed[1].LicenseChanged())
+
+	var buf bytes.Buffer
+	require.NoError(t, d.WriteTable(&buf))
+	assert.Contains(t, buf.String(), "none -> Zlib")
+	assert.Contains(t, buf.String(), "1 added, 1 removed, 2 changed")
+
+	_, err = CompareSBOMs(before, []byte("not an SBOM"))
+	assert.Error(t, err)
+}
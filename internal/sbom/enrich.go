@@ -0,0 +1,241 @@
+package sbom
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	cdx "github.com/CycloneDX/cyclonedx-go"
+	"github.com/rancherlabs/slsactl/pkg/attestation"
+	spdxjson "github.com/spdx/tools-golang/json"
+	"github.com/spdx/tools-golang/spdx/v2/common"
+)
+
+// propertyPrefix prefixes the CycloneDX properties set by Enrich.
+const propertyPrefix = "slsactl:source:"
+
+// ErrNoSource indicates no source metadata was found for an image.
+var ErrNoSource = errors.New("no source metadata found")
+
+// Source is where an image was built from, as injected into its SBOM by
+// Enrich.
+type Source struct {
+	// RepositoryURI is the source repository, such as
+	// https://github.com/rancher/cis-operator.
+	RepositoryURI string
+	// Commit is the source repository commit digest.
+	Commit string
+	// Ref is the source repository ref, such as refs/tags/v1.0.15.
+	Ref string
+	// WorkflowRef is the workflow that built the image.
+	WorkflowRef string
+	// Trigger is the event that triggered the build, such as push.
+	Trigger string
+}
+
+// IsZero returns whether s holds no metadata.
+func (s Source) IsZero() bool {
+	return s == Source{}
+}
+
+// Merge returns s, with its empty fields set from other.
+func (s Source) Merge(other Source) Source {
+	if s.RepositoryURI == "" {
+		s.RepositoryURI = other.RepositoryURI
+	}
+	if s.Commit == "" {
+		s.Commit = other.Commit
+	}
+	if s.Ref == "" {
+		s.Ref = other.Ref
+	}
+	if s.WorkflowRef == "" {
+		s.WorkflowRef = other.WorkflowRef
+	}
+	if s.Trigger == "" {
+		s.Trigger = other.Trigger
+	}
+	return s
+}
+
+// sourceField is a Source field, with its SPDX label and CycloneDX
+// property name.
+type sourceField struct {
+	label, property, value string
+}
+
+// fields returns the fields of s that are set.
+func (s Source) fields() []sourceField {
+	all := []sourceField{
+		{"Source repository", "repository", s.RepositoryURI},
+		{"Source commit", "commit", s.Commit},
+		{"Source ref", "ref", s.Ref},
+		{"Workflow ref", "workflow-ref", s.WorkflowRef},
+		{"Build trigger", "trigger", s.Trigger},
+	}
+
+	var set []sourceField
+	for _, f := range all {
+		if f.value != "" {
+			set = append(set, f)
+		}
+	}
+	return set
+}
+
+// SourceFromProvenance returns the source of an image from its SLSA v1 or
+// v0.2 provenance predicate: the first Git repository among its resolved
+// dependencies, or materials, and the trigger of provenance converted from
+// signing certificates.
+func SourceFromProvenance(predicate []byte) (Source, error) {
+	type dependency struct {
+		URI         string            `json:"uri"`
+		Digest      map[string]string `json:"digest"`
+		Annotations map[string]any    `json:"annotations"`
+	}
+	var p struct {
+		BuildDefinition struct {
+			InternalParameters   json.RawMessage `json:"internalParameters"`
+			ResolvedDependencies []dependency    `json:"resolvedDependencies"`
+		} `json:"buildDefinition"`
+		Materials []dependency `json:"materials"`
+	}
+	err := json.Unmarshal(predicate, &p)
+	if err != nil {
+		return Source{}, fmt.Errorf("failed to decode provenance: %w", err)
+	}
+
+	var src Source
+	for _, d := range append(p.BuildDefinition.ResolvedDependencies, p.Materials...) {
+		commit := d.Digest["gitCommit"]
+		if commit == "" {
+			commit = d.Digest["sha1"]
+		}
+		if commit == "" || d.URI == "" || strings.HasPrefix(d.URI, "pkg:") {
+			continue
+		}
+
+		// BuildKit records Git contexts as <repository>#<ref>.
+		uri, ref, _ := strings.Cut(d.URI, "#")
+		if r, ok := d.Annotations["ref"].(string); ok && r != "" {
+			ref = r
+		}
+		src = Source{RepositoryURI: uri, Commit: commit, Ref: ref}
+		break
+	}
+
+	var params struct {
+		Trigger string `json:"trigger"`
+	}
+	if json.Unmarshal(p.BuildDefinition.InternalParameters, &params) == nil {
+		src.Trigger = params.Trigger
+	}
+	return src, nil
+}
+
+// Enrich returns doc, an SPDX 2 or CycloneDX JSON document, with src added
+// to its creation info and to the metadata of the components it describes.
+// SPDX documents get it in the creation comment and the source info of the
+// described packages, CycloneDX documents as properties of the metadata
+// and of its component, which also references the repository.
+func Enrich(doc []byte, src Source) ([]byte, error) {
+	if src.IsZero() {
+		return nil, ErrNoSource
+	}
+
+	switch attestation.DetectSBOMFormat(doc) {
+	case attestation.FormatSPDX:
+		return enrichSPDX(doc, src)
+	case attestation.FormatCycloneDX:
+		return enrichCycloneDX(doc, src)
+	}
+	return nil, fmt.Errorf("%w: enriching supports %s or %s",
+		ErrUnsupportedFormat, FormatSPDXJSON, FormatCycloneDXJSON)
+}
+
+func enrichSPDX(data []byte, src Source) ([]byte, error) {
+	doc, err := spdxjson.Read(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode SPDX SBOM: %w", err)
+	}
+
+	lines := make([]string, 0, len(src.fields()))
+	for _, f := range src.fields() {
+		lines = append(lines, f.label+": "+f.value)
+	}
+
+	if doc.CreationInfo != nil {
+		doc.CreationInfo.CreatorComment = strings.TrimSpace(
+			doc.CreationInfo.CreatorComment + "\n" + strings.Join(lines, "\n"))
+	}
+
+	described := map[common.ElementID]bool{}
+	for _, r := range doc.Relationships {
+		if r.RefA.ElementRefID == "DOCUMENT" && r.Relationship == common.TypeRelationshipDescribe {
+			described[r.RefB.ElementRefID] = true
+		}
+	}
+	for _, p := range doc.Packages {
+		if described[p.PackageSPDXIdentifier] {
+			p.PackageSourceInfo = strings.TrimSpace(
+				p.PackageSourceInfo + "\n" + strings.Join(lines, "; "))
+		}
+	}
+
+	var buf bytes.Buffer
+	err = spdxjson.Write(doc, &buf, spdxjson.Indent("  "))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode SPDX SBOM: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func enrichCycloneDX(data []byte, src Source) ([]byte, error) {
+	var bom cdx.BOM
+	err := cdx.NewBOMDecoder(bytes.NewReader(data), cdx.BOMFileFormatJSON).Decode(&bom)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode CycloneDX SBOM: %w", err)
+	}
+
+	props := make([]cdx.Property, 0, len(src.fields()))
+	for _, f := range src.fields() {
+		props = append(props, cdx.Property{Name: propertyPrefix + f.property, Value: f.value})
+	}
+
+	if bom.Metadata == nil {
+		bom.Metadata = &cdx.Metadata{}
+	}
+	bom.Metadata.Properties = appendProperties(bom.Metadata.Properties, props)
+
+	if c := bom.Metadata.Component; c != nil {
+		c.Properties = appendProperties(c.Properties, props)
+		if src.RepositoryURI != "" {
+			ref := cdx.ExternalReference{URL: src.RepositoryURI, Type: cdx.ERTypeVCS}
+			if src.Commit != "" {
+				ref.Comment = "commit " + src.Commit
+			}
+			refs := []cdx.ExternalReference{ref}
+			if c.ExternalReferences != nil {
+				refs = append(*c.ExternalReferences, ref)
+			}
+			c.ExternalReferences = &refs
+		}
+	}
+
+	var buf bytes.Buffer
+	err = cdx.NewBOMEncoder(&buf, cdx.BOMFileFormatJSON).SetPretty(true).EncodeVersion(&bom, bom.SpecVersion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode CycloneDX SBOM: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func appendProperties(to *[]cdx.Property, props []cdx.Property) *[]cdx.Property {
+	if to == nil {
+		return &props
+	}
+	all := append(*to, props...)
+	return &all
+}
@@ -0,0 +1,187 @@
+package sbom
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSourceFromProvenance(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		provenance string
+		want       Source
+	}{
+		{
+			name: "buildkit v1 git context",
+			provenance: `{"buildDefinition":{"resolvedDependencies":[
+				{"uri":"pkg:docker/golang@1.24","digest":{"sha256":"abc"}},
+				{"uri":"https://github.com/rancher/cis-operator.git#refs/tags/v1.0.15","digest":{"sha1":"0123abc"}}]}}`,
+			want: Source{
+				RepositoryURI: "https://github.com/rancher/cis-operator.git",
+				Commit:        "0123abc",
+				Ref:           "refs/tags/v1.0.15",
+			},
+		},
+		{
+			name: "converted from certificate",
+			provenance: `{"buildDefinition":{"internalParameters":{"trigger":"push"},"resolvedDependencies":[
+				{"uri":"https://github.com/rancher/cis-operator","digest":{"gitCommit":"0123abc"},"annotations":{"ref":"refs/tags/v1.0.15"}}]}}`,
+			want: Source{
+				RepositoryURI: "https://github.com/rancher/cis-operator",
+				Commit:        "0123abc",
+				Ref:           "refs/tags/v1.0.15",
+				Trigger:       "push",
+			},
+		},
+		{
+			name: "v0.2 materials",
+			provenance: `{"buildType":"https://mobyproject.org/buildkit@v1","materials":[
+				{"uri":"https://github.com/rancher/cis-operator.git#main","digest":{"sha1":"0123abc"}}]}`,
+			want: Source{
+				RepositoryURI: "https://github.com/rancher/cis-operator.git",
+				Commit:        "0123abc",
+				Ref:           "main",
+			},
+		},
+		{
+			name:       "no git dependency",
+			provenance: `{"buildDefinition":{"resolvedDependencies":[{"uri":"pkg:docker/golang@1.24","digest":{"sha256":"abc"}}]}}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := SourceFromProvenance([]byte(tc.provenance))
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestSourceMerge(t *testing.T) {
+	t.Parallel()
+
+	got := Source{RepositoryURI: "https://github.com/a/b", Commit: "1"}.
+		Merge(Source{RepositoryURI: "https://github.com/c/d", Commit: "2", Trigger: "push"})
+	assert.Equal(t, Source{RepositoryURI: "https://github.com/a/b", Commit: "1", Trigger: "push"}, got)
+}
+
+var testSource = Source{
+	RepositoryURI: "https://github.com/rancher/cis-operator",
+	Commit:        "0123abc",
+	WorkflowRef:   "https://github.com/rancher/cis-operator/.github/workflows/release.yml@refs/tags/v1.0.15",
+	Trigger:       "push",
+}
+
+func TestEnrichSPDX(t *testing.T) {
+	t.Parallel()
+
+	got, err := Enrich([]byte(cisoperatorAMD64Spdx), testSource)
+	require.NoError(t, err)
+
+	var doc struct {
+		CreationInfo struct {
+			Comment string `json:"comment"`
+		} `json:"creationInfo"`
+		Relationships []struct {
+			Element      string `json:"spdxElementId"`
+			Related      string `json:"relatedSpdxElement"`
+			Relationship string `json:"relationshipType"`
+		} `json:"relationships"`
+		Packages []struct {
+			SPDXID     string `json:"SPDXID"`
+			SourceInfo string `json:"sourceInfo"`
+		} `json:"packages"`
+	}
+	require.NoError(t, json.Unmarshal(got, &doc))
+
+	assert.Equal(t, "Source repository: https://github.com/rancher/cis-operator\n"+
+		"Source commit: 0123abc\n"+
+		"Workflow ref: https://github.com/rancher/cis-operator/.github/workflows/release.yml@refs/tags/v1.0.15\n"+
+		"Build trigger: push", doc.CreationInfo.Comment)
+
+	var described []string
+	for _, r := range doc.Relationships {
+		if r.Element == "SPDXRef-DOCUMENT" && r.Relationship == "DESCRIBES" {
+			described = append(described, r.Related)
+		}
+	}
+	require.NotEmpty(t, described)
+
+	var enriched int
+	for _, p := range doc.Packages {
+		if slices.Contains(described, p.SPDXID) {
+			enriched++
+			assert.Contains(t, p.SourceInfo, "Source repository: https://github.com/rancher/cis-operator; Source commit: 0123abc")
+		} else {
+			assert.NotContains(t, p.SourceInfo, "Source repository")
+		}
+	}
+	assert.Equal(t, len(described), enriched)
+
+	v, err := Validate(got)
+	require.NoError(t, err)
+	assert.True(t, v.Valid(), v.SchemaErrors)
+}
+
+func TestEnrichCycloneDX(t *testing.T) {
+	t.Parallel()
+
+	in := `{"bomFormat":"CycloneDX","specVersion":"1.5","version":1,
+		"metadata":{"component":{"bom-ref":"img","type":"container","name":"rancher/cis-operator"}}}`
+
+	got, err := Enrich([]byte(in), testSource)
+	require.NoError(t, err)
+
+	type property struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}
+	var doc struct {
+		SpecVersion string `json:"specVersion"`
+		Metadata    struct {
+			Properties []property `json:"properties"`
+			Component  struct {
+				Properties         []property `json:"properties"`
+				ExternalReferences []struct {
+					URL     string `json:"url"`
+					Type    string `json:"type"`
+					Comment string `json:"comment"`
+				} `json:"externalReferences"`
+			} `json:"component"`
+		} `json:"metadata"`
+	}
+	require.NoError(t, json.Unmarshal(got, &doc))
+
+	want := []property{
+		{"slsactl:source:repository", "https://github.com/rancher/cis-operator"},
+		{"slsactl:source:commit", "0123abc"},
+		{"slsactl:source:workflow-ref", "https://github.com/rancher/cis-operator/.github/workflows/release.yml@refs/tags/v1.0.15"},
+		{"slsactl:source:trigger", "push"},
+	}
+	assert.Equal(t, "1.5", doc.SpecVersion)
+	assert.Equal(t, want, doc.Metadata.Properties)
+	assert.Equal(t, want, doc.Metadata.Component.Properties)
+	require.Len(t, doc.Metadata.Component.ExternalReferences, 1)
+	assert.Equal(t, "https://github.com/rancher/cis-operator", doc.Metadata.Component.ExternalReferences[0].URL)
+	assert.Equal(t, "vcs", doc.Metadata.Component.ExternalReferences[0].Type)
+	assert.Equal(t, "commit 0123abc", doc.Metadata.Component.ExternalReferences[0].Comment)
+}
+
+func TestEnrichErrors(t *testing.T) {
+	t.Parallel()
+
+	_, err := Enrich([]byte(cisoperatorAMD64Spdx), Source{})
+	require.ErrorIs(t, err, ErrNoSource)
+
+	_, err = Enrich([]byte(`{"foo":"bar"}`), testSource)
+	require.ErrorIs(t, err, ErrUnsupportedFormat)
+}
@@ -0,0 +1,107 @@
+package sbom
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/anchore/syft/syft/format"
+	"github.com/anchore/syft/syft/sbom"
+	"github.com/rancherlabs/slsactl/pkg/attestation"
+)
+
+// Output formats of Generate and Convert. A version may be selected by
+// appending it, as in cyclonedxjson@1.5 or spdxjson@2.2.
+const (
+	FormatSPDXJSON      = "spdxjson"
+	FormatSPDXTagValue  = "spdxtagvalue"
+	FormatCycloneDXJSON = "cyclonedxjson"
+	FormatCycloneDXXML  = "cyclonedxxml"
+	FormatSyftJSON      = "syftjson"
+	// FormatSPDX3JSON is SPDX 3.0 JSON-LD, the same as spdxjson@3.0.
+	FormatSPDX3JSON = "spdx3json"
+)
+
+// ErrUnsupportedFormat indicates an unknown output format or version.
+var ErrUnsupportedFormat = errors.New("unsupported SBOM format")
+
+// Formats returns the supported output formats.
+func Formats() []string {
+	return []string{
+		FormatSPDXJSON, FormatSPDXTagValue, FormatSPDX3JSON,
+		FormatCycloneDXJSON, FormatCycloneDXXML, FormatSyftJSON,
+	}
+}
+
+// ValidateFormat returns an error when outformat is not supported.
+func ValidateFormat(outformat string) error {
+	_, err := encoder(outformat, false)
+	return err
+}
+
+// Standard returns the SBOM standard of outformat, attestation.FormatSPDX or
+// attestation.FormatCycloneDX, or empty for Syft JSON.
+func Standard(outformat string) string {
+	name, _, _ := strings.Cut(cleanFormat(outformat), "@")
+	switch name {
+	case FormatSPDXJSON, FormatSPDXTagValue, FormatSPDX3JSON:
+		return attestation.FormatSPDX
+	case FormatCycloneDXJSON, FormatCycloneDXXML:
+		return attestation.FormatCycloneDX
+	}
+	return ""
+}
+
+// Extension returns the file extension of outformat, such as .spdx.json.
+func Extension(outformat string) string {
+	name, _, _ := strings.Cut(cleanFormat(outformat), "@")
+	switch name {
+	case FormatSPDXTagValue:
+		return ".spdx"
+	case FormatCycloneDXXML:
+		return ".cyclonedx.xml"
+	case FormatSyftJSON:
+		return ".syft.json"
+	}
+	if s := Standard(outformat); s != "" {
+		return "." + s + ".json"
+	}
+	return ".json"
+}
+
+func encoder(outformat string, pretty bool) (sbom.FormatEncoder, error) {
+	name, version, _ := strings.Cut(cleanFormat(outformat), "@")
+	switch name {
+	case FormatSPDX3JSON:
+		name, version = FormatSPDXJSON, "3.0"
+	case FormatSPDXJSON, FormatSPDXTagValue, FormatCycloneDXJSON, FormatCycloneDXXML, FormatSyftJSON:
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedFormat, outformat)
+	}
+
+	if version == "" {
+		version = sbom.AnyVersion
+	}
+
+	cfg := format.DefaultEncodersConfig()
+	cfg.SPDXJSON.Pretty = pretty
+	cfg.CyclonedxJSON.Pretty = pretty
+	cfg.CyclonedxXML.Pretty = pretty
+	cfg.SyftJSON.Pretty = pretty
+
+	encs, err := cfg.Encoders()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create encoders: %w", err)
+	}
+
+	enc := format.NewEncoderCollection(encs...).Get(name, version)
+	if enc == nil {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedFormat, outformat)
+	}
+	return enc, nil
+}
+
+// cleanFormat accepts the Syft format names too, such as spdx-json.
+func cleanFormat(outformat string) string {
+	return strings.NewReplacer("-", "", "_", "").Replace(strings.ToLower(outformat))
+}
@@ -0,0 +1,331 @@
+package sbom
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	cdx "github.com/CycloneDX/cyclonedx-go"
+	"github.com/google/uuid"
+	spdxjson "github.com/spdx/tools-golang/json"
+	"github.com/spdx/tools-golang/spdx"
+	"github.com/spdx/tools-golang/spdx/v2/common"
+)
+
+// Image is the SBOM of one of the images merged by Merge.
+type Image struct {
+	// Name is the image reference.
+	Name string
+	// SBOM is the document, in any format known to Syft.
+	SBOM []byte
+}
+
+// Merge writes to writer a single SBOM for the product name at version,
+// holding the SBOMs of all its images. Each image is a package, or a
+// component, containing its own packages, and the relationships between
+// packages are kept. Packages found in several images are included once,
+// identified by their package URL or otherwise by their name and version.
+// The file details of the image SBOMs are not included.
+//
+// outformat is either spdxjson or cyclonedxjson.
+func Merge(name, version string, images []Image, outformat string, writer io.Writer) error {
+	var merge func(string, string, []Image, io.Writer) error
+	outformat = cleanFormat(outformat)
+	switch outformat {
+	case FormatSPDXJSON:
+		merge = mergeSPDX
+	case FormatCycloneDXJSON:
+		merge = mergeCycloneDX
+	default:
+		return fmt.Errorf("%w: %q: merging supports %s or %s",
+			ErrUnsupportedFormat, outformat, FormatSPDXJSON, FormatCycloneDXJSON)
+	}
+
+	normalized := make([]Image, 0, len(images))
+	for _, img := range images {
+		doc, err := normalize(img.SBOM, outformat)
+		if err != nil {
+			return fmt.Errorf("failed to read SBOM of %s: %w", img.Name, err)
+		}
+		normalized = append(normalized, Image{Name: img.Name, SBOM: doc})
+	}
+
+	return merge(name, version, normalized, writer)
+}
+
+// normalize returns doc in outformat, converting it when needed.
+func normalize(doc []byte, outformat string) ([]byte, error) {
+	var head struct {
+		BOMFormat   string `json:"bomFormat"`
+		SPDXVersion string `json:"spdxVersion"`
+	}
+	if json.Unmarshal(doc, &head) == nil {
+		if (outformat == FormatCycloneDXJSON && strings.EqualFold(head.BOMFormat, "CycloneDX")) ||
+			(outformat == FormatSPDXJSON && strings.HasPrefix(head.SPDXVersion, "SPDX-2.")) {
+			return doc, nil
+		}
+	}
+
+	var buf bytes.Buffer
+	err := Convert(bytes.NewReader(doc), &buf, outformat)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// packageKey identifies the same package across images.
+func packageKey(purl, name, version string) string {
+	if purl != "" {
+		return purl
+	}
+	return name + "@" + version
+}
+
+func mergeCycloneDX(name, version string, images []Image, writer io.Writer) error {
+	product := cdx.Component{
+		BOMRef:  name + "@" + version,
+		Type:    cdx.ComponentTypeApplication,
+		Name:    name,
+		Version: version,
+	}
+
+	bom := cdx.NewBOM()
+	bom.SerialNumber = uuid.New().URN()
+	bom.Metadata = &cdx.Metadata{
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		Component: &product,
+	}
+
+	seen := map[string]bool{}
+	deps := map[string][]string{}
+	var components []cdx.Component
+
+	for _, img := range images {
+		var doc cdx.BOM
+		err := cdx.NewBOMDecoder(bytes.NewReader(img.SBOM), cdx.BOMFileFormatJSON).Decode(&doc)
+		if err != nil {
+			return fmt.Errorf("failed to decode CycloneDX SBOM of %s: %w", img.Name, err)
+		}
+
+		image := cdx.Component{Type: cdx.ComponentTypeContainer, Name: img.Name}
+		refs := map[string]string{}
+		if doc.Metadata != nil && doc.Metadata.Component != nil {
+			image = *doc.Metadata.Component
+			image.Components = nil
+			refs[image.BOMRef] = img.Name
+		}
+		image.BOMRef = img.Name
+
+		// Packages already seen in other images are only referenced from
+		// the dependency graph.
+		var nested []cdx.Component
+		for _, c := range flattenComponents(doc.Components) {
+			key := packageKey(c.PackageURL, c.Name, c.Version)
+			refs[c.BOMRef] = key
+			deps[img.Name] = append(deps[img.Name], key)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			c.BOMRef = key
+			c.Components = nil
+			nested = append(nested, c)
+		}
+		if len(nested) > 0 {
+			image.Components = &nested
+		}
+		components = append(components, image)
+		deps[product.BOMRef] = append(deps[product.BOMRef], img.Name)
+
+		if doc.Dependencies == nil {
+			continue
+		}
+		for _, d := range *doc.Dependencies {
+			ref, ok := refs[d.Ref]
+			if !ok || d.Dependencies == nil {
+				continue
+			}
+			for _, dep := range *d.Dependencies {
+				if to, ok := refs[dep]; ok {
+					deps[ref] = append(deps[ref], to)
+				}
+			}
+		}
+	}
+
+	bom.Components = &components
+
+	dependencies := make([]cdx.Dependency, 0, len(deps))
+	for _, ref := range slices.Sorted(maps.Keys(deps)) {
+		on := slices.Compact(slices.Sorted(slices.Values(deps[ref])))
+		dependencies = append(dependencies, cdx.Dependency{Ref: ref, Dependencies: &on})
+	}
+	bom.Dependencies = &dependencies
+
+	err := cdx.NewBOMEncoder(writer, cdx.BOMFileFormatJSON).SetPretty(true).Encode(bom)
+	if err != nil {
+		return fmt.Errorf("failed to encode sbom: %w", err)
+	}
+	return nil
+}
+
+// flattenComponents returns components and all the components nested
+// within them.
+func flattenComponents(components *[]cdx.Component) []cdx.Component {
+	if components == nil {
+		return nil
+	}
+
+	var flat []cdx.Component
+	for _, c := range *components {
+		flat = append(flat, c)
+		flat = append(flat, flattenComponents(c.Components)...)
+	}
+	return flat
+}
+
+var invalidSPDXIDChars = regexp.MustCompile(`[^A-Za-z0-9.-]`)
+
+// spdxID returns a valid SPDX element ID for kind and value.
+func spdxID(kind, value string) common.ElementID {
+	return common.ElementID(kind + "-" + invalidSPDXIDChars.ReplaceAllString(value, "-"))
+}
+
+func mergeSPDX(name, version string, images []Image, writer io.Writer) error {
+	productID := spdxID("Product", name)
+	doc := &spdx.Document{
+		SPDXVersion:       spdx.Version,
+		DataLicense:       spdx.DataLicense,
+		SPDXIdentifier:    "DOCUMENT",
+		DocumentName:      name + "-" + version,
+		DocumentNamespace: fmt.Sprintf("https://github.com/rancherlabs/slsactl/%s-%s-%s", name, version, uuid.NewString()),
+		CreationInfo: &spdx.CreationInfo{
+			Created:  time.Now().UTC().Format(time.RFC3339),
+			Creators: []common.Creator{{CreatorType: "Tool", Creator: "slsactl"}},
+		},
+		Packages: []*spdx.Package{{
+			PackageName:             name,
+			PackageSPDXIdentifier:   productID,
+			PackageVersion:          version,
+			PackageDownloadLocation: "NOASSERTION",
+			PrimaryPackagePurpose:   "APPLICATION",
+		}},
+	}
+
+	relationships := map[[3]string]bool{}
+	relate := func(a common.ElementID, rel string, b common.ElementID) {
+		key := [3]string{string(a), rel, string(b)}
+		if relationships[key] {
+			return
+		}
+		relationships[key] = true
+		doc.Relationships = append(doc.Relationships, &spdx.Relationship{
+			RefA:         common.MakeDocElementID("", string(a)),
+			RefB:         common.MakeDocElementID("", string(b)),
+			Relationship: rel,
+		})
+	}
+	relate("DOCUMENT", common.TypeRelationshipDescribe, productID)
+
+	ids := map[string]common.ElementID{}
+	licenses := map[string]bool{}
+	for i, img := range images {
+		in, err := spdxjson.Read(bytes.NewReader(img.SBOM))
+		if err != nil {
+			return fmt.Errorf("failed to decode SPDX SBOM of %s: %w", img.Name, err)
+		}
+
+		// Packages refer to the licenses not in the SPDX License List by
+		// their ID, which is derived from their text.
+		for _, l := range in.OtherLicenses {
+			if !licenses[l.LicenseIdentifier] {
+				licenses[l.LicenseIdentifier] = true
+				doc.OtherLicenses = append(doc.OtherLicenses, l)
+			}
+		}
+
+		described := map[common.ElementID]bool{}
+		for _, r := range in.Relationships {
+			if r.RefA.ElementRefID == "DOCUMENT" && r.Relationship == common.TypeRelationshipDescribe {
+				described[r.RefB.ElementRefID] = true
+			}
+		}
+
+		imageID := spdxID("Image", fmt.Sprintf("%d-%s", i+1, img.Name))
+		image := &spdx.Package{
+			PackageName:             img.Name,
+			PackageDownloadLocation: "NOASSERTION",
+			PrimaryPackagePurpose:   "CONTAINER",
+		}
+
+		local := map[common.ElementID]common.ElementID{}
+		var contained []common.ElementID
+		for _, p := range in.Packages {
+			orig := p.PackageSPDXIdentifier
+			if described[orig] && len(described) == 1 {
+				image = p
+				local[orig] = imageID
+				continue
+			}
+
+			key := packageKey(spdxPURL(p), p.PackageName, p.PackageVersion)
+			id, ok := ids[key]
+			if !ok {
+				id = spdxID("Package", fmt.Sprintf("%d", len(ids)+1))
+				ids[key] = id
+
+				p.PackageSPDXIdentifier = id
+				p.Files = nil
+				p.FilesAnalyzed = false
+				p.IsFilesAnalyzedTagPresent = false
+				p.PackageVerificationCode = nil
+				doc.Packages = append(doc.Packages, p)
+			}
+			local[orig] = id
+			contained = append(contained, id)
+		}
+
+		image.PackageSPDXIdentifier = imageID
+		image.PackageName = img.Name
+		image.Files = nil
+		image.FilesAnalyzed = false
+		image.PackageVerificationCode = nil
+		doc.Packages = append(doc.Packages, image)
+		relate(productID, common.TypeRelationshipContains, imageID)
+
+		for _, id := range contained {
+			relate(imageID, common.TypeRelationshipContains, id)
+		}
+		for _, r := range in.Relationships {
+			a, okA := local[r.RefA.ElementRefID]
+			b, okB := local[r.RefB.ElementRefID]
+			if okA && okB && r.RefA.DocumentRefID == "" && r.RefB.DocumentRefID == "" && a != b {
+				relate(a, r.Relationship, b)
+			}
+		}
+	}
+
+	err := spdxjson.Write(doc, writer, spdxjson.Indent("  "))
+	if err != nil {
+		return fmt.Errorf("failed to encode sbom: %w", err)
+	}
+	return nil
+}
+
+// spdxPURL returns the package URL of p, if any.
+func spdxPURL(p *spdx.Package) string {
+	for _, ref := range p.PackageExternalReferences {
+		if ref.RefType == common.TypePackageManagerPURL {
+			return ref.Locator
+		}
+	}
+	return ""
+}
@@ -0,0 +1,130 @@
+package sbom
+
+import (
+	"bytes"
+	"testing"
+
+	cdx "github.com/CycloneDX/cyclonedx-go"
+	spdxjson "github.com/spdx/tools-golang/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	spdxImageA = `{
+  "spdxVersion": "SPDX-2.3", "dataLicense": "CC0-1.0", "SPDXID": "SPDXRef-DOCUMENT",
+  "name": "a", "documentNamespace": "https://example.com/a",
+  "creationInfo": {"created": "2024-01-01T00:00:00Z", "creators": ["Tool: syft"]},
+  "packages": [
+    {"name": "registry.example.com/a", "SPDXID": "SPDXRef-DocumentRoot-Image-a", "downloadLocation": "NOASSERTION", "primaryPackagePurpose": "CONTAINER"},
+    {"name": "busybox", "SPDXID": "SPDXRef-Package-1", "versionInfo": "1.36", "downloadLocation": "NOASSERTION",
+     "externalRefs": [{"referenceCategory": "PACKAGE-MANAGER", "referenceType": "purl", "referenceLocator": "pkg:apk/busybox@1.36"}]},
+    {"name": "musl", "SPDXID": "SPDXRef-Package-2", "versionInfo": "1.2", "downloadLocation": "NOASSERTION"}
+  ],
+  "relationships": [
+    {"spdxElementId": "SPDXRef-DOCUMENT", "relationshipType": "DESCRIBES", "relatedSpdxElement": "SPDXRef-DocumentRoot-Image-a"},
+    {"spdxElementId": "SPDXRef-Package-1", "relationshipType": "DEPENDS_ON", "relatedSpdxElement": "SPDXRef-Package-2"}
+  ]
+}`
+	spdxImageB = `{
+  "spdxVersion": "SPDX-2.3", "dataLicense": "CC0-1.0", "SPDXID": "SPDXRef-DOCUMENT",
+  "name": "b", "documentNamespace": "https://example.com/b",
+  "creationInfo": {"created": "2024-01-01T00:00:00Z", "creators": ["Tool: syft"]},
+  "packages": [
+    {"name": "registry.example.com/b", "SPDXID": "SPDXRef-DocumentRoot-Image-b", "downloadLocation": "NOASSERTION", "primaryPackagePurpose": "CONTAINER"},
+    {"name": "busybox", "SPDXID": "SPDXRef-Package-1", "versionInfo": "1.36", "downloadLocation": "NOASSERTION",
+     "externalRefs": [{"referenceCategory": "PACKAGE-MANAGER", "referenceType": "purl", "referenceLocator": "pkg:apk/busybox@1.36"}]},
+    {"name": "zlib", "SPDXID": "SPDXRef-Package-2", "versionInfo": "1.3", "downloadLocation": "NOASSERTION"}
+  ],
+  "relationships": [
+    {"spdxElementId": "SPDXRef-DOCUMENT", "relationshipType": "DESCRIBES", "relatedSpdxElement": "SPDXRef-DocumentRoot-Image-b"}
+  ]
+}`
+)
+
+func TestMergeSPDX(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	err := Merge("rancher-prime", "v2.12.2", []Image{
+		{Name: "registry.example.com/a:v1", SBOM: []byte(spdxImageA)},
+		{Name: "registry.example.com/b:v1", SBOM: []byte(spdxImageB)},
+	}, FormatSPDXJSON, &buf)
+	require.NoError(t, err)
+
+	doc, err := spdxjson.Read(&buf)
+	require.NoError(t, err)
+
+	ids := map[string]string{}
+	for _, p := range doc.Packages {
+		ids[p.PackageName+"@"+p.PackageVersion] = string(p.PackageSPDXIdentifier)
+	}
+	assert.Equal(t, map[string]string{
+		"rancher-prime@v2.12.2":      "Product-rancher-prime",
+		"registry.example.com/a:v1@": "Image-1-registry.example.com-a-v1",
+		"registry.example.com/b:v1@": "Image-2-registry.example.com-b-v1",
+		"busybox@1.36":               "Package-1",
+		"musl@1.2":                   "Package-2",
+		"zlib@1.3":                   "Package-3",
+	}, ids)
+
+	var rels []string
+	for _, r := range doc.Relationships {
+		rels = append(rels, string(r.RefA.ElementRefID)+" "+r.Relationship+" "+string(r.RefB.ElementRefID))
+	}
+	assert.ElementsMatch(t, []string{
+		"DOCUMENT DESCRIBES Product-rancher-prime",
+		"Product-rancher-prime CONTAINS Image-1-registry.example.com-a-v1",
+		"Image-1-registry.example.com-a-v1 CONTAINS Package-1",
+		"Image-1-registry.example.com-a-v1 CONTAINS Package-2",
+		"Package-1 DEPENDS_ON Package-2",
+		"Product-rancher-prime CONTAINS Image-2-registry.example.com-b-v1",
+		"Image-2-registry.example.com-b-v1 CONTAINS Package-1",
+		"Image-2-registry.example.com-b-v1 CONTAINS Package-3",
+	}, rels)
+}
+
+func TestMergeCycloneDX(t *testing.T) {
+	t.Parallel()
+
+	// The SPDX documents are converted to CycloneDX first.
+	var buf bytes.Buffer
+	err := Merge("rancher-prime", "v2.12.2", []Image{
+		{Name: "registry.example.com/a:v1", SBOM: []byte(spdxImageA)},
+		{Name: "registry.example.com/b:v1", SBOM: []byte(spdxImageB)},
+	}, FormatCycloneDXJSON, &buf)
+	require.NoError(t, err)
+
+	var bom cdx.BOM
+	require.NoError(t, cdx.NewBOMDecoder(&buf, cdx.BOMFileFormatJSON).Decode(&bom))
+	require.NotNil(t, bom.Metadata.Component)
+	assert.Equal(t, "rancher-prime", bom.Metadata.Component.Name)
+	require.NotNil(t, bom.Components)
+	require.Len(t, *bom.Components, 2)
+
+	nested := map[string][]string{}
+	for _, image := range *bom.Components {
+		assert.Equal(t, cdx.ComponentTypeContainer, image.Type)
+		for _, c := range flattenComponents(image.Components) {
+			nested[image.BOMRef] = append(nested[image.BOMRef], c.Name)
+		}
+	}
+	assert.ElementsMatch(t, []string{"busybox", "musl"}, nested["registry.example.com/a:v1"])
+	assert.Equal(t, []string{"zlib"}, nested["registry.example.com/b:v1"])
+
+	deps := map[string][]string{}
+	for _, d := range *bom.Dependencies {
+		if d.Dependencies != nil {
+			deps[d.Ref] = *d.Dependencies
+		}
+	}
+	assert.Equal(t, []string{"registry.example.com/a:v1", "registry.example.com/b:v1"}, deps["rancher-prime@v2.12.2"])
+	assert.Contains(t, deps["registry.example.com/b:v1"], "pkg:apk/busybox@1.36")
+}
+
+func TestMergeUnsupportedFormat(t *testing.T) {
+	t.Parallel()
+
+	err := Merge("rancher-prime", "v2.12.2", nil, FormatSPDXTagValue, &bytes.Buffer{})
+	assert.ErrorIs(t, err, ErrUnsupportedFormat)
+}
@@ -3,109 +3,274 @@ package sbom
 import (
 	"bytes"
 	"context"
-	"encoding/json"
+	"crypto"
+	"errors"
 	"fmt"
 	"io"
-	"os"
-	"path/filepath"
+	"path"
+	"strings"
 
+	"github.com/anchore/stereoscope/pkg/image"
 	"github.com/anchore/syft/syft"
 	"github.com/anchore/syft/syft/cataloging/pkgcataloging"
+	"github.com/anchore/syft/syft/file"
 	"github.com/anchore/syft/syft/format"
-	"github.com/anchore/syft/syft/format/cyclonedxjson"
-	"github.com/anchore/syft/syft/format/spdxjson"
 	"github.com/anchore/syft/syft/sbom"
+	"github.com/anchore/syft/syft/source"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/rancherlabs/slsactl/pkg/attestation"
+
+	// Registers the sqlite driver Syft requires to catalog RPM databases.
+	_ "modernc.org/sqlite"
 )
 
-type BuildKitSBOM struct {
-	LinuxAmd64   *archSBOM        `json:"linux/amd64,omitempty"`
-	LinuxArm64   *archSBOM        `json:"linux/arm64,omitempty"`
-	WindowsAmd64 *archSBOM        `json:"windows/amd64,omitempty"`
-	SPDX         *json.RawMessage `json:"SPDX,omitempty"`
-}
+var createSBOM = defaultCreateSBOM
 
-type archSBOM struct {
-	SPDX json.RawMessage `json:"SPDX,omitempty"`
+// Options configure the generation of SBOMs. The zero value catalogs the
+// installed packages of the squashed filesystem of the default platform,
+// along with the SHA-256 digests of the files they own.
+type Options struct {
+	// Platform selects the image of multi-platform images, such as
+	// linux/arm64 or windows/amd64:10.0.20348.
+	Platform string
+	// Catalogers adds to, removes from or selects the catalogers in use, by
+	// name or tag, as in +sbom-cataloger, -rpm or java.
+	Catalogers []string
+	// Scope is either squashed or all-layers.
+	Scope string
+	// Files selects the files whose metadata is included: none,
+	// owned-by-package or all.
+	Files string
+	// FileDigests are the digest algorithms of those files, such as sha256.
+	FileDigests []string
+	// CacheDir caches the layers of registry images across generations, so
+	// that images sharing base layers download them once. Disabled when
+	// empty.
+	CacheDir string
 }
 
-var createSBOM = defaultCreateSBOM
+// ErrInvalidOption indicates an invalid value in Options.
+var ErrInvalidOption = errors.New("invalid SBOM option")
+
+// Cataloger tags selected by default for images and for filesystems, which
+// also include declared dependencies, such as those of lock files.
+var (
+	imageCatalogers      = []string{pkgcataloging.InstalledTag, pkgcataloging.PackageTag}
+	filesystemCatalogers = []string{pkgcataloging.DirectoryTag, pkgcataloging.PackageTag}
+)
+
+func defaultCreateSBOM(img string, opts Options) (*sbom.SBOM, error) {
+	defaults := imageCatalogers
+	if scheme, _, ok := LocalSource(img); ok && scheme != SchemeOCIArchive {
+		defaults = filesystemCatalogers
+	}
 
-func defaultCreateSBOM(img string) (*sbom.SBOM, error) {
-	src, err := syft.GetSource(context.Background(), img, nil)
+	cfg, err := createConfig(opts, defaults)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get source: %w", err)
+		return nil, err
 	}
 
+	input, platform, err := platformSource(img, opts.Platform)
+	if err != nil {
+		return nil, err
+	}
+
+	src, cleanup, err := getSource(context.Background(), input, platform, opts.CacheDir)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	return syft.CreateSBOM(context.Background(), src, cfg)
+}
+
+// createConfig returns the Syft configuration for opts, selecting the
+// catalogers tagged with defaults unless opts.Catalogers selects others.
+func createConfig(opts Options, defaults []string) (*syft.CreateSBOMConfig, error) {
 	cfg := syft.DefaultCreateSBOMConfig().
 		WithCatalogerSelection(
 			pkgcataloging.NewSelectionRequest().
-				WithDefaults(
-					pkgcataloging.InstalledTag,
-					pkgcataloging.PackageTag,
-				),
+				WithDefaults(defaults...).
+				WithExpression(opts.Catalogers...),
 		)
 
-	return syft.CreateSBOM(context.Background(), src, cfg)
+	if opts.Scope != "" {
+		scope := source.ParseScope(opts.Scope)
+		if scope != source.SquashedScope && scope != source.AllLayersScope {
+			return nil, fmt.Errorf("%w: scope %q: supported values are %s or %s",
+				ErrInvalidOption, opts.Scope, source.SquashedScope, source.AllLayersScope)
+		}
+		cfg = cfg.WithSearchConfig(cfg.Search.WithScope(scope))
+	}
+
+	files := cfg.Files
+	switch opts.Files {
+	case "":
+	case string(file.NoFilesSelection):
+		return cfg.WithoutFiles(), nil
+	case string(file.FilesOwnedByPackageSelection), string(file.AllFilesSelection):
+		files.Selection = file.Selection(opts.Files)
+	default:
+		return nil, fmt.Errorf("%w: files %q: supported values are %s, %s or %s", ErrInvalidOption, opts.Files,
+			file.NoFilesSelection, file.FilesOwnedByPackageSelection, file.AllFilesSelection)
+	}
+
+	if len(opts.FileDigests) > 0 {
+		files.Hashers = nil
+		for _, d := range opts.FileDigests {
+			h, ok := digestAlgorithms[strings.ToLower(strings.ReplaceAll(d, "-", ""))]
+			if !ok || !h.Available() {
+				return nil, fmt.Errorf("%w: unsupported file digest %q", ErrInvalidOption, d)
+			}
+			files.Hashers = append(files.Hashers, h)
+		}
+	}
+
+	return cfg.WithFilesConfig(files), nil
 }
 
-func Generate(img, outformat string, writer io.Writer) error {
-	defer cleanup()
+var digestAlgorithms = map[string]crypto.Hash{
+	"md5":    crypto.MD5,
+	"sha1":   crypto.SHA1,
+	"sha224": crypto.SHA224,
+	"sha256": crypto.SHA256,
+	"sha384": crypto.SHA384,
+	"sha512": crypto.SHA512,
+}
 
-	s, err := createSBOM(img)
+// platformSource returns the Syft source and platform for img. Syft cannot
+// select images by OS version, as needed for Windows images, so those are
+// resolved to the digest of the matching image instead.
+func platformSource(img, platform string) (string, *image.Platform, error) {
+	input := sourceInput(img)
+	scheme, _, fs := LocalSource(img)
+	if platform == "" || (fs && scheme != SchemeOCIArchive) {
+		return input, nil, nil
+	}
+
+	spec, err := v1.ParsePlatform(strings.ToLower(platform))
 	if err != nil {
-		return fmt.Errorf("failed to create SBOM: %w", err)
+		return "", nil, fmt.Errorf("%w: platform %q: %w", ErrInvalidOption, platform, err)
 	}
 
-	var enc sbom.FormatEncoder
-	switch outformat {
-	case "cyclonedxjson":
-		enc, _ = cyclonedxjson.NewFormatEncoderWithConfig(cyclonedxjson.DefaultEncoderConfig())
-	case "spdxjson":
-		enc, _ = spdxjson.NewFormatEncoderWithConfig(spdxjson.DefaultEncoderConfig())
-	default:
-		return fmt.Errorf("invalid format %s: failed to create encoder", outformat)
+	p, err := image.NewPlatform(path.Join(spec.OS, spec.Architecture, spec.Variant))
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: platform %q: %w", ErrInvalidOption, platform, err)
 	}
 
-	data, err := format.Encode(*s, enc)
+	if _, local := attestation.ParseLocalRef(img); local || fs || spec.OSVersion == "" {
+		return input, p, nil
+	}
+
+	resolved, err := resolvePlatform(img, *spec)
 	if err != nil {
-		return fmt.Errorf("failed to encode sbom: %w", err)
+		return "", nil, err
 	}
+	return resolved, p, nil
+}
 
-	_, err = io.Copy(writer, bytes.NewReader(data))
+// resolvePlatform returns the reference by digest of the image of img for
+// platform, or img itself when it is not a multi-platform image.
+func resolvePlatform(img string, platform v1.Platform, opts ...remote.Option) (string, error) {
+	ref, err := name.ParseReference(img)
 	if err != nil {
-		return fmt.Errorf("failed to copy sbom to writer: %w", err)
+		return "", fmt.Errorf("failed to parse image reference: %w", err)
 	}
 
-	return nil
+	opts = append([]remote.Option{remote.WithAuthFromKeychain(authn.DefaultKeychain)}, opts...)
+	desc, err := remote.Get(ref, opts...)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch image descriptor: %w", err)
+	}
+	if !desc.MediaType.IsIndex() {
+		return img, nil
+	}
+
+	idx, err := desc.ImageIndex()
+	if err != nil {
+		return "", fmt.Errorf("failed to get image index: %w", err)
+	}
+	manifest, err := idx.IndexManifest()
+	if err != nil {
+		return "", fmt.Errorf("failed to get index manifest: %w", err)
+	}
+
+	for _, m := range manifest.Manifests {
+		if m.Platform != nil && attestation.PlatformSatisfies(*m.Platform, platform) {
+			return ref.Context().Digest(m.Digest.String()).String(), nil
+		}
+	}
+	return "", fmt.Errorf("platform not supported: %q", platform.String())
+}
+
+// sourceInput returns the Syft source for img, mapping local references
+// to the equivalent Syft schemes.
+func sourceInput(img string) string {
+	l, ok := attestation.ParseLocalRef(img)
+	if !ok {
+		return img
+	}
+
+	switch l.Transport {
+	case attestation.TransportOCI:
+		return "oci-dir:" + l.Path
+	case attestation.TransportDockerArchive:
+		return "docker-archive:" + l.Path
+	case attestation.TransportDockerDaemon:
+		return "docker:" + l.Reference
+	}
+	return img
 }
 
-// During the sbom generation process, Syft uses temporary dirs in the
-// format /tmp/stereoscope-* that contains both the compressed and inflated
-// versions of the image. This ensures that both are removed.
-func cleanup() error {
-	m, err := filepath.Glob("/tmp/stereoscope-*")
+// Generate creates the SBOM of img, an image or a LocalSource, as
+// configured by opts and writes it to writer in outformat, which is one of
+// Formats, optionally followed by @version.
+func Generate(img, outformat string, opts Options, writer io.Writer) error {
+	enc, err := encoder(outformat, false)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to create encoder: %w", err)
 	}
 
-	for _, d := range m {
-		_ = os.RemoveAll(d)
+	s, err := createSBOM(img, opts)
+	if err != nil {
+		return fmt.Errorf("failed to create SBOM: %w", err)
+	}
+
+	data, err := format.Encode(*s, enc)
+	if err != nil {
+		return fmt.Errorf("failed to encode sbom: %w", err)
+	}
+
+	_, err = io.Copy(writer, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to copy sbom to writer: %w", err)
 	}
+
 	return nil
 }
 
 func ConvertToCyclonedxJson(reader io.Reader, writer io.Writer) error {
-	s, _, _, err := format.Decode(reader)
+	return Convert(reader, writer, FormatCycloneDXJSON)
+}
+
+// Convert converts an SBOM in any format known to Syft to outformat, which
+// is one of Formats, optionally followed by @version.
+func Convert(reader io.Reader, writer io.Writer, outformat string) error {
+	enc, err := encoder(outformat, true)
 	if err != nil {
-		return fmt.Errorf("failed to decode SPDX JSON SBOM: %w", err)
+		return fmt.Errorf("failed to create encoder: %w", err)
 	}
 
-	cfg := cyclonedxjson.DefaultEncoderConfig()
-	cfg.Pretty = true
+	return convert(reader, writer, enc)
+}
 
-	enc, err := cyclonedxjson.NewFormatEncoderWithConfig(cfg)
+func convert(reader io.Reader, writer io.Writer, enc sbom.FormatEncoder) error {
+	s, _, _, err := format.Decode(reader)
 	if err != nil {
-		return fmt.Errorf("failed to create cyclonedxjson encoder: %w", err)
+		return fmt.Errorf("failed to decode SBOM: %w", err)
 	}
 
 	data, err := format.Encode(*s, enc)
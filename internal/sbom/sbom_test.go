@@ -2,11 +2,31 @@ package sbom
 
 import (
 	"bytes"
+	"crypto"
 	"errors"
+	"io"
+	"log"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/anchore/syft/syft/file"
 	"github.com/anchore/syft/syft/sbom"
+	"github.com/anchore/syft/syft/source"
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/rancherlabs/slsactl/pkg/attestation"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	_ "embed"
 )
@@ -21,14 +41,14 @@ func TestGenerate(t *testing.T) {
 		name       string
 		img        string
 		outformat  string
-		createSBOM func(img string) (*sbom.SBOM, error)
+		createSBOM func(img string, opts Options) (*sbom.SBOM, error)
 		wantErr    bool
 	}{
 		{
 			name:      "successful generation with cyclonedxjson",
 			img:       "test-image",
 			outformat: "cyclonedxjson",
-			createSBOM: func(_ string) (*sbom.SBOM, error) {
+			createSBOM: func(_ string, _ Options) (*sbom.SBOM, error) {
 				return &sbom.SBOM{}, nil
 			},
 			wantErr: false,
@@ -37,16 +57,70 @@ func TestGenerate(t *testing.T) {
 			name:      "successful generation with spdxjson",
 			img:       "test-image",
 			outformat: "spdxjson",
-			createSBOM: func(_ string) (*sbom.SBOM, error) {
+			createSBOM: func(_ string, _ Options) (*sbom.SBOM, error) {
 				return &sbom.SBOM{}, nil
 			},
 			wantErr: false,
 		},
+		{
+			name:      "successful generation with cyclonedxxml",
+			img:       "test-image",
+			outformat: "cyclonedxxml",
+			createSBOM: func(_ string, _ Options) (*sbom.SBOM, error) {
+				return &sbom.SBOM{}, nil
+			},
+			wantErr: false,
+		},
+		{
+			name:      "successful generation with spdxtagvalue",
+			img:       "test-image",
+			outformat: "spdxtagvalue",
+			createSBOM: func(_ string, _ Options) (*sbom.SBOM, error) {
+				return &sbom.SBOM{}, nil
+			},
+			wantErr: false,
+		},
+		{
+			name:      "successful generation with syftjson",
+			img:       "test-image",
+			outformat: "syftjson",
+			createSBOM: func(_ string, _ Options) (*sbom.SBOM, error) {
+				return &sbom.SBOM{}, nil
+			},
+			wantErr: false,
+		},
+		{
+			name:      "successful generation with spdx3json",
+			img:       "test-image",
+			outformat: "spdx3json",
+			createSBOM: func(_ string, _ Options) (*sbom.SBOM, error) {
+				return &sbom.SBOM{}, nil
+			},
+			wantErr: false,
+		},
+		{
+			name:      "successful generation with versioned cyclonedxjson",
+			img:       "test-image",
+			outformat: "cyclonedxjson@1.5",
+			createSBOM: func(_ string, _ Options) (*sbom.SBOM, error) {
+				return &sbom.SBOM{}, nil
+			},
+			wantErr: false,
+		},
+		{
+			name:      "invalid version",
+			img:       "test-image",
+			outformat: "cyclonedxjson@0.1",
+			createSBOM: func(_ string, _ Options) (*sbom.SBOM, error) {
+				return &sbom.SBOM{}, nil
+			},
+			wantErr: true,
+		},
 		{
 			name:      "invalid format",
 			img:       "test-image",
 			outformat: "invalidformat",
-			createSBOM: func(_ string) (*sbom.SBOM, error) {
+			createSBOM: func(_ string, _ Options) (*sbom.SBOM, error) {
 				return &sbom.SBOM{}, nil
 			},
 			wantErr: true,
@@ -55,7 +129,7 @@ func TestGenerate(t *testing.T) {
 			name:      "failed to get source",
 			img:       "test-image",
 			outformat: "cyclonedxjson",
-			createSBOM: func(_ string) (*sbom.SBOM, error) {
+			createSBOM: func(_ string, _ Options) (*sbom.SBOM, error) {
 				return nil, errors.New("failed to get source")
 			},
 			wantErr: true,
@@ -64,7 +138,7 @@ func TestGenerate(t *testing.T) {
 			name:      "failed to create SBOM",
 			img:       "test-image",
 			outformat: "cyclonedxjson",
-			createSBOM: func(_ string) (*sbom.SBOM, error) {
+			createSBOM: func(_ string, _ Options) (*sbom.SBOM, error) {
 				return nil, errors.New("failed to generate SBOM")
 			},
 			wantErr: true,
@@ -76,7 +150,7 @@ func TestGenerate(t *testing.T) {
 			createSBOM = tt.createSBOM
 
 			var buf bytes.Buffer
-			err := Generate(tt.img, tt.outformat, &buf)
+			err := Generate(tt.img, tt.outformat, Options{}, &buf)
 
 			if tt.wantErr {
 				assert.Error(t, err)
@@ -127,3 +201,220 @@ func TestConvertToCyclonedxJson(t *testing.T) {
 		})
 	}
 }
+
+func TestCreateSBOMRPMDatabase(t *testing.T) {
+	t.Parallel()
+
+	// testdata/rpmdb.sqlite holds the publicsuffix-list-dafsa package, in
+	// the SQLite RPM database format of SUSE BCI and Fedora based images.
+	db, err := os.ReadFile("testdata/rpmdb.sqlite")
+	require.NoError(t, err)
+
+	layer, err := crane.Layer(map[string][]byte{"var/lib/rpm/rpmdb.sqlite": db})
+	require.NoError(t, err)
+	img, err := mutate.AppendLayers(empty.Image, layer)
+	require.NoError(t, err)
+
+	dir := filepath.Join(t.TempDir(), "layout")
+	p, err := layout.Write(dir, empty.Index)
+	require.NoError(t, err)
+	require.NoError(t, p.AppendImage(img))
+
+	s, err := defaultCreateSBOM("oci:"+dir, Options{})
+	require.NoError(t, err)
+
+	var names []string
+	for p := range s.Artifacts.Packages.Enumerate() {
+		names = append(names, p.Name)
+	}
+	assert.Contains(t, names, "publicsuffix-list-dafsa")
+}
+
+func TestSourceInput(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		"rancher/foo:v1":                        "rancher/foo:v1",
+		"oci:/tmp/layout:rancher/foo:v1":        "oci-dir:/tmp/layout",
+		"docker-archive:foo.tar:rancher/foo:v1": "docker-archive:foo.tar",
+		"docker-daemon:rancher/foo:v1":          "docker:rancher/foo:v1",
+	}
+
+	for img, want := range tests {
+		assert.Equal(t, want, sourceInput(img), img)
+	}
+}
+
+func TestConvert(t *testing.T) {
+	t.Parallel()
+
+	var cdx bytes.Buffer
+	err := ConvertToCyclonedxJson(bytes.NewReader([]byte(cisoperatorAMD64Spdx)), &cdx)
+	require.NoError(t, err)
+
+	tests := []struct {
+		outformat string
+		input     string
+		want      string
+		wantErr   error
+	}{
+		{outformat: "spdxjson", input: cdx.String(), want: `"spdxVersion": "SPDX-2.3"`},
+		{outformat: "spdx-json@2.2", input: cdx.String(), want: `"spdxVersion": "SPDX-2.2"`},
+		{outformat: "spdx3json", input: cisoperatorAMD64Spdx, want: `"@context"`},
+		{outformat: "spdxtagvalue", input: cisoperatorAMD64Spdx, want: "SPDXVersion: SPDX-2.3"},
+		{outformat: "cyclonedxxml", input: cisoperatorAMD64Spdx, want: "<bom xmlns="},
+		{outformat: "cyclonedxjson@1.5", input: cisoperatorAMD64Spdx, want: `"specVersion": "1.5"`},
+		{outformat: "syftjson", input: cisoperatorAMD64Spdx, want: `"artifacts"`},
+		{outformat: "cyclonedxjson@0.1", input: cisoperatorAMD64Spdx, wantErr: ErrUnsupportedFormat},
+		{outformat: "table", input: cisoperatorAMD64Spdx, wantErr: ErrUnsupportedFormat},
+		{outformat: "spdxjson", input: `<not valid>`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.outformat, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			err := Convert(strings.NewReader(tc.input), &buf, tc.outformat)
+			switch {
+			case tc.wantErr != nil:
+				assert.ErrorIs(t, err, tc.wantErr)
+			case tc.want == "":
+				assert.Error(t, err)
+			default:
+				require.NoError(t, err)
+				assert.Contains(t, buf.String(), tc.want)
+			}
+		})
+	}
+}
+
+func TestStandard(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		"spdxjson":         attestation.FormatSPDX,
+		"spdx-tag-value":   attestation.FormatSPDX,
+		"spdx3json":        attestation.FormatSPDX,
+		"cyclonedxxml@1.5": attestation.FormatCycloneDX,
+		"CycloneDX-JSON":   attestation.FormatCycloneDX,
+		"syftjson":         "",
+	}
+
+	for outformat, want := range tests {
+		assert.Equal(t, want, Standard(outformat), outformat)
+	}
+}
+
+func TestCreateConfig(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		opts       Options
+		wantScope  source.Scope
+		wantFiles  file.Selection
+		wantHashes []crypto.Hash
+		wantErr    error
+	}{
+		{
+			name:       "defaults",
+			wantScope:  source.SquashedScope,
+			wantFiles:  file.FilesOwnedByPackageSelection,
+			wantHashes: []crypto.Hash{crypto.SHA256},
+		},
+		{
+			name:       "all layers and files",
+			opts:       Options{Scope: "all-layers", Files: "all", FileDigests: []string{"sha1", "SHA-256"}},
+			wantScope:  source.AllLayersScope,
+			wantFiles:  file.AllFilesSelection,
+			wantHashes: []crypto.Hash{crypto.SHA1, crypto.SHA256},
+		},
+		{
+			name:      "no files",
+			opts:      Options{Files: "none"},
+			wantScope: source.SquashedScope,
+			wantFiles: file.NoFilesSelection,
+		},
+		{name: "invalid scope", opts: Options{Scope: "deep-squashed"}, wantErr: ErrInvalidOption},
+		{name: "invalid files", opts: Options{Files: "some"}, wantErr: ErrInvalidOption},
+		{name: "invalid digest", opts: Options{FileDigests: []string{"crc32"}}, wantErr: ErrInvalidOption},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg, err := createConfig(tc.opts, imageCatalogers)
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantScope, cfg.Search.Scope)
+			assert.Equal(t, tc.wantFiles, cfg.Files.Selection)
+			assert.Equal(t, tc.wantHashes, cfg.Files.Hashers)
+		})
+	}
+}
+
+func TestResolvePlatform(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", 0))))
+	t.Cleanup(srv.Close)
+
+	platforms := []v1.Platform{
+		{OS: "linux", Architecture: "amd64"},
+		{OS: "windows", Architecture: "amd64", OSVersion: "10.0.17763.7009"},
+		{OS: "windows", Architecture: "amd64", OSVersion: "10.0.20348.3328"},
+	}
+
+	var idx v1.ImageIndex = empty.Index
+	digests := make([]v1.Hash, len(platforms))
+	for i, p := range platforms {
+		img, err := random.Image(256, 1)
+		require.NoError(t, err)
+		digests[i], err = img.Digest()
+		require.NoError(t, err)
+
+		idx = mutate.AppendManifests(idx, mutate.IndexAddendum{
+			Add:        img,
+			Descriptor: v1.Descriptor{Platform: &p},
+		})
+	}
+
+	ref := strings.TrimPrefix(srv.URL, "http://") + "/rancher/foo:v1"
+	tag, err := name.NewTag(ref)
+	require.NoError(t, err)
+	require.NoError(t, remote.WriteIndex(tag, idx))
+
+	tests := []struct {
+		platform string
+		want     v1.Hash
+		wantErr  bool
+	}{
+		{platform: "windows/amd64:10.0.20348", want: digests[2]},
+		{platform: "windows/amd64:10.0.17763.7009", want: digests[1]},
+		{platform: "windows/amd64:10.0.26100", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.platform, func(t *testing.T) {
+			t.Parallel()
+
+			spec, err := v1.ParsePlatform(tc.platform)
+			require.NoError(t, err)
+
+			got, err := resolvePlatform(ref, *spec)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tag.Context().Digest(tc.want.String()).String(), got)
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package sbom
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/anchore/packageurl-go"
+	"github.com/anchore/syft/syft/format"
+	"github.com/anchore/syft/syft/pkg"
+	"github.com/rancherlabs/slsactl/internal/version"
+)
+
+// Query selects the packages returned by Search.
+type Query struct {
+	// Name is the package name. Distribution packages also match the
+	// name of their source package, so openssl matches libssl3.
+	Name string
+	// PURL, when set, selects packages by type, namespace and name.
+	PURL *packageurl.PackageURL
+	// Version selects the package versions.
+	Version version.Constraint
+}
+
+// NewQuery returns the query for pkg, a package name or URL, and the
+// version constraint, such as <3.0.14. The version of a package URL is
+// used when constraint is empty.
+func NewQuery(pkg, constraint string) (*Query, error) {
+	c, err := version.ParseConstraint(constraint)
+	if err != nil {
+		return nil, err
+	}
+
+	q := &Query{Name: pkg, Version: c}
+	if strings.HasPrefix(pkg, "pkg:") {
+		p, err := packageurl.FromString(pkg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid package URL %q: %w", pkg, err)
+		}
+
+		q.Name, q.PURL = p.Name, &p
+		if p.Version != "" && constraint == "" {
+			q.Version, _ = version.ParseConstraint(p.Version)
+		}
+	}
+	return q, nil
+}
+
+// Found is a package matching a query.
+type Found struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Type    string `json:"type"`
+	PURL    string `json:"purl,omitempty"`
+	// Locations holds the paths of the files the package was found in.
+	Locations []string `json:"locations,omitempty"`
+}
+
+// Search returns the packages of the SBOM doc, in any format known to Syft,
+// matching q.
+func Search(doc []byte, q *Query) ([]Found, error) {
+	s, _, _, err := format.Decode(bytes.NewReader(doc))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode SBOM: %w", err)
+	}
+	if s == nil {
+		return nil, fmt.Errorf("failed to decode SBOM: unknown format")
+	}
+
+	var found []Found
+	for _, p := range s.Artifacts.Packages.Sorted() {
+		if !q.matches(p.Name, p.PURL) || !q.Version.CheckWith(p.Version, comparer(p)) {
+			continue
+		}
+
+		f := Found{Name: p.Name, Version: p.Version, Type: string(p.Type), PURL: p.PURL}
+		for _, l := range p.Locations.ToSlice() {
+			if l.RealPath != "" && !slices.Contains(f.Locations, l.RealPath) {
+				f.Locations = append(f.Locations, l.RealPath)
+			}
+		}
+		found = append(found, f)
+	}
+	return found, nil
+}
+
+// comparer returns how the versions of p are compared, from the type of its
+// package URL, so that distribution packages such as 3.0.14-r0 are not
+// compared as Semantic Versions.
+func comparer(p pkg.Package) func(a, b string) int {
+	purlType := p.Type.PackageURLType()
+	if purl, err := packageurl.FromString(p.PURL); err == nil {
+		purlType = purl.Type
+	}
+	return version.ComparerFor(purlType)
+}
+
+func (q *Query) matches(name, purl string) bool {
+	p, err := packageurl.FromString(purl)
+	if q.PURL != nil {
+		return err == nil && p.Type == q.PURL.Type &&
+			strings.EqualFold(p.Namespace, q.PURL.Namespace) &&
+			(strings.EqualFold(p.Name, q.PURL.Name) || strings.EqualFold(upstreamName(p), q.PURL.Name))
+	}
+
+	if strings.EqualFold(name, q.Name) {
+		return true
+	}
+	return err == nil && strings.EqualFold(upstreamName(p), q.Name)
+}
+
+// upstreamName returns the name of the source package of a distribution
+// package, as in the upstream qualifier openssl@3.0.14-1.
+func upstreamName(p packageurl.PackageURL) string {
+	upstream := p.Qualifiers.Map()["upstream"]
+	if p.Type == packageurl.TypeRPM {
+		// Source RPM files, as in openssl-3.0.7-24.el9.src.rpm.
+		name := strings.TrimSuffix(upstream, ".src.rpm")
+		if name == upstream {
+			return upstream
+		}
+		for range 2 {
+			if i := strings.LastIndex(name, "-"); i > 0 {
+				name = name[:i]
+			}
+		}
+		return name
+	}
+
+	name, _, _ := strings.Cut(upstream, "@")
+	return name
+}
@@ -0,0 +1,63 @@
+package sbom
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearch(t *testing.T) {
+	t.Parallel()
+
+	doc := cdxDoc(
+		cdxComponent("libssl3", "3.0.13-r0", "pkg:apk/alpine/libssl3@3.0.13-r0?upstream=openssl", ""),
+		cdxComponent("openssl", "3.0.15-r0", "pkg:apk/alpine/openssl@3.0.15-r0", ""),
+		cdxComponent("openssl-libs", "3.0.7-24.el9", "pkg:rpm/redhat/openssl-libs@3.0.7-24.el9?upstream=openssl-3.0.7-24.el9.src.rpm", ""),
+		cdxComponent("log4j-core", "2.14.1", "pkg:maven/org.apache.logging.log4j/log4j-core@2.14.1", "Apache-2.0"),
+		cdxComponent("xz-utils", "5.6.0-0.2", "pkg:deb/debian/xz-utils@5.6.0-0.2?upstream=xz-utils%405.6.0", ""),
+		// Fixed in the SUSE BCI and Alpine releases of 3.0.14.
+		cdxComponent("libopenssl3", "3.0.14-150600.1.1", "pkg:rpm/suse/libopenssl3@3.0.14-150600.1.1?upstream=openssl-3.0.14-150600.1.1.src.rpm&distro=sles-15.6", ""),
+		cdxComponent("libcrypto3", "3.0.14-r0", "pkg:apk/alpine/libcrypto3@3.0.14-r0?upstream=openssl&distro=alpine-3.17.9", ""),
+		cdxComponent("libssl3", "3.0.14_rc1-r0", "pkg:apk/alpine/libssl3@3.0.14_rc1-r0?upstream=openssl&distro=alpine-3.17.9", ""),
+	)
+
+	tests := []struct {
+		name       string
+		pkg        string
+		constraint string
+		want       []string
+		wantErr    bool
+	}{
+		{name: "by name and upstream", pkg: "openssl", want: []string{"libssl3", "openssl", "openssl-libs", "libopenssl3", "libcrypto3", "libssl3"}},
+		{name: "by name and version", pkg: "openssl", constraint: "<3.0.14", want: []string{"libssl3", "openssl-libs", "libssl3"}},
+		{name: "by name and distro version", pkg: "openssl", constraint: "=3.0.14", want: []string{"libopenssl3", "libcrypto3"}},
+		{name: "by purl", pkg: "pkg:maven/org.apache.logging.log4j/log4j-core", constraint: ">=2.0.0, <2.17.1", want: []string{"log4j-core"}},
+		{name: "by purl version", pkg: "pkg:deb/debian/xz-utils@5.6.0-0.2", want: []string{"xz-utils"}},
+		{name: "purl namespace differs", pkg: "pkg:maven/other/log4j-core"},
+		{name: "no match", pkg: "xz", constraint: "5.6.1"},
+		{name: "invalid constraint", pkg: "xz", constraint: "<", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			q, err := NewQuery(tc.pkg, tc.constraint)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			found, err := Search(doc, q)
+			require.NoError(t, err)
+
+			var names []string
+			for _, f := range found {
+				names = append(names, f.Name)
+			}
+			assert.ElementsMatch(t, tc.want, names)
+		})
+	}
+}
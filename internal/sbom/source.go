@@ -0,0 +1,211 @@
+package sbom
+
+import (
+	"context"
+	"crypto"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/anchore/stereoscope/pkg/file"
+	"github.com/anchore/stereoscope/pkg/image"
+	"github.com/anchore/stereoscope/pkg/image/docker"
+	"github.com/anchore/stereoscope/pkg/image/oci"
+	"github.com/anchore/syft/syft/source"
+	"github.com/anchore/syft/syft/source/directorysource"
+	"github.com/anchore/syft/syft/source/filesource"
+	"github.com/anchore/syft/syft/source/stereoscopesource"
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/cache"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// Schemes of the sources SBOMs are generated from besides images, followed
+// by their path, as in dir:<path>.
+const (
+	// SchemeDir is a directory, such as an extracted root filesystem.
+	SchemeDir = "dir"
+	// SchemeFile is a file. Archives, such as release tarballs, are
+	// extracted to a temporary directory and their content cataloged.
+	SchemeFile = "file"
+	// SchemeOCIArchive is a tarball of an OCI image layout.
+	SchemeOCIArchive = "oci-archive"
+)
+
+// LocalSource returns the scheme and path of input when it is a directory,
+// file or OCI archive source, such as dir:<path>. It returns false for any
+// other input.
+func LocalSource(input string) (scheme, path string, ok bool) {
+	scheme, path, ok = strings.Cut(input, ":")
+	if !ok || path == "" {
+		return "", "", false
+	}
+
+	switch scheme {
+	case SchemeDir, SchemeFile, SchemeOCIArchive:
+		return scheme, path, true
+	}
+	return "", "", false
+}
+
+// tempDirPrefix prefixes the temporary directory of each SBOM generation,
+// created in os.TempDir(), which honours TMPDIR.
+const tempDirPrefix = "slsactl-sbom"
+
+// getSource returns the Syft source of input, a registry image reference,
+// a LocalSource or a source as returned by sourceInput, and the function
+// closing it. The image content, or the content of archives, is stored in a
+// temporary directory of its own, removed on close, so that concurrent
+// generations do not remove each other's files. Registry image layers are
+// cached in cacheDir, unless empty.
+func getSource(ctx context.Context, input string, platform *image.Platform, cacheDir string) (source.Source, func(), error) {
+	if scheme, path, ok := LocalSource(input); ok && scheme != SchemeOCIArchive {
+		return filesystemSource(scheme, path)
+	}
+
+	gen := file.NewTempDirGenerator(tempDirPrefix)
+	cleanup := func() {
+		if err := gen.Cleanup(); err != nil {
+			slog.Warn("failed to remove temporary files", "error", err)
+		}
+	}
+
+	var provider image.Provider
+	ref := input
+	switch scheme, path, _ := strings.Cut(input, ":"); scheme {
+	case SchemeOCIArchive:
+		ref = path
+		provider = oci.NewArchiveProviderWithPlatform(gen, path, platform)
+	case "oci-dir":
+		ref = path
+		provider = oci.NewDirectoryProviderWithPlatform(gen, path, platform)
+	case "docker-archive":
+		ref = path
+		provider = docker.NewArchiveProvider(gen, path)
+	case "docker":
+		ref = path
+		provider = docker.NewDaemonProvider(gen, path, platform)
+	default:
+		provider = registryProvider{gen: gen, ref: input, platform: platform, cacheDir: cacheDir}
+	}
+
+	img, err := provider.Provide(ctx)
+	if err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("failed to get source: %w", err)
+	}
+
+	src := stereoscopesource.New(img, stereoscopesource.ImageConfig{
+		Reference: ref,
+		Platform:  platform,
+	})
+	return src, func() {
+		_ = src.Close()
+		cleanup()
+	}, nil
+}
+
+// filesystemSource returns the Syft source of the directory or file at path,
+// and the function closing it. Directories are cataloged as root
+// filesystems, resolving their absolute symlinks within path.
+func filesystemSource(scheme, path string) (source.Source, func(), error) {
+	var src source.Source
+	var err error
+	if scheme == SchemeDir {
+		src, err = directorysource.New(directorysource.Config{Path: path, Base: path})
+	} else {
+		src, err = filesource.New(filesource.Config{Path: path, DigestAlgorithms: []crypto.Hash{crypto.SHA256}})
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get source: %w", err)
+	}
+
+	return src, func() {
+		if err := src.Close(); err != nil {
+			slog.Warn("failed to remove temporary files", "error", err)
+		}
+	}, nil
+}
+
+// registryProvider provides registry images, as the Stereoscope registry
+// provider does, reading their layers through the layer cache.
+type registryProvider struct {
+	gen      *file.TempDirGenerator
+	ref      string
+	platform *image.Platform
+	cacheDir string
+}
+
+func (p registryProvider) Name() string {
+	return image.OciRegistrySource
+}
+
+func (p registryProvider) Provide(ctx context.Context) (*image.Image, error) {
+	ref, err := name.ParseReference(p.ref)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse image reference: %w", err)
+	}
+
+	opts := []remote.Option{
+		remote.WithContext(ctx),
+		remote.WithAuthFromKeychain(authn.DefaultKeychain),
+	}
+	if p.platform != nil {
+		opts = append(opts, remote.WithPlatform(v1.Platform{
+			OS:           p.platform.OS,
+			Architecture: p.platform.Architecture,
+			Variant:      p.platform.Variant,
+		}))
+	}
+
+	desc, err := remote.Get(ref, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch image descriptor: %w", err)
+	}
+	img, err := desc.Image()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get image: %w", err)
+	}
+
+	if p.platform != nil {
+		cfg, err := img.ConfigFile()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get image config: %w", err)
+		}
+		if cfg.OS != p.platform.OS || cfg.Architecture != p.platform.Architecture {
+			return nil, fmt.Errorf("platform not supported: %q", p.platform.String())
+		}
+	}
+
+	if p.cacheDir != "" {
+		img = cache.Image(img, layerCache{dir: p.cacheDir})
+	}
+
+	dir, err := p.gen.NewDirectory("oci-registry-image")
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := []image.AdditionalMetadata{
+		image.WithRepoDigests(ref.Context().Name() + "@" + desc.Digest.String()),
+	}
+	if manifest, err := img.RawManifest(); err == nil {
+		metadata = append(metadata, image.WithManifest(manifest))
+	}
+	if p.platform != nil {
+		metadata = append(metadata,
+			image.WithArchitecture(p.platform.Architecture, p.platform.Variant),
+			image.WithOS(p.platform.OS),
+		)
+	}
+
+	out := image.New(img, nil, dir, metadata...)
+	err = out.Read()
+	if err != nil {
+		return nil, errors.Join(err, out.Cleanup())
+	}
+	return out, nil
+}
@@ -0,0 +1,199 @@
+package sbom
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetSourceRegistry(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+
+	// Directories of other Stereoscope users must be left alone.
+	other := filepath.Join(tmp, "stereoscope-123")
+	require.NoError(t, os.Mkdir(other, 0o700))
+
+	var blobs atomic.Int32
+	reg := registry.New(registry.Logger(log.New(io.Discard, "", 0)))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && strings.Contains(r.URL.Path, "/blobs/") {
+			blobs.Add(1)
+		}
+		reg.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	img, err := random.Image(1024, 2)
+	require.NoError(t, err)
+	ref, err := name.ParseReference(strings.TrimPrefix(srv.URL, "http://") + "/rancher/test:v1")
+	require.NoError(t, err)
+	require.NoError(t, remote.Write(ref, img))
+
+	cacheDir := t.TempDir()
+	generate := func() int32 {
+		blobs.Store(0)
+		src, cleanup, err := getSource(context.Background(), ref.String(), nil, cacheDir)
+		require.NoError(t, err)
+		assert.Equal(t, ref.Context().String(), src.Describe().Name)
+
+		dirs, err := filepath.Glob(filepath.Join(tmp, tempDirPrefix+"-*"))
+		require.NoError(t, err)
+		assert.Len(t, dirs, 1)
+
+		cleanup()
+		return blobs.Load()
+	}
+
+	first := generate()
+	second := generate()
+	assert.Less(t, second, first, "cached layers are downloaded again")
+
+	entries, err := os.ReadDir(tmp)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "stereoscope-123", entries[0].Name())
+
+	cached, err := os.ReadDir(cacheDir)
+	require.NoError(t, err)
+	assert.Len(t, cached, 2)
+}
+
+func TestCreateSBOMLocalLayout(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+
+	dir := filepath.Join(t.TempDir(), "layout")
+	img, err := random.Image(1024, 2)
+	require.NoError(t, err)
+	p, err := layout.Write(dir, empty.Index)
+	require.NoError(t, err)
+	require.NoError(t, p.AppendImage(img))
+
+	s, err := defaultCreateSBOM("oci:"+dir, Options{})
+	require.NoError(t, err)
+	assert.Equal(t, dir, s.Source.Name)
+
+	entries, err := os.ReadDir(tmp)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "temporary files are left behind")
+}
+
+func TestCreateSBOMFilesystem(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+
+	rootfs := filepath.Join(t.TempDir(), "rootfs")
+	require.NoError(t, os.MkdirAll(filepath.Join(rootfs, "lib", "apk", "db"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(rootfs, "lib", "apk", "db", "installed"),
+		[]byte("P:musl\nV:1.2.4-r2\nA:x86_64\nL:MIT\no:musl\n\n"), 0o644))
+
+	archive := filepath.Join(t.TempDir(), "rootfs.tar.gz")
+	writeTar(t, rootfs, archive, true)
+
+	dir := filepath.Join(t.TempDir(), "layout")
+	img, err := random.Image(1024, 2)
+	require.NoError(t, err)
+	p, err := layout.Write(dir, empty.Index)
+	require.NoError(t, err)
+	require.NoError(t, p.AppendImage(img))
+
+	ociArchive := filepath.Join(t.TempDir(), "layout.tar")
+	writeTar(t, dir, ociArchive, false)
+
+	tests := []struct {
+		input       string
+		wantName    string
+		wantPackage string
+	}{
+		{input: "dir:" + rootfs, wantName: rootfs, wantPackage: "musl"},
+		{input: "file:" + archive, wantName: "rootfs.tar.gz", wantPackage: "musl"},
+		{input: "oci-archive:" + ociArchive, wantName: ociArchive},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			s, err := defaultCreateSBOM(tc.input, Options{Platform: "linux/amd64"})
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantName, s.Source.Name)
+
+			var names []string
+			for p := range s.Artifacts.Packages.Enumerate() {
+				names = append(names, p.Name)
+			}
+			if tc.wantPackage != "" {
+				assert.Contains(t, names, tc.wantPackage)
+			}
+
+			entries, err := os.ReadDir(tmp)
+			require.NoError(t, err)
+			assert.Empty(t, entries, "temporary files are left behind")
+		})
+	}
+}
+
+func TestLocalSource(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		input      string
+		wantScheme string
+		wantPath   string
+	}{
+		{input: "dir:/rootfs", wantScheme: SchemeDir, wantPath: "/rootfs"},
+		{input: "file:rke2-images.linux-amd64.tar.zst", wantScheme: SchemeFile, wantPath: "rke2-images.linux-amd64.tar.zst"},
+		{input: "oci-archive:./layout.tar", wantScheme: SchemeOCIArchive, wantPath: "./layout.tar"},
+		{input: "dir:"},
+		{input: "oci:./layout"},
+		{input: "rancher/rancher:v2.12.2"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			t.Parallel()
+
+			scheme, path, ok := LocalSource(tc.input)
+			assert.Equal(t, tc.wantScheme != "", ok)
+			assert.Equal(t, tc.wantScheme, scheme)
+			assert.Equal(t, tc.wantPath, path)
+		})
+	}
+}
+
+// writeTar archives the content of dir to path, gzip compressed when gz.
+func writeTar(t *testing.T, dir, path string, gz bool) {
+	t.Helper()
+
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var w io.Writer = f
+	if gz {
+		zw := gzip.NewWriter(f)
+		defer zw.Close()
+		w = zw
+	}
+	tw := tar.NewWriter(w)
+	defer tw.Close()
+
+	require.NoError(t, tw.AddFS(os.DirFS(dir)))
+}
@@ -0,0 +1,458 @@
+package sbom
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rancherlabs/slsactl/pkg/attestation"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// Minimum elements of an SBOM, as defined by the NTIA and the BSI TR-03183.
+const (
+	ElementSupplier     = "supplier"
+	ElementName         = "name"
+	ElementVersion      = "version"
+	ElementUniqueID     = "unique identifier"
+	ElementDependencies = "dependency relationships"
+	ElementAuthor       = "author"
+	ElementTimestamp    = "timestamp"
+)
+
+// maxSchemaErrors limits the schema errors reported for each document.
+const maxSchemaErrors = 20
+
+var (
+	// ErrInvalidSBOM indicates an SBOM that does not follow its schema.
+	ErrInvalidSBOM = errors.New("SBOM is not valid")
+	// ErrIncompleteSBOM indicates an SBOM without the minimum elements.
+	ErrIncompleteSBOM = errors.New("SBOM lacks minimum elements")
+
+	//go:embed schema/*.json
+	schemas embed.FS
+
+	// schemaFiles maps the spec versions to their JSON schema.
+	schemaFiles = map[string]string{
+		"SPDX-2.2":      "spdx-2.3.schema.json",
+		"SPDX-2.3":      "spdx-2.3.schema.json",
+		"CycloneDX-1.4": "bom-1.4.schema.json",
+		"CycloneDX-1.5": "bom-1.5.schema.json",
+		"CycloneDX-1.6": "bom-1.6.schema.json",
+	}
+	compiled sync.Map
+
+	// dependencyRelationships are the SPDX relationships between a package
+	// and its dependencies.
+	dependencyRelationships = []string{
+		"CONTAINS", "CONTAINED_BY", "DEPENDS_ON", "DEPENDENCY_OF",
+		"DYNAMIC_LINK", "STATIC_LINK", "RUNTIME_DEPENDENCY_OF", "BUILD_DEPENDENCY_OF",
+	}
+)
+
+// Validation is the result of validating an SBOM.
+type Validation struct {
+	// Format is the SBOM standard, spdx or cyclonedx.
+	Format      string `json:"format"`
+	SpecVersion string `json:"specVersion"`
+	// SchemaErrors holds the first errors found validating the document
+	// against the JSON schema of its spec version.
+	SchemaErrors []string `json:"schemaErrors,omitempty"`
+	// Elements holds the minimum elements checks.
+	Elements []ElementCheck `json:"elements"`
+}
+
+// ElementCheck is the check of a minimum element. Document elements, such
+// as the author, have a total of 1, while package elements count the
+// packages.
+type ElementCheck struct {
+	Element string `json:"element"`
+	Missing int    `json:"missing"`
+	Total   int    `json:"total"`
+}
+
+// Valid reports whether the document follows its schema.
+func (v *Validation) Valid() bool {
+	return len(v.SchemaErrors) == 0
+}
+
+// Complete reports whether the document has all the minimum elements.
+func (v *Validation) Complete() bool {
+	for _, e := range v.Elements {
+		if e.Missing > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Err returns ErrInvalidSBOM or ErrIncompleteSBOM when v is not valid or
+// complete.
+func (v *Validation) Err() error {
+	switch {
+	case !v.Valid():
+		return fmt.Errorf("%w: %d schema errors", ErrInvalidSBOM, len(v.SchemaErrors))
+	case !v.Complete():
+		var missing []string
+		for _, e := range v.Elements {
+			if e.Missing > 0 {
+				missing = append(missing, e.Element)
+			}
+		}
+		return fmt.Errorf("%w: %s", ErrIncompleteSBOM, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// Validate validates the SPDX or CycloneDX JSON document doc against its
+// JSON schema, and checks its minimum elements: the supplier, name, version
+// and unique identifier of each package, their dependency relationships,
+// and the author and timestamp of the document.
+func Validate(doc []byte) (*Validation, error) {
+	var v *Validation
+	var err error
+	switch attestation.DetectSBOMFormat(doc) {
+	case attestation.FormatSPDX:
+		v, err = checkSPDX(doc)
+	case attestation.FormatCycloneDX:
+		v, err = checkCycloneDX(doc)
+	default:
+		return nil, fmt.Errorf("%w: only SPDX and CycloneDX JSON documents can be validated", ErrUnsupportedFormat)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSBOM, err)
+	}
+
+	v.SchemaErrors, err = schemaErrors(v.Format, v.SpecVersion, doc)
+	if err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func schemaErrors(format, specVersion string, doc []byte) ([]string, error) {
+	key := specVersion
+	if format == attestation.FormatCycloneDX {
+		key = "CycloneDX-" + specVersion
+	}
+
+	file, ok := schemaFiles[key]
+	if !ok {
+		return []string{fmt.Sprintf("no schema available for %s", key)}, nil
+	}
+
+	schema, err := compileSchema(file)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := schema.Validate(gojsonschema.NewBytesLoader(doc))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSBOM, err)
+	}
+
+	var errs []string
+	for _, e := range result.Errors() {
+		if len(errs) == maxSchemaErrors {
+			errs = append(errs, fmt.Sprintf("and %d more errors", len(result.Errors())-maxSchemaErrors))
+			break
+		}
+		errs = append(errs, e.String())
+	}
+	return errs, nil
+}
+
+// compileSchema returns the embedded schema file, along with the schemas
+// it refers to.
+func compileSchema(file string) (*gojsonschema.Schema, error) {
+	if s, ok := compiled.Load(file); ok {
+		return s.(*gojsonschema.Schema), nil
+	}
+
+	loader := gojsonschema.NewSchemaLoader()
+	if strings.HasPrefix(file, "bom-") {
+		for _, ref := range []string{"spdx.schema.json", "jsf-0.82.schema.json"} {
+			data, err := schemas.ReadFile("schema/" + ref)
+			if err != nil {
+				return nil, err
+			}
+			err = loader.AddSchemas(gojsonschema.NewBytesLoader(data))
+			if err != nil {
+				return nil, fmt.Errorf("failed to load schema %s: %w", ref, err)
+			}
+		}
+	}
+
+	data, err := schemas.ReadFile("schema/" + file)
+	if err != nil {
+		return nil, err
+	}
+	s, err := loader.Compile(gojsonschema.NewBytesLoader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile schema %s: %w", file, err)
+	}
+
+	compiled.Store(file, s)
+	return s, nil
+}
+
+// counter counts the packages missing an element.
+type counter struct {
+	checks []ElementCheck
+}
+
+func (c *counter) add(element string, ok bool) {
+	for i := range c.checks {
+		if c.checks[i].Element == element {
+			c.checks[i].Total++
+			if !ok {
+				c.checks[i].Missing++
+			}
+			return
+		}
+	}
+
+	check := ElementCheck{Element: element, Total: 1}
+	if !ok {
+		check.Missing = 1
+	}
+	c.checks = append(c.checks, check)
+}
+
+func newCounter() *counter {
+	c := &counter{}
+	for _, e := range []string{ElementSupplier, ElementName, ElementVersion, ElementUniqueID, ElementDependencies} {
+		c.checks = append(c.checks, ElementCheck{Element: e})
+	}
+	return c
+}
+
+func checkSPDX(doc []byte) (*Validation, error) {
+	var d struct {
+		SPDXVersion  string `json:"spdxVersion"`
+		CreationInfo struct {
+			Created  string   `json:"created"`
+			Creators []string `json:"creators"`
+		} `json:"creationInfo"`
+		Packages []struct {
+			SPDXID       string `json:"SPDXID"`
+			Name         string `json:"name"`
+			VersionInfo  string `json:"versionInfo"`
+			Supplier     string `json:"supplier"`
+			Purpose      string `json:"primaryPackagePurpose"`
+			ExternalRefs []struct {
+				ReferenceType string `json:"referenceType"`
+			} `json:"externalRefs"`
+		} `json:"packages"`
+		Relationships []struct {
+			Element string `json:"spdxElementId"`
+			Type    string `json:"relationshipType"`
+			Related string `json:"relatedSpdxElement"`
+		} `json:"relationships"`
+	}
+	err := json.Unmarshal(doc, &d)
+	if err != nil {
+		return nil, err
+	}
+
+	related := map[string]bool{}
+	for _, r := range d.Relationships {
+		if slices.Contains(dependencyRelationships, r.Type) {
+			related[r.Element] = true
+			related[r.Related] = true
+		}
+	}
+
+	ids := map[string]int{}
+	for _, p := range d.Packages {
+		ids[p.SPDXID]++
+	}
+
+	c := newCounter()
+	for _, p := range d.Packages {
+		// Files, such as the directory scanned, are not packages.
+		if p.Purpose == "FILE" {
+			continue
+		}
+
+		c.add(ElementSupplier, p.Supplier != "" && p.Supplier != "NOASSERTION")
+		c.add(ElementName, p.Name != "")
+		c.add(ElementVersion, p.VersionInfo != "" && p.VersionInfo != "NOASSERTION")
+
+		identified := false
+		for _, ref := range p.ExternalRefs {
+			switch ref.ReferenceType {
+			case "purl", "cpe22Type", "cpe23Type", "swid":
+				identified = true
+			}
+		}
+		c.add(ElementUniqueID, identified && p.SPDXID != "" && ids[p.SPDXID] == 1)
+		c.add(ElementDependencies, related[p.SPDXID])
+	}
+
+	author := false
+	for _, creator := range d.CreationInfo.Creators {
+		if strings.HasPrefix(creator, "Organization:") || strings.HasPrefix(creator, "Person:") {
+			author = true
+		}
+	}
+	c.add(ElementAuthor, author)
+	c.add(ElementTimestamp, validTimestamp(d.CreationInfo.Created))
+
+	return &Validation{Format: attestation.FormatSPDX, SpecVersion: d.SPDXVersion, Elements: c.checks}, nil
+}
+
+// cdxElement holds the fields of CycloneDX components checked.
+type cdxElement struct {
+	BOMRef       string          `json:"bom-ref"`
+	Type         string          `json:"type"`
+	Name         string          `json:"name"`
+	Version      string          `json:"version"`
+	PURL         string          `json:"purl"`
+	CPE          string          `json:"cpe"`
+	SWID         json.RawMessage `json:"swid"`
+	Publisher    string          `json:"publisher"`
+	Supplier     json.RawMessage `json:"supplier"`
+	Manufacturer json.RawMessage `json:"manufacturer"`
+	Components   []cdxElement    `json:"components"`
+}
+
+func checkCycloneDX(doc []byte) (*Validation, error) {
+	var d struct {
+		SpecVersion string `json:"specVersion"`
+		Metadata    struct {
+			Timestamp    string            `json:"timestamp"`
+			Authors      []json.RawMessage `json:"authors"`
+			Manufacturer json.RawMessage   `json:"manufacturer"`
+			Manufacture  json.RawMessage   `json:"manufacture"`
+			Supplier     json.RawMessage   `json:"supplier"`
+		} `json:"metadata"`
+		Components   []cdxElement `json:"components"`
+		Dependencies []struct {
+			Ref       string   `json:"ref"`
+			DependsOn []string `json:"dependsOn"`
+		} `json:"dependencies"`
+	}
+	err := json.Unmarshal(doc, &d)
+	if err != nil {
+		return nil, err
+	}
+
+	related := map[string]bool{}
+	for _, dep := range d.Dependencies {
+		related[dep.Ref] = true
+		for _, ref := range dep.DependsOn {
+			related[ref] = true
+		}
+	}
+
+	var components []cdxElement
+	var walk func([]cdxElement)
+	walk = func(cs []cdxElement) {
+		for _, c := range cs {
+			components = append(components, c)
+			walk(c.Components)
+		}
+	}
+	walk(d.Components)
+
+	refs := map[string]int{}
+	for _, comp := range components {
+		refs[comp.BOMRef]++
+	}
+
+	c := newCounter()
+	for _, comp := range components {
+		if comp.Type == "file" {
+			continue
+		}
+
+		c.add(ElementSupplier, present(comp.Supplier) || present(comp.Manufacturer) || comp.Publisher != "")
+		c.add(ElementName, comp.Name != "")
+		c.add(ElementVersion, comp.Version != "")
+		c.add(ElementUniqueID, (comp.PURL != "" || comp.CPE != "" || present(comp.SWID)) &&
+			comp.BOMRef != "" && refs[comp.BOMRef] == 1)
+		c.add(ElementDependencies, related[comp.BOMRef])
+	}
+
+	m := d.Metadata
+	c.add(ElementAuthor, len(m.Authors) > 0 || present(m.Manufacturer) || present(m.Manufacture) || present(m.Supplier))
+	c.add(ElementTimestamp, validTimestamp(m.Timestamp))
+
+	return &Validation{Format: attestation.FormatCycloneDX, SpecVersion: d.SpecVersion, Elements: c.checks}, nil
+}
+
+func present(raw json.RawMessage) bool {
+	s := strings.TrimSpace(string(raw))
+	return s != "" && s != "null" && s != "{}"
+}
+
+func validTimestamp(s string) bool {
+	_, err := time.Parse(time.RFC3339, s)
+	return err == nil
+}
+
+// NamedValidation is the validation of an SBOM, named after its image or
+// file. Error is set instead when the SBOM could not be validated.
+type NamedValidation struct {
+	Name  string `json:"name"`
+	Error string `json:"error,omitempty"`
+	*Validation
+}
+
+// WriteValidations writes validations to w as a table listing the schema
+// status and the minimum elements missing of each SBOM, followed by the
+// schema errors found.
+func WriteValidations(w io.Writer, validations []NamedValidation) error {
+	tw := new(tabwriter.Writer)
+	tw.Init(w, 12, 12, 4, ' ', 0)
+
+	fmt.Fprintln(tw, "SBOM\tFormat\tSchema\tElement\tMissing")
+	fmt.Fprintln(tw, "----\t------\t------\t-------\t-------")
+	for _, nv := range validations {
+		if nv.Validation == nil {
+			fmt.Fprintf(tw, "%s\t\t%s\t\t\n", nv.Name, "error: "+nv.Error)
+			continue
+		}
+
+		v := nv.Validation
+		format := v.Format + " " + strings.TrimPrefix(v.SpecVersion, "SPDX-")
+		schema := "valid"
+		if !v.Valid() {
+			schema = fmt.Sprintf("%d errors", len(v.SchemaErrors))
+		}
+
+		rows := 0
+		for _, e := range v.Elements {
+			if e.Missing > 0 {
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d of %d\n", nv.Name, format, schema, e.Element, e.Missing, e.Total)
+				rows++
+			}
+		}
+		if rows == 0 {
+			fmt.Fprintf(tw, "%s\t%s\t%s\tcomplete\t\n", nv.Name, format, schema)
+		}
+	}
+
+	err := tw.Flush()
+	if err != nil {
+		return err
+	}
+
+	for _, nv := range validations {
+		if nv.Validation == nil {
+			continue
+		}
+		for _, e := range nv.SchemaErrors {
+			fmt.Fprintf(w, "%s: %s\n", nv.Name, e)
+		}
+	}
+	return nil
+}
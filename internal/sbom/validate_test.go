@@ -0,0 +1,171 @@
+package sbom
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const completeCycloneDX = `{
+  "bomFormat": "CycloneDX",
+  "specVersion": "1.5",
+  "version": 1,
+  "metadata": {
+    "timestamp": "2024-09-16T07:18:04Z",
+    "authors": [{"name": "SUSE"}]
+  },
+  "components": [
+    {
+      "bom-ref": "pkg:golang/example.com/app@v1.0.0",
+      "type": "application",
+      "name": "example.com/app",
+      "version": "v1.0.0",
+      "purl": "pkg:golang/example.com/app@v1.0.0",
+      "supplier": {"name": "SUSE"}
+    },
+    {
+      "bom-ref": "pkg:golang/golang.org/x/net@v0.20.0",
+      "type": "library",
+      "name": "golang.org/x/net",
+      "version": "v0.20.0",
+      "purl": "pkg:golang/golang.org/x/net@v0.20.0",
+      "publisher": "The Go Authors"
+    }
+  ],
+  "dependencies": [
+    {"ref": "pkg:golang/example.com/app@v1.0.0", "dependsOn": ["pkg:golang/golang.org/x/net@v0.20.0"]}
+  ]
+}`
+
+func missing(v *Validation) map[string]int {
+	m := map[string]int{}
+	for _, e := range v.Elements {
+		if e.Missing > 0 {
+			m[e.Element] = e.Missing
+		}
+	}
+	return m
+}
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	var cdx bytes.Buffer
+	require.NoError(t, Convert(strings.NewReader(cisoperatorAMD64Spdx), &cdx, FormatCycloneDXJSON+"@1.6"))
+
+	tests := []struct {
+		name        string
+		doc         string
+		format      string
+		specVersion string
+		valid       bool
+		missing     map[string]int
+		wantErr     error
+	}{
+		{
+			name:        "complete CycloneDX",
+			doc:         completeCycloneDX,
+			format:      "cyclonedx",
+			specVersion: "1.5",
+			valid:       true,
+			missing:     map[string]int{},
+		},
+		{
+			name:        "SPDX generated by Syft",
+			doc:         cisoperatorAMD64Spdx,
+			format:      "spdx",
+			specVersion: "SPDX-2.3",
+			valid:       true,
+			missing:     map[string]int{ElementSupplier: 83},
+		},
+		{
+			name:        "CycloneDX converted by Syft",
+			doc:         cdx.String(),
+			format:      "cyclonedx",
+			specVersion: "1.6",
+			valid:       true,
+			missing:     map[string]int{ElementSupplier: 83, ElementDependencies: 83, ElementAuthor: 1},
+		},
+		{
+			name: "schema errors and missing elements",
+			doc: `{"bomFormat": "CycloneDX", "specVersion": "1.6", "version": "one",
+				"components": [{"type": "library", "name": "zlib", "bom-ref": "a"}, {"type": "library", "name": "bash", "bom-ref": "a"}]}`,
+			format:      "cyclonedx",
+			specVersion: "1.6",
+			missing: map[string]int{
+				ElementSupplier: 2, ElementVersion: 2, ElementUniqueID: 2,
+				ElementDependencies: 2, ElementAuthor: 1, ElementTimestamp: 1,
+			},
+		},
+		{
+			name:        "unknown spec version",
+			doc:         `{"bomFormat": "CycloneDX", "specVersion": "9.9", "version": 1}`,
+			format:      "cyclonedx",
+			specVersion: "9.9",
+			missing:     map[string]int{ElementAuthor: 1, ElementTimestamp: 1},
+		},
+		{
+			name:    "not an SBOM",
+			doc:     `{"predicateType": "https://slsa.dev/provenance/v1"}`,
+			wantErr: ErrUnsupportedFormat,
+		},
+		{
+			name:    "tag-value SPDX",
+			doc:     "SPDXVersion: SPDX-2.3\n",
+			wantErr: ErrUnsupportedFormat,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			v, err := Validate([]byte(tc.doc))
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.format, v.Format)
+			assert.Equal(t, tc.specVersion, v.SpecVersion)
+			assert.Equal(t, tc.valid, v.Valid(), v.SchemaErrors)
+			assert.Equal(t, tc.missing, missing(v))
+			assert.Equal(t, len(tc.missing) == 0, v.Complete())
+
+			switch {
+			case !tc.valid:
+				require.ErrorIs(t, v.Err(), ErrInvalidSBOM)
+			case len(tc.missing) > 0:
+				require.ErrorIs(t, v.Err(), ErrIncompleteSBOM)
+			default:
+				require.NoError(t, v.Err())
+			}
+		})
+	}
+}
+
+func TestWriteValidations(t *testing.T) {
+	t.Parallel()
+
+	v, err := Validate([]byte(completeCycloneDX))
+	require.NoError(t, err)
+	incomplete, err := Validate([]byte(cisoperatorAMD64Spdx))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	err = WriteValidations(&buf, []NamedValidation{
+		{Name: "rancher/app:v1.0.0", Validation: v},
+		{Name: "rancher/cis-operator:v1.0.0", Validation: incomplete},
+		{Name: "rancher/missing:v1.0.0", Error: "not found"},
+	})
+	require.NoError(t, err)
+
+	out := buf.String()
+	assert.Regexp(t, `rancher/app:v1.0.0\s+cyclonedx 1.5\s+valid\s+complete`, out)
+	assert.Regexp(t, `rancher/cis-operator:v1.0.0\s+spdx 2.3\s+valid\s+supplier\s+83 of 83`, out)
+	assert.Regexp(t, `rancher/missing:v1.0.0\s+error: not found`, out)
+}
@@ -2,6 +2,8 @@ package spinner
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"strings"
 	"sync"
 	"time"
@@ -20,17 +22,21 @@ const (
 )
 
 type Spinner struct {
-	message    string
-	statusChan chan string
-	wg         sync.WaitGroup
-	stop       chan struct{}
+	message string
+	status  string
+	m       sync.Mutex
+	wg      sync.WaitGroup
+	stop    chan struct{}
+	// out is captured on creation, as callers may temporarily reset
+	// os.Stdout to silence verbose dependencies.
+	out io.Writer
 }
 
 func New(message string) *Spinner {
 	return &Spinner{
-		message:    message,
-		stop:       make(chan struct{}),
-		statusChan: make(chan string),
+		message: message,
+		stop:    make(chan struct{}),
+		out:     os.Stdout,
 	}
 }
 
@@ -44,9 +50,13 @@ func (s *Spinner) Start() {
 			select {
 			case <-s.stop:
 				return
-			case status := <-s.statusChan:
-				msg = s.message + ": " + status
 			default:
+				s.m.Lock()
+				if s.status != "" {
+					msg = s.message + ": " + s.status
+				}
+				s.m.Unlock()
+
 				newLen := fmt.Sprintf("%c %s...", frames[i%len(frames)], msg)
 
 				padding := ""
@@ -54,7 +64,7 @@ func (s *Spinner) Start() {
 					padding = strings.Repeat(" ", previousLen-len(newLen))
 				}
 
-				fmt.Printf("\r%s%s", newLen, padding)
+				fmt.Fprintf(s.out, "\r%s%s", newLen, padding)
 
 				i++
 				time.Sleep(sleep)
@@ -64,8 +74,12 @@ func (s *Spinner) Start() {
 	})
 }
 
+// UpdateStatus sets the status shown after the message. It does not block,
+// so it is safe to call for frequent updates such as transfer progress.
 func (s *Spinner) UpdateStatus(newStatus string) {
-	s.statusChan <- newStatus
+	s.m.Lock()
+	s.status = newStatus
+	s.m.Unlock()
 }
 
 func (s *Spinner) Stop(success bool) {
@@ -73,11 +87,11 @@ func (s *Spinner) Stop(success bool) {
 	s.wg.Wait()
 
 	clearLine := "\r" + strings.Repeat(" ", paddingSize)
-	fmt.Print(clearLine)
+	fmt.Fprint(s.out, clearLine)
 
 	if success {
-		fmt.Printf("\r%s %s: %s\n", successIcon, s.message, successText)
+		fmt.Fprintf(s.out, "\r%s %s: %s\n", successIcon, s.message, successText)
 	} else {
-		fmt.Printf("\r%s %s: %s\n", failureIcon, s.message, failureText)
+		fmt.Fprintf(s.out, "\r%s %s: %s\n", failureIcon, s.message, failureText)
 	}
 }
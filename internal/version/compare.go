@@ -0,0 +1,120 @@
+// Package version compares package versions across ecosystems.
+package version
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/mod/semver"
+)
+
+// Compare compares the versions a and b, returning -1, 0 or +1. Semantic
+// Versions, with or without the v prefix, are compared as such, while any
+// other version is compared with CompareDebian.
+func Compare(a, b string) int {
+	va, vb := "v"+strings.TrimPrefix(a, "v"), "v"+strings.TrimPrefix(b, "v")
+	if !semver.IsValid(va) || !semver.IsValid(vb) {
+		return CompareDebian(a, b)
+	}
+	return semver.Compare(va, vb)
+}
+
+// CompareDebian compares the versions a and b as dpkg does, returning -1, 0
+// or +1. Versions are [epoch:]upstream[-revision], where ~ sorts before
+// anything, even the end of the version. This also suits Alpine and RPM
+// versions and most dotted versions.
+func CompareDebian(a, b string) int {
+	ea, ua, ra := splitDebian(a)
+	eb, ub, rb := splitDebian(b)
+
+	if c := compareNumeric(ea, eb); c != 0 {
+		return c
+	}
+	if c := compareFragment(ua, ub); c != 0 {
+		return c
+	}
+	return compareFragment(ra, rb)
+}
+
+func splitDebian(v string) (epoch, upstream, revision string) {
+	epoch, upstream, ok := strings.Cut(v, ":")
+	if !ok || strings.TrimFunc(epoch, unicode.IsDigit) != "" {
+		epoch, upstream = "0", v
+	}
+
+	if i := strings.LastIndex(upstream, "-"); i >= 0 {
+		upstream, revision = upstream[:i], upstream[i+1:]
+	}
+	return epoch, upstream, revision
+}
+
+// compareFragment compares alternating non-digit and digit parts of a and b.
+func compareFragment(a, b string) int {
+	for a != "" || b != "" {
+		var na, nb string
+		na, a = splitPrefix(a, func(r rune) bool { return !unicode.IsDigit(r) })
+		nb, b = splitPrefix(b, func(r rune) bool { return !unicode.IsDigit(r) })
+		if c := compareLexical(na, nb); c != 0 {
+			return c
+		}
+
+		na, a = splitPrefix(a, unicode.IsDigit)
+		nb, b = splitPrefix(b, unicode.IsDigit)
+		if c := compareNumeric(na, nb); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+func splitPrefix(s string, f func(rune) bool) (string, string) {
+	i := strings.IndexFunc(s, func(r rune) bool { return !f(r) })
+	if i < 0 {
+		return s, ""
+	}
+	return s[:i], s[i:]
+}
+
+// compareLexical compares non-digit parts, where ~ sorts first, then the
+// end of the part, then letters and then any other character.
+func compareLexical(a, b string) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var ca, cb byte
+		if i < len(a) {
+			ca = a[i]
+		}
+		if i < len(b) {
+			cb = b[i]
+		}
+		if oa, ob := order(ca), order(cb); oa != ob {
+			if oa < ob {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+func order(c byte) int {
+	switch {
+	case c == '~':
+		return -1
+	case c == 0:
+		return 0
+	case unicode.IsLetter(rune(c)):
+		return int(c)
+	}
+	return int(c) + 256
+}
+
+func compareNumeric(a, b string) int {
+	a, b = strings.TrimLeft(a, "0"), strings.TrimLeft(b, "0")
+	if len(a) != len(b) {
+		if len(a) < len(b) {
+			return -1
+		}
+		return 1
+	}
+	return strings.Compare(a, b)
+}
@@ -0,0 +1,35 @@
+package version
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompare(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{a: "v1.2.0", b: "1.2.0", want: 0},
+		{a: "1.10.0", b: "1.9.0", want: 1},
+		{a: "1.0.0-rc.1", b: "1.0.0", want: -1},
+		{a: "2.36-9+deb12u7", b: "2.36-9+deb12u10", want: -1},
+		{a: "1:1.0-1", b: "2.0-1", want: 1},
+		{a: "1.0~rc1-1", b: "1.0-1", want: -1},
+		{a: "1.0", b: "1.0-0", want: 0},
+		{a: "3.3.1-r0", b: "3.3.2-r0", want: -1},
+		{a: "3.3.2-r1", b: "3.3.2-r0", want: 1},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.a+" "+tc.b, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.want, sign(Compare(tc.a, tc.b)))
+			assert.Equal(t, -tc.want, sign(Compare(tc.b, tc.a)))
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package version
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidConstraint indicates a version constraint that could not be
+// parsed.
+var ErrInvalidConstraint = errors.New("invalid version constraint")
+
+// Constraint is a set of comparisons that versions must all satisfy, such
+// as >=2.0.0, <2.17.1.
+type Constraint []comparison
+
+type comparison struct {
+	op      string
+	version string
+}
+
+// ParseConstraint parses s, a comma-separated list of comparisons made of
+// an operator, =, !=, <, <=, > or >=, and a version. Versions without
+// operator must be equal.
+func ParseConstraint(s string) (Constraint, error) {
+	var c Constraint
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		op := "="
+		for _, o := range []string{"!=", "<=", ">=", "==", "<", ">", "="} {
+			if strings.HasPrefix(part, o) {
+				op, part = o, strings.TrimSpace(part[len(o):])
+				break
+			}
+		}
+		if op == "==" {
+			op = "="
+		}
+		if part == "" || strings.ContainsAny(part, "<>=! ") {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidConstraint, s)
+		}
+
+		c = append(c, comparison{op: op, version: part})
+	}
+	return c, nil
+}
+
+// Check reports whether v satisfies c, comparing versions with Compare. Any
+// version satisfies an empty constraint.
+func (c Constraint) Check(v string) bool {
+	return c.CheckWith(v, Compare)
+}
+
+// CheckWith reports whether v satisfies c, comparing versions with compare,
+// such as the one returned by ComparerFor for the type of the package.
+func (c Constraint) CheckWith(v string, compare func(a, b string) int) bool {
+	for _, cmp := range c {
+		r := compare(v, cmp.version)
+
+		var ok bool
+		switch cmp.op {
+		case "=":
+			ok = r == 0
+		case "!=":
+			ok = r != 0
+		case "<":
+			ok = r < 0
+		case "<=":
+			ok = r <= 0
+		case ">":
+			ok = r > 0
+		case ">=":
+			ok = r >= 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns c as parsed by ParseConstraint.
+func (c Constraint) String() string {
+	parts := make([]string, 0, len(c))
+	for _, cmp := range c {
+		parts = append(parts, cmp.op+cmp.version)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,75 @@
+package version
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConstraint(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		constraint string
+		version    string
+		want       bool
+	}{
+		{constraint: "<3.0.14", version: "3.0.13-r0", want: true},
+		{constraint: "<3.0.14", version: "3.0.14", want: false},
+		{constraint: "<3.0.14", version: "3.0.2", want: true},
+		{constraint: ">=2.0.0, <2.17.1", version: "2.14.1", want: true},
+		{constraint: ">=2.0.0, <2.17.1", version: "2.17.1", want: false},
+		{constraint: ">=2.0.0, <2.17.1", version: "1.2.17", want: false},
+		{constraint: "5.6.0", version: "5.6.0", want: true},
+		{constraint: "== v5.6.0", version: "5.6.0", want: true},
+		{constraint: "!=5.6.1", version: "5.6.0", want: true},
+		{constraint: "> 1:1.0", version: "2.0", want: false},
+		{constraint: "", version: "1.0", want: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.constraint+" "+tc.version, func(t *testing.T) {
+			t.Parallel()
+
+			c, err := ParseConstraint(tc.constraint)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, c.Check(tc.version))
+		})
+	}
+
+	for _, s := range []string{"<", ">=1.0 <2.0", "=>1.0"} {
+		_, err := ParseConstraint(s)
+		assert.ErrorIs(t, err, ErrInvalidConstraint, s)
+	}
+}
+
+func TestConstraintCheckWith(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		purlType string
+		version  string
+		want     bool
+	}{
+		{purlType: "rpm", version: "3.0.14-150600.1.1", want: false},
+		{purlType: "rpm", version: "3.0.13-150600.1.1", want: true},
+		{purlType: "apk", version: "3.0.14-r0", want: false},
+		{purlType: "apk", version: "3.0.13-r1", want: true},
+		{purlType: "apk", version: "3.0.14_rc1-r0", want: true},
+		{purlType: "deb", version: "3.0.14-1~deb12u1", want: false},
+		{purlType: "pypi", version: "3.0.14rc1", want: true},
+		{purlType: "golang", version: "v3.0.14-rc.1", want: true},
+	}
+
+	c, err := ParseConstraint("<3.0.14")
+	require.NoError(t, err)
+
+	for _, tc := range tests {
+		t.Run(tc.purlType+" "+tc.version, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.want, c.CheckWith(tc.version, ComparerFor(tc.purlType)))
+		})
+	}
+}
@@ -0,0 +1,576 @@
+package version
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// pep440 matches the versions of Python packages, as normalized by PEP 440.
+var pep440 = regexp.MustCompile(`^v?` +
+	`(?:([0-9]+)!)?` +
+	`([0-9]+(?:\.[0-9]+)*)` +
+	`(?:[-_.]?(alpha|a|beta|b|preview|pre|c|rc)[-_.]?([0-9]+)?)?` +
+	`(?:-([0-9]+)|[-_.]?(post|rev|r)[-_.]?([0-9]+)?)?` +
+	`(?:[-_.]?(dev)[-_.]?([0-9]+)?)?` +
+	`(?:\+([a-z0-9]+(?:[-_.][a-z0-9]+)*))?$`)
+
+// ComparePEP440 compares the versions a and b of Python packages as PEP 440
+// does, returning -1, 0 or +1. Development releases sort before
+// pre-releases, which sort before the final release, followed by its post
+// releases: 1.0.dev1 < 1.0a1 < 1.0rc1 < 1.0 < 1.0.post1. Invalid versions are
+// compared with CompareDebian.
+func ComparePEP440(a, b string) int {
+	ma := pep440.FindStringSubmatch(strings.ToLower(strings.TrimSpace(a)))
+	mb := pep440.FindStringSubmatch(strings.ToLower(strings.TrimSpace(b)))
+	if ma == nil || mb == nil {
+		return CompareDebian(a, b)
+	}
+
+	if c := compareNumeric(ma[1], mb[1]); c != 0 {
+		return c
+	}
+	if c := compareRelease(ma[2], mb[2]); c != 0 {
+		return c
+	}
+
+	for _, f := range []func([]string) (int, string){pep440Pre, pep440Post, pep440Dev} {
+		ra, na := f(ma)
+		rb, nb := f(mb)
+		if ra != rb {
+			return sign(ra - rb)
+		}
+		if c := compareNumeric(na, nb); c != 0 {
+			return c
+		}
+	}
+	return compareLocal(ma[10], mb[10])
+}
+
+// pep440Pre ranks the pre-release of m, where releases without one sort
+// after any pre-release, unless they are development releases only.
+func pep440Pre(m []string) (int, string) {
+	switch m[3] {
+	case "":
+		if m[5] == "" && m[6] == "" && m[8] != "" {
+			return 0, ""
+		}
+		return 4, ""
+	case "alpha", "a":
+		return 1, m[4]
+	case "beta", "b":
+		return 2, m[4]
+	}
+	return 3, m[4]
+}
+
+// pep440Post ranks the post release of m, after releases without one.
+func pep440Post(m []string) (int, string) {
+	switch {
+	case m[5] != "":
+		return 1, m[5]
+	case m[6] != "":
+		return 1, m[7]
+	}
+	return 0, ""
+}
+
+// pep440Dev ranks the development release of m, before releases without
+// one.
+func pep440Dev(m []string) (int, string) {
+	if m[8] != "" {
+		return 0, m[9]
+	}
+	return 1, ""
+}
+
+// compareRelease compares dotted numbers, where missing numbers are zeros.
+func compareRelease(a, b string) int {
+	sa, sb := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(sa) || i < len(sb); i++ {
+		var na, nb string
+		if i < len(sa) {
+			na = sa[i]
+		}
+		if i < len(sb) {
+			nb = sb[i]
+		}
+		if c := compareNumeric(na, nb); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// compareLocal compares PEP 440 local versions, where numeric segments sort
+// after alphanumeric ones, and versions without one sort first.
+func compareLocal(a, b string) int {
+	split := func(s string) []string {
+		return strings.FieldsFunc(s, func(r rune) bool { return r == '-' || r == '_' || r == '.' })
+	}
+	sa, sb := split(a), split(b)
+	for i := 0; i < len(sa) && i < len(sb); i++ {
+		da, db := isNumeric(sa[i]), isNumeric(sb[i])
+		switch {
+		case da && db:
+			if c := compareNumeric(sa[i], sb[i]); c != 0 {
+				return c
+			}
+		case da != db:
+			if da {
+				return 1
+			}
+			return -1
+		default:
+			if c := strings.Compare(sa[i], sb[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	return sign(len(sa) - len(sb))
+}
+
+// mavenQualifiers are the well-known Maven qualifiers, by order.
+var mavenQualifiers = []string{"alpha", "beta", "milestone", "rc", "snapshot", "", "sp"}
+
+// mavenItem is an item of a Maven version: a number, a qualifier or a list
+// of items, started by a dash or a transition between digits and letters.
+type mavenItem struct {
+	number    string
+	qualifier string
+	list      []*mavenItem
+	isNumber  bool
+	isList    bool
+}
+
+// CompareMaven compares the versions a and b of Maven artifacts as Maven
+// does, returning -1, 0 or +1. Qualifiers sort as alpha < beta < milestone <
+// rc < snapshot < release < sp, before any other qualifier, so that 1.0-rc1
+// and 1.0-alpha-1 sort before 1.0.
+func CompareMaven(a, b string) int {
+	return parseMaven(a).compare(parseMaven(b))
+}
+
+func parseMaven(v string) *mavenItem {
+	v = strings.ToLower(strings.TrimSpace(v))
+	root := &mavenItem{isList: true}
+	list := root
+	stack := []*mavenItem{root}
+
+	var isDigit bool
+	start := 0
+	push := func() {
+		l := &mavenItem{isList: true}
+		list.list = append(list.list, l)
+		list = l
+		stack = append(stack, l)
+	}
+	for i := 0; i < len(v); i++ {
+		c := rune(v[i])
+		switch {
+		case c == '.' || c == '-':
+			if i == start {
+				list.list = append(list.list, &mavenItem{isNumber: true, number: "0"})
+			} else {
+				list.list = append(list.list, newMavenItem(isDigit, v[start:i], false))
+			}
+			start = i + 1
+			if c == '-' {
+				push()
+			}
+		case unicode.IsDigit(c):
+			if !isDigit && i > start {
+				list.list = append(list.list, newMavenItem(false, v[start:i], true))
+				start = i
+				push()
+			}
+			isDigit = true
+		default:
+			if isDigit && i > start {
+				list.list = append(list.list, newMavenItem(true, v[start:i], false))
+				start = i
+				push()
+			}
+			isDigit = false
+		}
+	}
+	if len(v) > start {
+		list.list = append(list.list, newMavenItem(isDigit, v[start:], false))
+	}
+
+	for i := len(stack) - 1; i >= 0; i-- {
+		stack[i].normalize()
+	}
+	return root
+}
+
+func newMavenItem(isDigit bool, s string, followedByDigit bool) *mavenItem {
+	if isDigit {
+		return &mavenItem{isNumber: true, number: s}
+	}
+
+	if followedByDigit && len(s) == 1 {
+		switch s {
+		case "a":
+			s = "alpha"
+		case "b":
+			s = "beta"
+		case "m":
+			s = "milestone"
+		}
+	}
+	switch s {
+	case "ga", "final", "release":
+		s = ""
+	case "cr":
+		s = "rc"
+	}
+	return &mavenItem{qualifier: s}
+}
+
+// isNull reports whether i is equivalent to nothing, as 0, release or an
+// empty list.
+func (i *mavenItem) isNull() bool {
+	switch {
+	case i.isNumber:
+		return strings.TrimLeft(i.number, "0") == ""
+	case i.isList:
+		return len(i.list) == 0
+	}
+	return i.qualifier == ""
+}
+
+// normalize removes the trailing null items of the list i.
+func (i *mavenItem) normalize() {
+	for j := len(i.list) - 1; j >= 0; j-- {
+		last := i.list[j]
+		if last.isNull() {
+			i.list = append(i.list[:j], i.list[j+1:]...)
+		} else if !last.isList {
+			break
+		}
+	}
+}
+
+// compare compares i with other, which is nil for missing items.
+func (i *mavenItem) compare(other *mavenItem) int {
+	switch {
+	case i.isNumber:
+		switch {
+		case other == nil:
+			if i.isNull() {
+				return 0
+			}
+			return 1
+		case other.isNumber:
+			return compareNumeric(i.number, other.number)
+		}
+		return 1
+	case i.isList:
+		switch {
+		case other == nil:
+			if len(i.list) == 0 {
+				return 0
+			}
+			return i.list[0].compare(nil)
+		case other.isNumber:
+			return -1
+		case !other.isList:
+			return 1
+		}
+		for j := 0; j < len(i.list) || j < len(other.list); j++ {
+			var l, r *mavenItem
+			if j < len(i.list) {
+				l = i.list[j]
+			}
+			if j < len(other.list) {
+				r = other.list[j]
+			}
+
+			var c int
+			switch {
+			case l == nil && r == nil:
+			case l == nil:
+				c = -r.compare(nil)
+			default:
+				c = l.compare(r)
+			}
+			if c != 0 {
+				return c
+			}
+		}
+		return 0
+	}
+
+	switch {
+	case other == nil:
+		return strings.Compare(mavenQualifier(i.qualifier), mavenQualifier(""))
+	case other.isNumber, other.isList:
+		return -1
+	}
+	return strings.Compare(mavenQualifier(i.qualifier), mavenQualifier(other.qualifier))
+}
+
+// mavenQualifier returns the sort key of a qualifier: the index of the
+// well-known ones, followed by the others in lexical order.
+func mavenQualifier(q string) string {
+	for i, known := range mavenQualifiers {
+		if q == known {
+			return string(rune('0' + i))
+		}
+	}
+	return string(rune('0'+len(mavenQualifiers))) + "-" + q
+}
+
+// gemSegment matches the segments of RubyGems versions.
+var gemSegment = regexp.MustCompile(`[0-9]+|[a-zA-Z]+`)
+
+// CompareRubyGems compares the versions a and b of Ruby gems as RubyGems
+// does, returning -1, 0 or +1. Versions holding letters are pre-releases,
+// sorting before their release, as in 2.0.0.beta1 < 2.0.0.
+func CompareRubyGems(a, b string) int {
+	sa, sb := gemSegments(a), gemSegments(b)
+	for i := 0; i < len(sa) || i < len(sb); i++ {
+		l, r := "0", "0"
+		if i < len(sa) {
+			l = sa[i]
+		}
+		if i < len(sb) {
+			r = sb[i]
+		}
+
+		dl, dr := isNumeric(l), isNumeric(r)
+		switch {
+		case dl && dr:
+			if c := compareNumeric(l, r); c != 0 {
+				return c
+			}
+		case dl != dr:
+			if dl {
+				return 1
+			}
+			return -1
+		default:
+			if c := strings.Compare(l, r); c != 0 {
+				return c
+			}
+		}
+	}
+	return 0
+}
+
+// gemSegments returns the canonical segments of v, without the trailing
+// zeros of its release and pre-release parts.
+func gemSegments(v string) []string {
+	segments := gemSegment.FindAllString(strings.ReplaceAll(strings.TrimSpace(v), "-", ".pre."), -1)
+
+	i := len(segments)
+	for j, s := range segments {
+		if !isNumeric(s) {
+			i = j
+			break
+		}
+	}
+	return slices.Concat(trimZeros(segments[:i]), trimZeros(segments[i:]))
+}
+
+func trimZeros(segments []string) []string {
+	for len(segments) > 0 && strings.TrimLeft(segments[len(segments)-1], "0") == "" &&
+		isNumeric(segments[len(segments)-1]) {
+		segments = segments[:len(segments)-1]
+	}
+	return segments
+}
+
+func isNumeric(s string) bool {
+	return s != "" && strings.TrimFunc(s, unicode.IsDigit) == ""
+}
+
+func sign(v int) int {
+	switch {
+	case v < 0:
+		return -1
+	case v > 0:
+		return 1
+	}
+	return 0
+}
+
+// CompareRPM compares the versions a and b of RPM packages as rpm does,
+// returning -1, 0 or +1. Versions are [epoch:]version[-release], where ~
+// sorts before anything, even the end of the version, and ^ after the end
+// only. As for rpm dependencies, releases are only compared when both
+// versions have one, so 3.0.14 matches 3.0.14-150600.1.1.
+func CompareRPM(a, b string) int {
+	ea, va, ra := splitRPM(a)
+	eb, vb, rb := splitRPM(b)
+
+	if c := compareNumeric(ea, eb); c != 0 {
+		return c
+	}
+	if c := rpmvercmp(va, vb); c != 0 || ra == "" || rb == "" {
+		return c
+	}
+	return rpmvercmp(ra, rb)
+}
+
+func splitRPM(v string) (epoch, version, release string) {
+	epoch, version, ok := strings.Cut(v, ":")
+	if !ok || !isNumeric(epoch) {
+		epoch, version = "0", v
+	}
+	version, release, _ = strings.Cut(version, "-")
+	return epoch, version, release
+}
+
+// rpmvercmp compares alternating alphabetic and numeric segments of a and
+// b, ignoring any other character but ~ and ^. Numeric segments sort after
+// alphabetic ones.
+func rpmvercmp(a, b string) int {
+	isSeparator := func(r rune) bool {
+		return r != '~' && r != '^' && !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}
+	for a != "" || b != "" {
+		a = strings.TrimLeftFunc(a, isSeparator)
+		b = strings.TrimLeftFunc(b, isSeparator)
+
+		if strings.HasPrefix(a, "~") || strings.HasPrefix(b, "~") {
+			if !strings.HasPrefix(a, "~") {
+				return 1
+			}
+			if !strings.HasPrefix(b, "~") {
+				return -1
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+		if strings.HasPrefix(a, "^") || strings.HasPrefix(b, "^") {
+			switch {
+			case a == "":
+				return -1
+			case b == "":
+				return 1
+			case !strings.HasPrefix(a, "^"):
+				return 1
+			case !strings.HasPrefix(b, "^"):
+				return -1
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+		if a == "" || b == "" {
+			break
+		}
+
+		var sa, sb string
+		isNum := unicode.IsDigit(rune(a[0]))
+		if isNum {
+			sa, a = splitPrefix(a, unicode.IsDigit)
+			sb, b = splitPrefix(b, unicode.IsDigit)
+		} else {
+			sa, a = splitPrefix(a, unicode.IsLetter)
+			sb, b = splitPrefix(b, unicode.IsLetter)
+		}
+		if sb == "" {
+			if isNum {
+				return 1
+			}
+			return -1
+		}
+
+		var c int
+		if isNum {
+			c = compareNumeric(sa, sb)
+		} else {
+			c = strings.Compare(sa, sb)
+		}
+		if c != 0 {
+			return c
+		}
+	}
+
+	switch {
+	case a == "" && b == "":
+		return 0
+	case a == "":
+		return -1
+	}
+	return 1
+}
+
+// apkVersion matches the versions of Alpine packages: dotted numbers, an
+// optional letter, suffixes and the package release.
+var apkVersion = regexp.MustCompile(`^([0-9]+(?:\.[0-9]+)*)([a-z])?((?:_(?:alpha|beta|pre|rc|cvs|svn|git|hg|p)[0-9]*)*)(?:-r([0-9]+))?$`)
+
+// apkSuffix matches a suffix of an Alpine package version.
+var apkSuffix = regexp.MustCompile(`_([a-z]+)([0-9]*)`)
+
+// apkSuffixes are the Alpine version suffixes, by order, where the empty
+// suffix stands for the release itself.
+var apkSuffixes = []string{"alpha", "beta", "pre", "rc", "", "cvs", "svn", "git", "hg", "p"}
+
+// CompareAPK compares the versions a and b of Alpine packages as apk does,
+// returning -1, 0 or +1. Versions are number[letter][_suffix[number]][-rN],
+// where the alpha, beta, pre and rc suffixes sort before the release, as in
+// 3.0.14_rc1-r0 < 3.0.14-r0. Invalid versions are compared with
+// CompareDebian.
+func CompareAPK(a, b string) int {
+	ma, mb := apkVersion.FindStringSubmatch(a), apkVersion.FindStringSubmatch(b)
+	if ma == nil || mb == nil {
+		return CompareDebian(a, b)
+	}
+
+	na, nb := strings.Split(ma[1], "."), strings.Split(mb[1], ".")
+	for i := 0; i < len(na) && i < len(nb); i++ {
+		if c := compareNumeric(na[i], nb[i]); c != 0 {
+			return c
+		}
+	}
+	if c := sign(len(na) - len(nb)); c != 0 {
+		return c
+	}
+	if c := strings.Compare(ma[2], mb[2]); c != 0 {
+		return c
+	}
+
+	sa, sb := apkSuffix.FindAllStringSubmatch(ma[3], -1), apkSuffix.FindAllStringSubmatch(mb[3], -1)
+	for i := 0; i < len(sa) || i < len(sb); i++ {
+		la, lb := []string{"", "", ""}, []string{"", "", ""}
+		if i < len(sa) {
+			la = sa[i]
+		}
+		if i < len(sb) {
+			lb = sb[i]
+		}
+		if c := sign(slices.Index(apkSuffixes, la[1]) - slices.Index(apkSuffixes, lb[1])); c != 0 {
+			return c
+		}
+		if c := compareNumeric(la[2], lb[2]); c != 0 {
+			return c
+		}
+	}
+	return compareNumeric(ma[4], mb[4])
+}
+
+// ComparerFor returns how the versions of packages of the package URL type
+// purlType, such as rpm or pypi, are compared. Distribution packages are
+// compared as their package manager does, the packages of ecosystems with
+// their own rules as those do, and any other package with Compare.
+func ComparerFor(purlType string) func(a, b string) int {
+	switch purlType {
+	case "rpm":
+		return CompareRPM
+	case "deb":
+		return CompareDebian
+	case "apk":
+		return CompareAPK
+	case "pypi":
+		return ComparePEP440
+	case "maven":
+		return CompareMaven
+	case "gem":
+		return CompareRubyGems
+	}
+	return Compare
+}
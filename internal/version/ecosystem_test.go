@@ -0,0 +1,81 @@
+package version
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEcosystemCompare(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		compare func(a, b string) int
+		a, b    string
+		want    int
+	}{
+		{name: "pep440", compare: ComparePEP440, a: "1.0rc1", b: "1.0", want: -1},
+		{name: "pep440", compare: ComparePEP440, a: "1.0.dev1", b: "1.0a1", want: -1},
+		{name: "pep440", compare: ComparePEP440, a: "1.0a2", b: "1.0b1", want: -1},
+		{name: "pep440", compare: ComparePEP440, a: "1.0b2", b: "1.0rc1", want: -1},
+		{name: "pep440", compare: ComparePEP440, a: "1.0c1", b: "1.0rc1", want: 0},
+		{name: "pep440", compare: ComparePEP440, a: "1.0a1.dev1", b: "1.0a1", want: -1},
+		{name: "pep440", compare: ComparePEP440, a: "1.0", b: "1.0.post1", want: -1},
+		{name: "pep440", compare: ComparePEP440, a: "1.0.post1.dev1", b: "1.0.post1", want: -1},
+		{name: "pep440", compare: ComparePEP440, a: "1.0-1", b: "1.0.post1", want: 0},
+		{name: "pep440", compare: ComparePEP440, a: "1.0", b: "1.0.0", want: 0},
+		{name: "pep440", compare: ComparePEP440, a: "1.10", b: "1.9", want: 1},
+		{name: "pep440", compare: ComparePEP440, a: "1!0.1", b: "2.0", want: 1},
+		{name: "pep440", compare: ComparePEP440, a: "1.0", b: "1.0+local.1", want: -1},
+		{name: "pep440", compare: ComparePEP440, a: "1.0+abc", b: "1.0+1", want: -1},
+		{name: "pep440", compare: ComparePEP440, a: "2.32.0", b: "2.32.3", want: -1},
+		{name: "maven", compare: CompareMaven, a: "2.0-beta1", b: "2.0", want: -1},
+		{name: "maven", compare: CompareMaven, a: "1.0-alpha-1", b: "1.0", want: -1},
+		{name: "maven", compare: CompareMaven, a: "1.0-alpha1", b: "1.0-beta1", want: -1},
+		{name: "maven", compare: CompareMaven, a: "1.0-M1", b: "1.0-RC1", want: -1},
+		{name: "maven", compare: CompareMaven, a: "1.0-rc1", b: "1.0-cr1", want: 0},
+		{name: "maven", compare: CompareMaven, a: "1.0-SNAPSHOT", b: "1.0", want: -1},
+		{name: "maven", compare: CompareMaven, a: "1.0", b: "1.0-sp1", want: -1},
+		{name: "maven", compare: CompareMaven, a: "1.0.Final", b: "1.0", want: 0},
+		{name: "maven", compare: CompareMaven, a: "1.0", b: "1.0.0", want: 0},
+		{name: "maven", compare: CompareMaven, a: "1.0", b: "1.0-1", want: -1},
+		{name: "maven", compare: CompareMaven, a: "1.0-foo", b: "1.0", want: 1},
+		{name: "maven", compare: CompareMaven, a: "2.17.0", b: "2.17.1", want: -1},
+		{name: "maven", compare: CompareMaven, a: "2.9.10.8", b: "2.10.0", want: -1},
+		{name: "rubygems", compare: CompareRubyGems, a: "2.0.0.beta1", b: "2.0.0", want: -1},
+		{name: "rubygems", compare: CompareRubyGems, a: "2.0.0.rc1", b: "2.0.0.beta2", want: 1},
+		{name: "rubygems", compare: CompareRubyGems, a: "1.0.0-1", b: "1.0.0", want: -1},
+		{name: "rubygems", compare: CompareRubyGems, a: "1.0", b: "1.0.0", want: 0},
+		{name: "rubygems", compare: CompareRubyGems, a: "1.10.0", b: "1.9.3", want: 1},
+		{name: "rubygems", compare: CompareRubyGems, a: "7.0.8.4", b: "7.0.8", want: 1},
+		{name: "rpm", compare: CompareRPM, a: "3.0.14-150600.1.1", b: "3.0.14", want: 0},
+		{name: "rpm", compare: CompareRPM, a: "3.0.13-150600.1.1", b: "3.0.14", want: -1},
+		{name: "rpm", compare: CompareRPM, a: "3.1.4-150600.5.7.1", b: "3.1.4-150600.5.15.1", want: -1},
+		{name: "rpm", compare: CompareRPM, a: "1:1.0-1", b: "2.0-1", want: 1},
+		{name: "rpm", compare: CompareRPM, a: "1.0~rc1-1", b: "1.0-1", want: -1},
+		{name: "rpm", compare: CompareRPM, a: "1.0^git1-1", b: "1.0-1", want: 1},
+		{name: "rpm", compare: CompareRPM, a: "1.0^git1-1", b: "1.0.1-1", want: -1},
+		{name: "rpm", compare: CompareRPM, a: "1.0a-1", b: "1.0-1", want: 1},
+		{name: "rpm", compare: CompareRPM, a: "1.0a", b: "1.0.1", want: -1},
+		{name: "rpm", compare: CompareRPM, a: "3.0.7-24.el9", b: "3.0.7-27.el9", want: -1},
+		{name: "rpm", compare: CompareRPM, a: "2.9.14-150400.5.32.1", b: "2.9.14-150400.5.4.1", want: 1},
+		{name: "apk", compare: CompareAPK, a: "3.0.14-r0", b: "3.0.14", want: 0},
+		{name: "apk", compare: CompareAPK, a: "3.0.13-r1", b: "3.0.14", want: -1},
+		{name: "apk", compare: CompareAPK, a: "3.0.14-r1", b: "3.0.14-r0", want: 1},
+		{name: "apk", compare: CompareAPK, a: "3.0.14_rc1-r0", b: "3.0.14-r0", want: -1},
+		{name: "apk", compare: CompareAPK, a: "1.2_alpha1", b: "1.2_beta1", want: -1},
+		{name: "apk", compare: CompareAPK, a: "1.2_p1", b: "1.2", want: 1},
+		{name: "apk", compare: CompareAPK, a: "1.2a", b: "1.2", want: 1},
+		{name: "apk", compare: CompareAPK, a: "1.36.1-r29", b: "1.36.1-r5", want: 1},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name+" "+tc.a+" "+tc.b, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.want, sign(tc.compare(tc.a, tc.b)))
+			assert.Equal(t, -tc.want, sign(tc.compare(tc.b, tc.a)))
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package vex
+
+import (
+	"strings"
+
+	"github.com/anchore/packageurl-go"
+	"github.com/google/go-containerregistry/pkg/name"
+)
+
+// Lookup returns the latest statement of docs about the vulnerability
+// known as any of ids, in the package purl of image. Statements apply to
+// the package when their product is the image, and the package is one of
+// its subcomponents or none is listed, or when their product is the
+// package itself.
+func Lookup(docs []*Document, image, purl string, ids ...string) (Statement, bool) {
+	var found Statement
+	var ok bool
+	for _, d := range docs {
+		for _, s := range d.Statements {
+			if s.Timestamp == nil {
+				s.Timestamp = d.Timestamp
+			}
+			if !s.about(ids) || !s.appliesTo(image, purl) {
+				continue
+			}
+			if !ok || !s.timestamp().Before(found.timestamp()) {
+				found, ok = s, true
+			}
+		}
+	}
+	return found, ok
+}
+
+func (s Statement) about(ids []string) bool {
+	names := append([]string{s.Vulnerability.Name, s.Vulnerability.ID}, s.Vulnerability.Aliases...)
+	for _, n := range names {
+		for _, id := range ids {
+			if n != "" && strings.EqualFold(n, id) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (s Statement) appliesTo(image, purl string) bool {
+	for _, p := range s.Products {
+		for _, id := range p.ids() {
+			if purl != "" && samePackage(id, purl) {
+				return true
+			}
+			if !sameImage(id, image) {
+				continue
+			}
+			if len(p.Subcomponents) == 0 {
+				return true
+			}
+			for _, sub := range p.Subcomponents {
+				for _, subID := range sub.ids() {
+					if purl != "" && samePackage(subID, purl) {
+						return true
+					}
+				}
+			}
+		}
+	}
+	return false
+}
+
+// samePackage reports whether the package URL purl is the package id.
+// Qualifiers are ignored, as well as the version when id has none.
+func samePackage(id, purl string) bool {
+	want, err := packageurl.FromString(id)
+	if err != nil {
+		return false
+	}
+	got, err := packageurl.FromString(purl)
+	if err != nil {
+		return false
+	}
+
+	return want.Type == got.Type &&
+		strings.EqualFold(want.Namespace, got.Namespace) &&
+		strings.EqualFold(want.Name, got.Name) &&
+		(want.Version == "" || want.Version == got.Version)
+}
+
+// sameImage reports whether image is the product id, either an image
+// reference or an OCI package URL such as
+// pkg:oci/rancher@sha256:...?repository_url=docker.io/rancher/rancher. The
+// registry is ignored, so mirrored images match. An id that pins a digest
+// only matches image referenced by that digest, as a tag may point to
+// releases the statement does not cover.
+func sameImage(id, image string) bool {
+	if id == image {
+		return true
+	}
+
+	ref, err := name.ParseReference(image, name.WeakValidation)
+	if err != nil {
+		return false
+	}
+	repo := ref.Context().RepositoryStr()
+
+	if strings.HasPrefix(id, "pkg:") {
+		p, err := packageurl.FromString(id)
+		if err != nil || p.Type != packageurl.TypeOCI {
+			return false
+		}
+
+		q := p.Qualifiers.Map()
+		if url := q["repository_url"]; url != "" {
+			if _, path, ok := strings.Cut(url, "/"); !ok || !strings.EqualFold(path, repo) {
+				return false
+			}
+		} else if !strings.EqualFold(p.Name, repo[strings.LastIndex(repo, "/")+1:]) {
+			return false
+		}
+
+		switch r := ref.(type) {
+		case name.Digest:
+			return p.Version == "" || p.Version == r.DigestStr()
+		case name.Tag:
+			return p.Version == "" && (q["tag"] == "" || q["tag"] == r.TagStr())
+		}
+		return true
+	}
+
+	want, err := name.ParseReference(id, name.WeakValidation)
+	if err != nil || !strings.EqualFold(want.Context().RepositoryStr(), repo) {
+		return false
+	}
+	switch w := want.(type) {
+	case name.Digest:
+		d, ok := ref.(name.Digest)
+		return ok && d.DigestStr() == w.DigestStr()
+	case name.Tag:
+		// References without tag parse as latest, yet they match any tag.
+		if t, ok := ref.(name.Tag); ok && strings.HasSuffix(id, ":"+w.TagStr()) {
+			return t.TagStr() == w.TagStr()
+		}
+	}
+	return true
+}
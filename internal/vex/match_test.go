@@ -0,0 +1,74 @@
+package vex
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLookup(t *testing.T) {
+	t.Parallel()
+
+	d, err := Load("testdata/rancher.openvex.json")
+	require.NoError(t, err)
+	docs := []*Document{d}
+
+	tests := []struct {
+		name   string
+		image  string
+		purl   string
+		ids    []string
+		want   string
+		wantOK bool
+	}{
+		{name: "oci purl with tag", image: "registry.rancher.com/rancher/rancher:v2.12.2", purl: "pkg:deb/debian/bash@5.2", ids: []string{"CVE-2024-0001"}, want: StatusNotAffected, wantOK: true},
+		{name: "by alias", image: "rancher/rancher:v2.12.2", ids: []string{"GO-2024-0001", "GHSA-aaaa-bbbb-cccc"}, want: StatusNotAffected, wantOK: true},
+		{name: "oci purl other tag", image: "rancher/rancher:v2.12.1", ids: []string{"CVE-2024-0001"}},
+		{name: "other repository", image: "rancher/shell:v2.12.2", ids: []string{"CVE-2024-0001"}},
+		{name: "latest statement wins", image: "rancher/rancher:v2.12.2", purl: "pkg:golang/golang.org/x/net@v0.20.0", ids: []string{"CVE-2024-0002"}, want: StatusFixed, wantOK: true},
+		{name: "subcomponent without version", image: "rancher/rancher:v2.12.1", purl: "pkg:golang/golang.org/x/net@v0.19.0", ids: []string{"CVE-2024-0002"}, want: StatusAffected, wantOK: true},
+		{name: "not a subcomponent", image: "rancher/rancher:v2.12.2", purl: "pkg:golang/golang.org/x/text@v0.14.0", ids: []string{"CVE-2024-0002"}},
+		{name: "other vulnerability", image: "rancher/rancher:v2.12.2", ids: []string{"CVE-2024-9999"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			s, ok := Lookup(docs, tc.image, tc.purl, tc.ids...)
+			assert.Equal(t, tc.wantOK, ok)
+			assert.Equal(t, tc.want, s.Status)
+		})
+	}
+}
+
+func TestSameImage(t *testing.T) {
+	t.Parallel()
+
+	digest := "sha256:" + "a1b2c3d4e5f6a1b2c3d4e5f6a1b2c3d4e5f6a1b2c3d4e5f6a1b2c3d4e5f6a1b2"
+	tests := []struct {
+		id    string
+		image string
+		want  bool
+	}{
+		{id: "rancher/rancher:v2.12.2", image: "rancher/rancher:v2.12.2", want: true},
+		{id: "docker.io/rancher/rancher", image: "registry.rancher.com/rancher/rancher:v2.12.2", want: true},
+		{id: "rancher/rancher@" + digest, image: "rancher/rancher@" + digest, want: true},
+		{id: "rancher/rancher@" + digest, image: "rancher/rancher@sha256:" + digest[len(digest)-64:len(digest)-1] + "0"},
+		{id: "pkg:oci/rancher@" + digest, image: "rancher/rancher@" + digest, want: true},
+		{id: "rancher/rancher@" + digest, image: "rancher/rancher:v2.13.0"},
+		{id: "rancher/rancher@" + digest, image: "rancher/rancher"},
+		{id: "pkg:oci/rancher@" + digest, image: "rancher/rancher:v2.12.2"},
+		{id: "pkg:oci/rancher@" + digest + "?repository_url=docker.io/rancher/rancher", image: "rancher/rancher:v2.13.0"},
+		{id: "pkg:oci/rancher@" + digest + "?tag=v2.12.2", image: "rancher/rancher:v2.12.2"},
+		{id: "pkg:oci/rancher?tag=v2.12.2", image: "rancher/rancher:v2.12.2", want: true},
+		{id: "pkg:oci/rancher?tag=v2.12.2", image: "rancher/rancher:v2.13.0"},
+		{id: "pkg:oci/rancher?repository_url=docker.io/other/rancher", image: "rancher/rancher:v2.12.2"},
+		{id: "pkg:deb/debian/rancher", image: "rancher/rancher:v2.12.2"},
+	}
+
+	for _, tc := range tests {
+		assert.Equal(t, tc.want, sameImage(tc.id, tc.image), "%s %s", tc.id, tc.image)
+	}
+}
@@ -0,0 +1,272 @@
+package vex
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rancherlabs/slsactl/pkg/attestation"
+)
+
+// Context is the OpenVEX context of the documents written.
+const Context = "https://openvex.dev/ns/v0.2.0"
+
+// Statuses of a vulnerability in a product.
+const (
+	StatusNotAffected        = "not_affected"
+	StatusAffected           = "affected"
+	StatusFixed              = "fixed"
+	StatusUnderInvestigation = "under_investigation"
+)
+
+var (
+	// ErrInvalidDocument indicates a document that is not valid OpenVEX.
+	ErrInvalidDocument = errors.New("invalid OpenVEX document")
+
+	statuses = []string{StatusNotAffected, StatusAffected, StatusFixed, StatusUnderInvestigation}
+
+	justifications = []string{
+		"component_not_present",
+		"vulnerable_code_not_present",
+		"vulnerable_code_not_in_execute_path",
+		"vulnerable_code_cannot_be_controlled_by_adversary",
+		"inline_mitigations_already_exist",
+	}
+)
+
+// Document is an OpenVEX document, as in https://github.com/openvex/spec.
+type Document struct {
+	Context     string      `json:"@context"`
+	ID          string      `json:"@id"`
+	Author      string      `json:"author"`
+	Role        string      `json:"role,omitempty"`
+	Timestamp   *time.Time  `json:"timestamp"`
+	LastUpdated *time.Time  `json:"last_updated,omitempty"`
+	Version     int         `json:"version"`
+	Tooling     string      `json:"tooling,omitempty"`
+	Statements  []Statement `json:"statements"`
+}
+
+// Statement is the status of a vulnerability in some products.
+type Statement struct {
+	ID              string        `json:"@id,omitempty"`
+	Vulnerability   Vulnerability `json:"vulnerability"`
+	Timestamp       *time.Time    `json:"timestamp,omitempty"`
+	LastUpdated     *time.Time    `json:"last_updated,omitempty"`
+	Products        []Product     `json:"products,omitempty"`
+	Status          string        `json:"status"`
+	StatusNotes     string        `json:"status_notes,omitempty"`
+	Justification   string        `json:"justification,omitempty"`
+	ImpactStatement string        `json:"impact_statement,omitempty"`
+	ActionStatement string        `json:"action_statement,omitempty"`
+}
+
+// Vulnerability identifies a vulnerability by name, such as CVE-2024-3094,
+// and its aliases.
+type Vulnerability struct {
+	ID      string   `json:"@id,omitempty"`
+	Name    string   `json:"name"`
+	Aliases []string `json:"aliases,omitempty"`
+}
+
+// Product is a product, identified by a package URL or an image
+// reference, and the subcomponents the statement applies to.
+type Product struct {
+	Component
+	Subcomponents []Component `json:"subcomponents,omitempty"`
+}
+
+// Component is a piece of software, identified by its @id or identifiers.
+type Component struct {
+	ID          string            `json:"@id,omitempty"`
+	Identifiers map[string]string `json:"identifiers,omitempty"`
+}
+
+// ids returns the identifiers of c, such as its package URL.
+func (c Component) ids() []string {
+	var ids []string
+	if c.ID != "" {
+		ids = append(ids, c.ID)
+	}
+	for _, k := range []string{"purl", "cpe23", "cpe22"} {
+		if v := c.Identifiers[k]; v != "" {
+			ids = append(ids, v)
+		}
+	}
+	return ids
+}
+
+// Parse decodes the OpenVEX document in data and validates it.
+func Parse(data []byte) (*Document, error) {
+	var d Document
+	err := json.Unmarshal(data, &d)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidDocument, err)
+	}
+
+	err = d.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// Load reads and validates the OpenVEX document at path.
+func Load(path string) (*Document, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return d, nil
+}
+
+// Validate checks the fields required by the OpenVEX specification,
+// returning all the problems found.
+func (d *Document) Validate() error {
+	var errs []error
+	add := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if !strings.HasPrefix(d.Context, "https://openvex.dev/ns") {
+		add("@context: unknown context %q", d.Context)
+	}
+	if d.ID == "" {
+		add("@id: missing")
+	}
+	if d.Author == "" {
+		add("author: missing")
+	}
+	if d.Timestamp == nil {
+		add("timestamp: missing")
+	}
+	if d.Version < 1 {
+		add("version: must be 1 or higher")
+	}
+
+	for i, s := range d.Statements {
+		prefix := fmt.Sprintf("statements[%d]", i)
+		if s.Vulnerability.Name == "" {
+			add("%s.vulnerability.name: missing", prefix)
+		}
+		if s.Timestamp == nil && d.Timestamp == nil {
+			add("%s.timestamp: missing", prefix)
+		}
+		if len(s.Products) == 0 {
+			add("%s.products: missing", prefix)
+		}
+		for j, p := range s.Products {
+			if len(p.ids()) == 0 {
+				add("%s.products[%d]: missing @id or identifiers", prefix, j)
+			}
+		}
+
+		switch {
+		case !slices.Contains(statuses, s.Status):
+			add("%s.status: unknown status %q", prefix, s.Status)
+		case s.Status == StatusNotAffected && s.Justification == "" && s.ImpactStatement == "":
+			add("%s: not_affected requires a justification or an impact_statement", prefix)
+		case s.Status == StatusAffected && s.ActionStatement == "":
+			add("%s: affected requires an action_statement", prefix)
+		}
+		if s.Justification != "" && !slices.Contains(justifications, s.Justification) {
+			add("%s.justification: unknown justification %q", prefix, s.Justification)
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%w: %w", ErrInvalidDocument, errors.Join(errs...))
+	}
+	return nil
+}
+
+// ForImage returns the OpenVEX documents attached to img as attestations.
+// Documents that are not valid are skipped.
+func ForImage(img string) ([]*Document, error) {
+	atts, err := attestation.List(context.TODO(), img, attestation.Options{})
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []*Document
+	for _, a := range attestation.Filter(atts, attestation.KindVEX) {
+		predicate, err := a.Predicate()
+		if err != nil {
+			slog.Warn("skipping VEX attestation", "image", img, "error", err)
+			continue
+		}
+
+		d, err := Parse(predicate)
+		if err != nil {
+			slog.Warn("skipping VEX attestation", "image", img, "error", err)
+			continue
+		}
+		if !slices.ContainsFunc(docs, func(o *Document) bool { return o.ID == d.ID && o.Version == d.Version }) {
+			docs = append(docs, d)
+		}
+	}
+	return docs, nil
+}
+
+// Merge returns a single document, authored by author, holding the
+// statements of docs. Duplicated statements are kept once, and each
+// statement keeps the timestamp of its document.
+func Merge(author string, docs []*Document) *Document {
+	now := time.Now().UTC()
+	merged := &Document{
+		Context:    Context,
+		ID:         "urn:uuid:" + uuid.NewString(),
+		Author:     author,
+		Timestamp:  &now,
+		Version:    1,
+		Tooling:    "slsactl",
+		Statements: []Statement{},
+	}
+
+	seen := map[string]bool{}
+	for _, d := range docs {
+		for _, s := range d.Statements {
+			if s.Timestamp == nil {
+				s.Timestamp = d.Timestamp
+			}
+
+			key, err := json.Marshal(s)
+			if err != nil || seen[string(key)] {
+				continue
+			}
+			seen[string(key)] = true
+			merged.Statements = append(merged.Statements, s)
+		}
+	}
+
+	slices.SortStableFunc(merged.Statements, func(a, b Statement) int {
+		return cmp.Or(
+			strings.Compare(a.Vulnerability.Name, b.Vulnerability.Name),
+			a.timestamp().Compare(b.timestamp()),
+		)
+	})
+	return merged
+}
+
+func (s Statement) timestamp() time.Time {
+	if s.LastUpdated != nil {
+		return *s.LastUpdated
+	}
+	if s.Timestamp != nil {
+		return *s.Timestamp
+	}
+	return time.Time{}
+}
@@ -0,0 +1,80 @@
+package vex
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	d, err := Load("testdata/rancher.openvex.json")
+	require.NoError(t, err)
+	assert.Len(t, d.Statements, 3)
+
+	tests := []struct {
+		name string
+		doc  string
+		want []string
+	}{
+		{
+			name: "missing fields",
+			doc:  `{"@context": "https://openvex.dev/ns/v0.2.0", "statements": [{"status": "not_affected"}]}`,
+			want: []string{
+				"@id: missing", "author: missing", "timestamp: missing", "version: must be 1 or higher",
+				"statements[0].vulnerability.name: missing", "statements[0].products: missing",
+				"statements[0]: not_affected requires a justification or an impact_statement",
+			},
+		},
+		{
+			name: "unknown values",
+			doc: `{"@context": "https://example.com", "@id": "x", "author": "a", "timestamp": "2024-05-01T10:00:00Z", "version": 1,
+				"statements": [{"vulnerability": {"name": "CVE-1"}, "products": [{"@id": "p"}], "status": "wontfix", "justification": "because"}]}`,
+			want: []string{
+				`@context: unknown context "https://example.com"`,
+				`statements[0].status: unknown status "wontfix"`,
+				`statements[0].justification: unknown justification "because"`,
+			},
+		},
+		{
+			name: "affected without action",
+			doc: `{"@context": "https://openvex.dev/ns", "@id": "x", "author": "a", "timestamp": "2024-05-01T10:00:00Z", "version": 1,
+				"statements": [{"vulnerability": {"name": "CVE-1"}, "products": [{"identifiers": {"purl": "pkg:oci/x"}}], "status": "affected"}]}`,
+			want: []string{"statements[0]: affected requires an action_statement"},
+		},
+		{
+			name: "not JSON",
+			doc:  `not json`,
+			want: []string{"invalid character"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := Parse([]byte(tc.doc))
+			require.ErrorIs(t, err, ErrInvalidDocument)
+			for _, want := range tc.want {
+				assert.Contains(t, err.Error(), want)
+			}
+		})
+	}
+}
+
+func TestMerge(t *testing.T) {
+	t.Parallel()
+
+	d, err := Load("testdata/rancher.openvex.json")
+	require.NoError(t, err)
+
+	merged := Merge("SUSE", []*Document{d, d})
+	require.NoError(t, merged.Validate())
+	assert.Equal(t, "SUSE", merged.Author)
+	require.Len(t, merged.Statements, 3, "duplicated statements are kept once")
+	assert.Equal(t, "CVE-2024-0001", merged.Statements[0].Vulnerability.Name)
+	assert.Equal(t, d.Timestamp, merged.Statements[0].Timestamp, "statements keep the timestamp of their document")
+	assert.Equal(t, StatusAffected, merged.Statements[1].Status, "oldest statements first")
+}
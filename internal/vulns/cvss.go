@@ -0,0 +1,138 @@
+package vulns
+
+import (
+	"math"
+	"strings"
+)
+
+// Severities of vulnerabilities, from the most to the least severe.
+const (
+	SeverityCritical = "Critical"
+	SeverityHigh     = "High"
+	SeverityMedium   = "Medium"
+	SeverityLow      = "Low"
+	SeverityUnknown  = "Unknown"
+)
+
+var severities = []string{SeverityCritical, SeverityHigh, SeverityMedium, SeverityLow, SeverityUnknown}
+
+// severity returns the severity of v for the package affected by a. CVSS v3
+// vectors are preferred over the severities given by the databases.
+func severity(v *Vulnerability, a *Affected) string {
+	for _, s := range v.Severity {
+		if s.Type != "CVSS_V3" {
+			continue
+		}
+		if score, ok := cvss3Score(s.Score); ok {
+			return scoreSeverity(score)
+		}
+	}
+
+	for _, s := range []string{a.EcosystemSpecific.Severity, v.DatabaseSpecific.Severity} {
+		switch strings.ToLower(s) {
+		case "critical":
+			return SeverityCritical
+		case "high", "important":
+			return SeverityHigh
+		case "medium", "moderate":
+			return SeverityMedium
+		case "low", "negligible":
+			return SeverityLow
+		}
+	}
+	return SeverityUnknown
+}
+
+func scoreSeverity(score float64) string {
+	switch {
+	case score >= 9:
+		return SeverityCritical
+	case score >= 7:
+		return SeverityHigh
+	case score >= 4:
+		return SeverityMedium
+	case score > 0:
+		return SeverityLow
+	}
+	return SeverityUnknown
+}
+
+var cvss3Weights = map[string]map[string]float64{
+	"AV": {"N": 0.85, "A": 0.62, "L": 0.55, "P": 0.2},
+	"AC": {"L": 0.77, "H": 0.44},
+	"UI": {"N": 0.85, "R": 0.62},
+	"C":  {"H": 0.56, "L": 0.22, "N": 0},
+	"I":  {"H": 0.56, "L": 0.22, "N": 0},
+	"A":  {"H": 0.56, "L": 0.22, "N": 0},
+}
+
+// cvss3Score returns the base score of a CVSS v3.0 or v3.1 vector, such as
+// CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H.
+func cvss3Score(vector string) (float64, bool) {
+	parts := strings.Split(vector, "/")
+	if len(parts) < 9 || !strings.HasPrefix(parts[0], "CVSS:3") {
+		return 0, false
+	}
+
+	metrics := map[string]string{}
+	for _, p := range parts[1:] {
+		k, v, ok := strings.Cut(p, ":")
+		if !ok {
+			return 0, false
+		}
+		metrics[k] = v
+	}
+
+	w := map[string]float64{}
+	for k, values := range cvss3Weights {
+		v, ok := values[metrics[k]]
+		if !ok {
+			return 0, false
+		}
+		w[k] = v
+	}
+
+	changed := metrics["S"] == "C"
+	var pr float64
+	switch metrics["PR"] {
+	case "N":
+		pr = 0.85
+	case "L":
+		pr = 0.62
+		if changed {
+			pr = 0.68
+		}
+	case "H":
+		pr = 0.27
+		if changed {
+			pr = 0.5
+		}
+	default:
+		return 0, false
+	}
+
+	iss := 1 - (1-w["C"])*(1-w["I"])*(1-w["A"])
+	impact := 6.42 * iss
+	if changed {
+		impact = 7.52*(iss-0.029) - 3.25*math.Pow(iss-0.02, 15)
+	}
+	if impact <= 0 {
+		return 0, true
+	}
+
+	exploitability := 8.22 * w["AV"] * w["AC"] * pr * w["UI"]
+	if changed {
+		return roundUp(math.Min(1.08*(impact+exploitability), 10)), true
+	}
+	return roundUp(math.Min(impact+exploitability, 10)), true
+}
+
+// roundUp returns the smallest number, to one decimal place, equal to or
+// higher than v, as defined by CVSS v3.1.
+func roundUp(v float64) float64 {
+	i := int(math.Round(v * 100000))
+	if i%10000 == 0 {
+		return float64(i) / 100000
+	}
+	return (math.Floor(float64(i)/10000) + 1) / 10
+}
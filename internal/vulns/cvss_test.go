@@ -0,0 +1,34 @@
+package vulns
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCVSS3Score(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		vector string
+		want   float64
+		ok     bool
+	}{
+		{vector: "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H", want: 9.8, ok: true},
+		{vector: "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:C/C:H/I:H/A:H", want: 10.0, ok: true},
+		{vector: "CVSS:3.0/AV:L/AC:L/PR:L/UI:N/S:U/C:H/I:N/A:N", want: 5.5, ok: true},
+		{vector: "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:N/I:N/A:N", want: 0, ok: true},
+		{vector: "CVSS:3.1/AV:N/AC:L", ok: false},
+		{vector: "AV:N/AC:L/Au:N/C:P/I:P/A:P", ok: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.vector, func(t *testing.T) {
+			t.Parallel()
+
+			got, ok := cvss3Score(tc.vector)
+			assert.Equal(t, tc.ok, ok)
+			assert.InDelta(t, tc.want, got, 0.001)
+		})
+	}
+}
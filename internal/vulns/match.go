@@ -0,0 +1,326 @@
+package vulns
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/anchore/packageurl-go"
+	"github.com/anchore/syft/syft/format"
+	"github.com/rancherlabs/slsactl/internal/version"
+)
+
+// Package is a package listed by an SBOM.
+type Package struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	PURL    string `json:"purl,omitempty"`
+}
+
+// Match is a vulnerability affecting a package.
+type Match struct {
+	ID           string   `json:"id"`
+	Aliases      []string `json:"aliases,omitempty"`
+	Package      string   `json:"package"`
+	Version      string   `json:"version"`
+	PURL         string   `json:"purl,omitempty"`
+	Ecosystem    string   `json:"ecosystem"`
+	FixedVersion string   `json:"fixedVersion,omitempty"`
+	Severity     string   `json:"severity"`
+	Summary      string   `json:"summary,omitempty"`
+	// VEXStatus is the status given by the VEX statement about the match,
+	// along with its justification or notes.
+	VEXStatus        string `json:"vexStatus,omitempty"`
+	VEXJustification string `json:"vexJustification,omitempty"`
+}
+
+// Packages returns the packages of the SBOM read from r, in any format
+// known to Syft.
+func Packages(r io.Reader) ([]Package, error) {
+	s, _, _, err := format.Decode(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode SBOM: %w", err)
+	}
+	if s == nil {
+		return nil, fmt.Errorf("failed to decode SBOM: unknown format")
+	}
+
+	var pkgs []Package
+	for _, p := range s.Artifacts.Packages.Sorted() {
+		pkgs = append(pkgs, Package{Name: p.Name, Version: p.Version, PURL: p.PURL})
+	}
+	return pkgs, nil
+}
+
+// compareFunc compares the versions a and b, returning -1, 0 or +1.
+type compareFunc func(a, b string) int
+
+// comparer returns how versions of ecosystem are compared. Ecosystems that
+// follow Semantic Versioning are compared as such, PyPI, Maven and RubyGems
+// by their own rules, while the remaining ones use the Debian algorithm,
+// which also suits Alpine and RPM versions.
+func comparer(ecosystem string) compareFunc {
+	switch ecosystem {
+	case "Go", "npm", "crates.io", "NuGet", "Hex", "Pub", "Packagist", "SwiftURL":
+		return version.Compare
+	case "PyPI":
+		return version.ComparePEP440
+	case "Maven":
+		return version.CompareMaven
+	case "RubyGems":
+		return version.CompareRubyGems
+	}
+	return version.CompareDebian
+}
+
+// query is how a package is looked up in the database.
+type query struct {
+	ecosystem string
+	release   string
+	name      string
+	version   string
+}
+
+// Match returns the vulnerabilities of db affecting pkgs. Packages are
+// identified by their package URL, so those without one are not matched.
+func (db *Database) Match(pkgs []Package) []Match {
+	var matches []Match
+	for _, p := range pkgs {
+		q, ok := newQuery(p.PURL)
+		if !ok {
+			continue
+		}
+
+		for _, v := range db.byPackage[packageID{ecosystem: q.ecosystem, name: q.name}] {
+			for i := range v.Affected {
+				a := &v.Affected[i]
+				if baseEcosystem(a.Package.Ecosystem) != q.ecosystem ||
+					normalizeName(q.ecosystem, a.Package.Name) != q.name ||
+					!sameRelease(a.Package.Ecosystem, q.release) {
+					continue
+				}
+
+				fixed, affected := a.affects(q.version, comparer(q.ecosystem))
+				if !affected {
+					continue
+				}
+
+				matches = append(matches, Match{
+					ID:           v.ID,
+					Aliases:      v.Aliases,
+					Package:      p.Name,
+					Version:      p.Version,
+					PURL:         p.PURL,
+					Ecosystem:    a.Package.Ecosystem,
+					FixedVersion: fixed,
+					Severity:     severity(v, a),
+					Summary:      v.Summary,
+				})
+				break
+			}
+		}
+	}
+	return matches
+}
+
+// affects reports whether version is affected, along with the version that
+// fixed it, if known.
+func (a *Affected) affects(version string, cmp compareFunc) (string, bool) {
+	for _, v := range a.Versions {
+		if cmp(v, version) == 0 {
+			return "", true
+		}
+	}
+
+	for _, r := range a.Ranges {
+		if r.Type != "SEMVER" && r.Type != "ECOSYSTEM" {
+			continue
+		}
+		if fixed, ok := r.affects(version, cmp); ok {
+			return fixed, true
+		}
+	}
+	return "", false
+}
+
+// affects evaluates the events of r in version order, as defined by the
+// OSV schema.
+func (r Range) affects(version string, cmp compareFunc) (string, bool) {
+	events := slices.Clone(r.Events)
+	slices.SortStableFunc(events, func(a, b Event) int {
+		va, vb := a.version(), b.version()
+		switch {
+		case va == vb:
+			return 0
+		case va == "0":
+			return -1
+		case vb == "0":
+			return 1
+		}
+		return cmp(va, vb)
+	})
+
+	affected := false
+	var fixed string
+	for _, e := range events {
+		switch {
+		case e.Introduced != "":
+			if e.Introduced == "0" || cmp(version, e.Introduced) >= 0 {
+				affected = true
+				fixed = ""
+			}
+		case e.Fixed != "":
+			if cmp(version, e.Fixed) >= 0 {
+				affected = false
+			} else if affected && fixed == "" {
+				fixed = e.Fixed
+			}
+		case e.LastAffected != "":
+			if cmp(version, e.LastAffected) > 0 {
+				affected = false
+			}
+		case e.Limit != "":
+			if cmp(version, e.Limit) >= 0 {
+				affected = false
+			}
+		}
+	}
+	return fixed, affected
+}
+
+func (e Event) version() string {
+	for _, v := range []string{e.Introduced, e.Fixed, e.LastAffected, e.Limit} {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// sameRelease reports whether the release of an ecosystem, as in Debian:12,
+// matches release. Ecosystems or packages without release always match.
+func sameRelease(ecosystem, release string) bool {
+	parts := strings.Split(ecosystem, ":")
+	if release == "" || len(parts) < 2 {
+		return true
+	}
+	return parts[1] == release
+}
+
+var purlEcosystems = map[string]string{
+	packageurl.TypeGolang:   "Go",
+	packageurl.TypeNPM:      "npm",
+	packageurl.TypePyPi:     "PyPI",
+	packageurl.TypeMaven:    "Maven",
+	packageurl.TypeGem:      "RubyGems",
+	packageurl.TypeCargo:    "crates.io",
+	packageurl.TypeNuget:    "NuGet",
+	packageurl.TypeComposer: "Packagist",
+	packageurl.TypeHex:      "Hex",
+	packageurl.TypePub:      "Pub",
+}
+
+var distroEcosystems = map[string]string{
+	"alpine":     "Alpine",
+	"wolfi":      "Wolfi",
+	"chainguard": "Chainguard",
+	"debian":     "Debian",
+	"ubuntu":     "Ubuntu",
+	"redhat":     "Red Hat",
+	"rocky":      "Rocky Linux",
+	"almalinux":  "AlmaLinux",
+	"opensuse":   "openSUSE",
+	"sles":       "SUSE",
+	"suse":       "SUSE",
+}
+
+// newQuery returns how the package with purl is looked up in the database.
+// Distribution packages are looked up by their source package, as done by
+// the distribution advisories.
+func newQuery(purl string) (query, bool) {
+	p, err := packageurl.FromString(purl)
+	if err != nil || p.Version == "" {
+		return query{}, false
+	}
+
+	q := query{name: p.Name, version: p.Version}
+	qualifiers := p.Qualifiers.Map()
+
+	switch p.Type {
+	case packageurl.TypeAlpine, packageurl.TypeDebian, packageurl.TypeRPM:
+		q.ecosystem = distroEcosystems[strings.ToLower(p.Namespace)]
+		if q.ecosystem == "" {
+			return query{}, false
+		}
+		q.release = distroRelease(q.ecosystem, qualifiers["distro"])
+
+		if upstream := qualifiers["upstream"]; upstream != "" {
+			if p.Type == packageurl.TypeRPM {
+				q.name = srpmName(upstream)
+			} else {
+				name, version, ok := strings.Cut(upstream, "@")
+				q.name = name
+				if ok {
+					q.version = version
+				}
+			}
+		}
+		if epoch := qualifiers["epoch"]; epoch != "" && epoch != "0" {
+			q.version = epoch + ":" + q.version
+		}
+	default:
+		q.ecosystem = purlEcosystems[p.Type]
+		if q.ecosystem == "" {
+			return query{}, false
+		}
+
+		switch p.Type {
+		case packageurl.TypeGolang, packageurl.TypeComposer, packageurl.TypeNPM:
+			if p.Namespace != "" {
+				q.name = p.Namespace + "/" + p.Name
+			}
+		case packageurl.TypeMaven:
+			q.name = p.Namespace + ":" + p.Name
+		}
+	}
+
+	q.name = normalizeName(q.ecosystem, q.name)
+	return q, true
+}
+
+// distroRelease returns the release of distro, such as alpine-3.18.4 or
+// debian-12, as used by the OSV ecosystem of the distribution.
+func distroRelease(ecosystem, distro string) string {
+	_, version, ok := strings.Cut(distro, "-")
+	if !ok {
+		return ""
+	}
+
+	switch ecosystem {
+	case "Alpine":
+		parts := strings.Split(version, ".")
+		if len(parts) < 2 {
+			return ""
+		}
+		return "v" + parts[0] + "." + parts[1]
+	case "Debian":
+		major, _, _ := strings.Cut(version, ".")
+		return major
+	case "Ubuntu":
+		return version
+	}
+	return ""
+}
+
+// srpmName returns the name of a source RPM file, such as
+// openssl-3.0.7-24.el9.src.rpm.
+func srpmName(srpm string) string {
+	name := strings.TrimSuffix(srpm, ".src.rpm")
+	for range 2 {
+		if i := strings.LastIndex(name, "-"); i > 0 {
+			name = name[:i]
+		}
+	}
+	return name
+}
@@ -0,0 +1,113 @@
+package vulns
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatch(t *testing.T) {
+	t.Parallel()
+
+	db, err := Load("testdata/osv")
+	require.NoError(t, err)
+	assert.Equal(t, 6, db.Len())
+
+	tests := []struct {
+		name  string
+		pkg   Package
+		want  []string
+		fixed string
+	}{
+		{
+			name:  "go module in first range",
+			pkg:   Package{Name: "example.com/lib", Version: "v1.1.0", PURL: "pkg:golang/example.com/lib@v1.1.0"},
+			want:  []string{"GO-2024-0001"},
+			fixed: "1.2.0",
+		},
+		{
+			name: "go module between ranges",
+			pkg:  Package{Name: "example.com/lib", Version: "v1.2.5", PURL: "pkg:golang/example.com/lib@v1.2.5"},
+		},
+		{
+			name:  "go module in second range",
+			pkg:   Package{Name: "example.com/lib", Version: "v1.3.0", PURL: "pkg:golang/example.com/lib@v1.3.0"},
+			want:  []string{"GO-2024-0001"},
+			fixed: "1.3.1",
+		},
+		{
+			name:  "apk by upstream and distro release",
+			pkg:   Package{Name: "libcrypto3", Version: "3.3.1-r0", PURL: "pkg:apk/alpine/libcrypto3@3.3.1-r0?arch=x86_64&upstream=openssl&distro=alpine-3.20.1"},
+			want:  []string{"ALPINE-CVE-2024-0002"},
+			fixed: "3.3.2-r0",
+		},
+		{
+			name: "apk fixed in another release",
+			pkg:  Package{Name: "libcrypto3", Version: "3.1.7-r0", PURL: "pkg:apk/alpine/libcrypto3@3.1.7-r0?upstream=openssl&distro=alpine-3.19.4"},
+		},
+		{
+			name: "deb up to last affected",
+			pkg:  Package{Name: "libc6", Version: "2.36-9+deb12u7", PURL: "pkg:deb/debian/libc6@2.36-9+deb12u7?upstream=glibc&distro=debian-12.7"},
+			want: []string{"DEBIAN-CVE-2024-0003"},
+		},
+		{
+			name: "deb after last affected",
+			pkg:  Package{Name: "libc6", Version: "2.36-9+deb12u8", PURL: "pkg:deb/debian/libc6@2.36-9+deb12u8?upstream=glibc&distro=debian-12.7"},
+		},
+		{
+			name: "npm scoped package by version",
+			pkg:  Package{Name: "@scope/pkg", Version: "2.0.0", PURL: "pkg:npm/%40scope/pkg@2.0.0"},
+			want: []string{"GHSA-0000-0000-0005"},
+		},
+		{
+			name:  "pypi pre-release of fixed version",
+			pkg:   Package{Name: "example-lib", Version: "2.0.0rc1", PURL: "pkg:pypi/example-lib@2.0.0rc1"},
+			want:  []string{"PYSEC-2024-0006"},
+			fixed: "2.0.0",
+		},
+		{
+			name: "pypi post release of fixed version",
+			pkg:  Package{Name: "example-lib", Version: "2.0.0.post1", PURL: "pkg:pypi/example-lib@2.0.0.post1"},
+		},
+		{
+			name: "maven pre-release before introduced",
+			pkg:  Package{Name: "example-core", Version: "2.0-alpha1", PURL: "pkg:maven/com.example/example-core@2.0-alpha1"},
+		},
+		{
+			name:  "maven release after introduced pre-release",
+			pkg:   Package{Name: "example-core", Version: "2.0", PURL: "pkg:maven/com.example/example-core@2.0"},
+			want:  []string{"GHSA-0000-0000-0007"},
+			fixed: "2.0.1",
+		},
+		{
+			name: "package without purl",
+			pkg:  Package{Name: "example.com/lib", Version: "v1.1.0"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			matches := db.Match([]Package{tc.pkg})
+
+			var ids []string
+			for _, m := range matches {
+				ids = append(ids, m.ID)
+				assert.Equal(t, tc.fixed, m.FixedVersion)
+			}
+			assert.Equal(t, tc.want, ids)
+		})
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	t.Parallel()
+
+	_, err := Load("testdata/missing")
+	assert.ErrorIs(t, err, ErrInvalidDatabase)
+
+	_, err = Load("testdata/osv/README.md")
+	assert.ErrorIs(t, err, ErrInvalidDatabase)
+}
@@ -0,0 +1,197 @@
+package vulns
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrInvalidDatabase indicates a vulnerability database that could not be
+// read.
+var ErrInvalidDatabase = errors.New("invalid vulnerability database")
+
+// maxRecordSize limits the size of each OSV record.
+const maxRecordSize = 32 << 20
+
+// Vulnerability is an OSV record, as in https://ossf.github.io/osv-schema.
+type Vulnerability struct {
+	ID               string     `json:"id"`
+	Aliases          []string   `json:"aliases,omitempty"`
+	Summary          string     `json:"summary,omitempty"`
+	Details          string     `json:"details,omitempty"`
+	Withdrawn        string     `json:"withdrawn,omitempty"`
+	Severity         []Severity `json:"severity,omitempty"`
+	Affected         []Affected `json:"affected,omitempty"`
+	DatabaseSpecific struct {
+		Severity string `json:"severity,omitempty"`
+	} `json:"database_specific,omitempty"`
+}
+
+type Severity struct {
+	Type  string `json:"type"`
+	Score string `json:"score"`
+}
+
+type Affected struct {
+	Package struct {
+		Ecosystem string `json:"ecosystem"`
+		Name      string `json:"name"`
+		PURL      string `json:"purl,omitempty"`
+	} `json:"package"`
+	Ranges            []Range  `json:"ranges,omitempty"`
+	Versions          []string `json:"versions,omitempty"`
+	EcosystemSpecific struct {
+		Severity string `json:"severity,omitempty"`
+	} `json:"ecosystem_specific,omitempty"`
+}
+
+type Range struct {
+	Type   string  `json:"type"`
+	Events []Event `json:"events"`
+}
+
+type Event struct {
+	Introduced   string `json:"introduced,omitempty"`
+	Fixed        string `json:"fixed,omitempty"`
+	LastAffected string `json:"last_affected,omitempty"`
+	Limit        string `json:"limit,omitempty"`
+}
+
+// Database holds the vulnerabilities of an OSV dump, indexed by package.
+type Database struct {
+	byPackage map[packageID][]*Vulnerability
+	records   int
+}
+
+type packageID struct {
+	ecosystem string
+	name      string
+}
+
+// Load reads the OSV dump at path, which is either a directory holding
+// OSV JSON files, a zip archive of them, as published by osv.dev for each
+// ecosystem, or a single JSON file with one record or a list of them.
+func Load(path string) (*Database, error) {
+	db := &Database{byPackage: map[packageID][]*Vulnerability{}}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidDatabase, err)
+	}
+
+	switch {
+	case info.IsDir():
+		err = db.loadFS(os.DirFS(path))
+	case strings.EqualFold(filepath.Ext(path), ".zip"):
+		var r *zip.ReadCloser
+		r, err = zip.OpenReader(path)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidDatabase, err)
+		}
+		defer r.Close()
+		err = db.loadFS(r)
+	default:
+		var data []byte
+		data, err = os.ReadFile(path)
+		if err == nil {
+			err = db.add(data)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidDatabase, err)
+	}
+
+	return db, nil
+}
+
+// Len returns the number of vulnerabilities in db.
+func (db *Database) Len() int {
+	return db.records
+}
+
+func (db *Database) loadFS(fsys fs.FS) error {
+	return fs.WalkDir(fsys, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.EqualFold(filepath.Ext(path), ".json") {
+			return nil
+		}
+
+		f, err := fsys.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		data, err := io.ReadAll(io.LimitReader(f, maxRecordSize))
+		if err != nil {
+			return err
+		}
+
+		err = db.add(data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		return nil
+	})
+}
+
+func (db *Database) add(data []byte) error {
+	var vulns []*Vulnerability
+	if data = bytes.TrimSpace(data); len(data) > 0 && data[0] == '[' {
+		err := json.Unmarshal(data, &vulns)
+		if err != nil {
+			return err
+		}
+	} else {
+		var v Vulnerability
+		err := json.Unmarshal(data, &v)
+		if err != nil {
+			return err
+		}
+		vulns = append(vulns, &v)
+	}
+
+	for _, v := range vulns {
+		if v.ID == "" || v.Withdrawn != "" {
+			continue
+		}
+		db.records++
+
+		seen := map[packageID]bool{}
+		for _, a := range v.Affected {
+			id := packageID{
+				ecosystem: baseEcosystem(a.Package.Ecosystem),
+				name:      normalizeName(baseEcosystem(a.Package.Ecosystem), a.Package.Name),
+			}
+			if !seen[id] {
+				seen[id] = true
+				db.byPackage[id] = append(db.byPackage[id], v)
+			}
+		}
+	}
+	return nil
+}
+
+// baseEcosystem drops the release of an ecosystem, as in Alpine:v3.18.
+func baseEcosystem(ecosystem string) string {
+	base, _, _ := strings.Cut(ecosystem, ":")
+	return base
+}
+
+// normalizeName returns the name of a package as it is compared within
+// ecosystem.
+func normalizeName(ecosystem, name string) string {
+	if ecosystem == "PyPI" {
+		return strings.NewReplacer("_", "-", ".", "-").Replace(strings.ToLower(name))
+	}
+	return name
+}
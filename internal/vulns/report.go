@@ -0,0 +1,335 @@
+package vulns
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/rancherlabs/slsactl/internal/vex"
+)
+
+// Output formats of Write.
+const (
+	OutputJSON  = "json"
+	OutputTable = "table"
+	OutputSARIF = "sarif"
+)
+
+// ErrUnsupportedOutput indicates an unknown output format.
+var ErrUnsupportedOutput = errors.New("unsupported output format")
+
+// Report holds the vulnerabilities found in the images of a product, or in
+// a single image.
+type Report struct {
+	Product string        `json:"product,omitempty"`
+	Version string        `json:"version,omitempty"`
+	Images  []ImageReport `json:"images"`
+	// Summary counts the vulnerabilities of all images, counting those of
+	// the same package version in several images once.
+	Summary Summary `json:"summary"`
+}
+
+// ImageReport holds the vulnerabilities found in an image, or an SBOM file.
+type ImageReport struct {
+	Image           string  `json:"image"`
+	Packages        int     `json:"packages"`
+	Vulnerabilities []Match `json:"vulnerabilities,omitempty"`
+	// Suppressed holds the matches that VEX statements declare not
+	// affected or fixed. They are not counted by Summary.
+	Suppressed []Match `json:"suppressed,omitempty"`
+	Summary    Summary `json:"summary"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// Summary counts vulnerabilities by severity.
+type Summary struct {
+	Total    int `json:"total"`
+	Critical int `json:"critical"`
+	High     int `json:"high"`
+	Medium   int `json:"medium"`
+	Low      int `json:"low"`
+	Unknown  int `json:"unknown"`
+	// Suppressed counts the vulnerabilities suppressed by VEX statements.
+	Suppressed int `json:"suppressed,omitempty"`
+}
+
+func (s *Summary) add(severity string) {
+	s.Total++
+	switch severity {
+	case SeverityCritical:
+		s.Critical++
+	case SeverityHigh:
+		s.High++
+	case SeverityMedium:
+		s.Medium++
+	case SeverityLow:
+		s.Low++
+	default:
+		s.Unknown++
+	}
+}
+
+// NewImageReport returns the report of image, for the vulnerabilities of db
+// affecting pkgs.
+func NewImageReport(image string, pkgs []Package, db *Database) ImageReport {
+	r := ImageReport{
+		Image:           image,
+		Packages:        len(pkgs),
+		Vulnerabilities: db.Match(pkgs),
+	}
+
+	slices.SortStableFunc(r.Vulnerabilities, func(a, b Match) int {
+		if c := slices.Index(severities, a.Severity) - slices.Index(severities, b.Severity); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+
+	for _, m := range r.Vulnerabilities {
+		r.Summary.add(m.Severity)
+	}
+	return r
+}
+
+// ApplyVEX applies the VEX statements of docs to the matches of r. Matches
+// declared not affected or fixed are moved to Suppressed, while the others
+// keep the status given.
+func (r *ImageReport) ApplyVEX(docs []*vex.Document) {
+	if len(docs) == 0 {
+		return
+	}
+
+	var kept []Match
+	for _, m := range r.Vulnerabilities {
+		s, ok := vex.Lookup(docs, r.Image, m.PURL, append([]string{m.ID}, m.Aliases...)...)
+		if ok {
+			m.VEXStatus = s.Status
+			m.VEXJustification = cmp.Or(s.Justification, s.ImpactStatement, s.StatusNotes)
+		}
+
+		if ok && (s.Status == vex.StatusNotAffected || s.Status == vex.StatusFixed) {
+			r.Suppressed = append(r.Suppressed, m)
+		} else {
+			kept = append(kept, m)
+		}
+	}
+	r.Vulnerabilities = kept
+
+	r.Summary = Summary{Suppressed: len(r.Suppressed)}
+	for _, m := range r.Vulnerabilities {
+		r.Summary.add(m.Severity)
+	}
+}
+
+// Add adds the report of an image to r.
+func (r *Report) Add(image ImageReport) {
+	r.Images = append(r.Images, image)
+
+	r.Summary = Summary{}
+	seen := map[string]bool{}
+	for _, img := range r.Images {
+		for _, m := range img.Vulnerabilities {
+			if key := matchKey(m); !seen[key] {
+				seen[key] = true
+				r.Summary.add(m.Severity)
+			}
+		}
+		for _, m := range img.Suppressed {
+			if key := "suppressed " + matchKey(m); !seen[key] {
+				seen[key] = true
+				r.Summary.Suppressed++
+			}
+		}
+	}
+}
+
+func matchKey(m Match) string {
+	return m.ID + " " + m.PURL + " " + m.Package + "@" + m.Version
+}
+
+// ValidateOutput checks whether output is supported by Write.
+func ValidateOutput(output string) error {
+	switch output {
+	case OutputJSON, OutputTable, OutputSARIF:
+		return nil
+	}
+	return fmt.Errorf("%w %q: supported values are %s, %s or %s",
+		ErrUnsupportedOutput, output, OutputJSON, OutputTable, OutputSARIF)
+}
+
+// Write writes r to w in output, which is json, table or sarif.
+func (r *Report) Write(w io.Writer, output string) error {
+	if err := ValidateOutput(output); err != nil {
+		return err
+	}
+
+	switch output {
+	case OutputJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	case OutputTable:
+		return r.writeTable(w)
+	case OutputSARIF:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r.sarif())
+	}
+	return nil
+}
+
+func (r *Report) writeTable(w io.Writer) error {
+	tw := new(tabwriter.Writer)
+	tw.Init(w, 12, 12, 4, ' ', 0)
+
+	fmt.Fprintln(tw, "Image\tID\tSeverity\tPackage\tVersion\tFixed In\tVEX")
+	fmt.Fprintln(tw, "-----\t--\t--------\t-------\t-------\t--------\t---")
+	for _, img := range r.Images {
+		if img.Error != "" {
+			fmt.Fprintf(tw, "%s\t%s\t\t\t\t\t\n", img.Image, "error: "+img.Error)
+		}
+		for _, m := range img.Vulnerabilities {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", img.Image, m.ID, m.Severity, m.Package, m.Version, m.FixedVersion, m.VEXStatus)
+		}
+	}
+
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "Image\tTotal\tCritical\tHigh\tMedium\tLow\tUnknown\tSuppressed")
+	fmt.Fprintln(tw, "-----\t-----\t--------\t----\t------\t---\t-------\t----------")
+	for _, img := range r.Images {
+		writeSummaryRow(tw, img.Image, img.Summary)
+	}
+	if len(r.Images) > 1 {
+		name := "all images"
+		if r.Product != "" {
+			name = r.Product + ":" + r.Version
+		}
+		writeSummaryRow(tw, name, r.Summary)
+	}
+
+	return tw.Flush()
+}
+
+func writeSummaryRow(w io.Writer, name string, s Summary) {
+	fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\t%d\t%d\t%d\n", name, s.Total, s.Critical, s.High, s.Medium, s.Low, s.Unknown, s.Suppressed)
+}
+
+// sarifLog is the subset of SARIF 2.1.0 written by Write.
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool struct {
+		Driver struct {
+			Name           string      `json:"name"`
+			InformationURI string      `json:"informationUri"`
+			Rules          []sarifRule `json:"rules"`
+		} `json:"driver"`
+	} `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+	Properties       struct {
+		Severity string `json:"severity"`
+	} `json:"properties"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID       string             `json:"ruleId"`
+	Level        string             `json:"level"`
+	Message      sarifMessage       `json:"message"`
+	Locations    []sarifLocation    `json:"locations"`
+	Suppressions []sarifSuppression `json:"suppressions,omitempty"`
+}
+
+type sarifSuppression struct {
+	Kind          string `json:"kind"`
+	Justification string `json:"justification,omitempty"`
+}
+
+type sarifLocation struct {
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations"`
+}
+
+type sarifLogicalLocation struct {
+	Name               string `json:"name"`
+	FullyQualifiedName string `json:"fullyQualifiedName,omitempty"`
+	Kind               string `json:"kind"`
+}
+
+func (r *Report) sarif() sarifLog {
+	var run sarifRun
+	run.Tool.Driver.Name = "slsactl"
+	run.Tool.Driver.InformationURI = "https://github.com/rancherlabs/slsactl"
+	run.Tool.Driver.Rules = []sarifRule{}
+	run.Results = []sarifResult{}
+
+	rules := map[string]bool{}
+	for _, img := range r.Images {
+		for _, m := range slices.Concat(img.Vulnerabilities, img.Suppressed) {
+			if !rules[m.ID] {
+				rules[m.ID] = true
+				rule := sarifRule{ID: m.ID, ShortDescription: sarifMessage{Text: m.ID}}
+				if m.Summary != "" {
+					rule.ShortDescription.Text = m.Summary
+				}
+				rule.Properties.Severity = m.Severity
+				run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, rule)
+			}
+
+			text := fmt.Sprintf("%s %s in %s is affected by %s", m.Package, m.Version, img.Image, m.ID)
+			if m.FixedVersion != "" {
+				text += ", fixed in " + m.FixedVersion
+			}
+			result := sarifResult{
+				RuleID:  m.ID,
+				Level:   sarifLevel(m.Severity),
+				Message: sarifMessage{Text: text},
+				Locations: []sarifLocation{{LogicalLocations: []sarifLogicalLocation{{
+					Name:               m.Package,
+					FullyQualifiedName: img.Image + "/" + m.PURL,
+					Kind:               "package",
+				}}}},
+			}
+			// Suppressed matches are kept, as SARIF viewers hide them.
+			if m.VEXStatus == vex.StatusNotAffected || m.VEXStatus == vex.StatusFixed {
+				result.Suppressions = []sarifSuppression{{
+					Kind:          "external",
+					Justification: strings.TrimSuffix(m.VEXStatus+": "+m.VEXJustification, ": "),
+				}}
+			}
+			run.Results = append(run.Results, result)
+		}
+	}
+
+	return sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	}
+}
+
+func sarifLevel(severity string) string {
+	switch severity {
+	case SeverityCritical, SeverityHigh:
+		return "error"
+	case SeverityMedium:
+		return "warning"
+	}
+	return "note"
+}
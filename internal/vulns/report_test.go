@@ -0,0 +1,102 @@
+package vulns
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/rancherlabs/slsactl/internal/vex"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReport(t *testing.T) {
+	t.Parallel()
+
+	db, err := Load("testdata/osv")
+	require.NoError(t, err)
+
+	pkgs := []Package{
+		{Name: "example.com/lib", Version: "v1.1.0", PURL: "pkg:golang/example.com/lib@v1.1.0"},
+		{Name: "libc6", Version: "2.36-9", PURL: "pkg:deb/debian/libc6@2.36-9?upstream=glibc&distro=debian-12"},
+	}
+
+	r := Report{Product: "rancher", Version: "v2.12.2"}
+	r.Add(NewImageReport("a", pkgs, db))
+	r.Add(NewImageReport("b", pkgs[:1], db))
+	r.Add(ImageReport{Image: "c", Error: "no SBOM"})
+
+	require.Len(t, r.Images, 3)
+	assert.Equal(t, Summary{Total: 2, Critical: 1, Low: 1}, r.Images[0].Summary)
+	assert.Equal(t, "GO-2024-0001", r.Images[0].Vulnerabilities[0].ID, "most severe first")
+	assert.Equal(t, Summary{Total: 1, Critical: 1}, r.Images[1].Summary)
+	assert.Equal(t, Summary{Total: 2, Critical: 1, Low: 1}, r.Summary, "shared packages counted once")
+
+	var buf bytes.Buffer
+	require.NoError(t, r.Write(&buf, OutputTable))
+	assert.Contains(t, buf.String(), "rancher:v2.12.2")
+	assert.Contains(t, buf.String(), "error: no SBOM")
+
+	buf.Reset()
+	require.NoError(t, r.Write(&buf, OutputSARIF))
+	var log sarifLog
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &log))
+	require.Len(t, log.Runs, 1)
+	assert.Len(t, log.Runs[0].Tool.Driver.Rules, 2)
+	assert.Len(t, log.Runs[0].Results, 3)
+	assert.Equal(t, "error", log.Runs[0].Results[0].Level)
+
+	assert.ErrorIs(t, r.Write(&buf, "xml"), ErrUnsupportedOutput)
+}
+
+func TestApplyVEX(t *testing.T) {
+	t.Parallel()
+
+	db, err := Load("testdata/osv")
+	require.NoError(t, err)
+
+	doc, err := vex.Parse([]byte(`{
+  "@context": "https://openvex.dev/ns/v0.2.0",
+  "@id": "https://example.com/vex/1",
+  "author": "Rancher Security",
+  "timestamp": "2024-05-01T10:00:00Z",
+  "version": 1,
+  "statements": [{
+    "vulnerability": {"name": "CVE-2024-0001"},
+    "products": [{"@id": "rancher/rancher", "subcomponents": [{"@id": "pkg:golang/example.com/lib"}]}],
+    "status": "not_affected",
+    "justification": "vulnerable_code_not_in_execute_path"
+  }, {
+    "vulnerability": {"name": "DEBIAN-CVE-2024-0003"},
+    "products": [{"@id": "rancher/rancher"}],
+    "status": "under_investigation"
+  }]
+}`))
+	require.NoError(t, err)
+
+	pkgs := []Package{
+		{Name: "example.com/lib", Version: "v1.1.0", PURL: "pkg:golang/example.com/lib@v1.1.0"},
+		{Name: "libc6", Version: "2.36-9", PURL: "pkg:deb/debian/libc6@2.36-9?upstream=glibc&distro=debian-12"},
+	}
+
+	img := NewImageReport("rancher/rancher:v2.12.2", pkgs, db)
+	img.ApplyVEX([]*vex.Document{doc})
+
+	require.Len(t, img.Suppressed, 1, "matched by alias")
+	assert.Equal(t, "GO-2024-0001", img.Suppressed[0].ID)
+	assert.Equal(t, "vulnerable_code_not_in_execute_path", img.Suppressed[0].VEXJustification)
+	require.Len(t, img.Vulnerabilities, 1)
+	assert.Equal(t, vex.StatusUnderInvestigation, img.Vulnerabilities[0].VEXStatus)
+	assert.Equal(t, Summary{Total: 1, Low: 1, Suppressed: 1}, img.Summary)
+
+	var r Report
+	r.Add(img)
+	assert.Equal(t, Summary{Total: 1, Low: 1, Suppressed: 1}, r.Summary)
+
+	var buf bytes.Buffer
+	require.NoError(t, r.Write(&buf, OutputSARIF))
+	var log sarifLog
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &log))
+	require.Len(t, log.Runs[0].Results, 2)
+	assert.Equal(t, "external", log.Runs[0].Results[1].Suppressions[0].Kind)
+}
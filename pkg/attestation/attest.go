@@ -0,0 +1,278 @@
+package attestation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/sigstore/sigstore/pkg/signature"
+	"github.com/sigstore/sigstore/pkg/signature/dsse"
+	"github.com/sigstore/sigstore/pkg/signature/options"
+)
+
+// Predicate types of the attestations created by Attest.
+const (
+	PredicateSPDX      = "https://spdx.dev/Document"
+	PredicateCycloneDX = "https://cyclonedx.org/bom"
+	PredicateSLSAv1    = "https://slsa.dev/provenance/v1"
+	PredicateSLSAv02   = "https://slsa.dev/provenance/v0.2"
+)
+
+// statementType is the type of the in-toto statements created by Attest.
+const statementType = "https://in-toto.io/Statement/v1"
+
+// Annotations cosign sets on the layers of the .att tag.
+const (
+	cosignSignatureAnnotation = "dev.cosignproject.cosign/signature"
+	predicateTypeAnnotation   = "predicateType"
+)
+
+var (
+	// ErrUnknownPredicate indicates the predicate type cannot be derived
+	// from the predicate content.
+	ErrUnknownPredicate = errors.New("unknown predicate")
+	// ErrUnsupportedSource indicates attestations cannot be attached to
+	// the requested source.
+	ErrUnsupportedSource = errors.New("unsupported attestation source")
+	// ErrPlatformNotFound indicates the image has no platform matching the
+	// requested one.
+	ErrPlatformNotFound = errors.New("platform not found")
+)
+
+// AttestOptions tunes how attestations are created and attached.
+type AttestOptions struct {
+	Options
+	// Source is where the attestation is attached: SourceCosign, the
+	// default, or SourceReferrer.
+	Source string
+	// Platform selects the image attested within multi-platform images,
+	// such as linux/amd64. When empty, the image ref points to is attested.
+	Platform string
+}
+
+// PredicateType returns the predicate type of predicate, a JSON document
+// of kind KindSBOM or KindProvenance.
+func PredicateType(kind string, predicate []byte) (string, error) {
+	switch kind {
+	case KindSBOM:
+		switch DetectSBOMFormat(predicate) {
+		case FormatSPDX:
+			return PredicateSPDX, nil
+		case FormatCycloneDX:
+			return PredicateCycloneDX, nil
+		}
+		return "", fmt.Errorf("%w: not an SPDX or CycloneDX JSON document", ErrUnknownPredicate)
+
+	case KindProvenance:
+		var p struct {
+			BuildDefinition json.RawMessage `json:"buildDefinition"`
+			BuildType       string          `json:"buildType"`
+			Builder         json.RawMessage `json:"builder"`
+		}
+		if json.Unmarshal(predicate, &p) != nil {
+			return "", fmt.Errorf("%w: not a JSON document", ErrUnknownPredicate)
+		}
+		switch {
+		case len(p.BuildDefinition) > 0:
+			return PredicateSLSAv1, nil
+		case p.BuildType != "" || len(p.Builder) > 0:
+			return PredicateSLSAv02, nil
+		}
+		return "", fmt.Errorf("%w: not a SLSA provenance", ErrUnknownPredicate)
+	}
+	return "", fmt.Errorf("%w: kind %q", ErrUnknownPredicate, kind)
+}
+
+// LoadSigner returns the signer of the PEM private key key, which may be a
+// cosign key pair encrypted with password, or a PKCS #8, PKCS #1 or EC
+// private key. ED25519 keys sign pre-hashed messages, as cosign does.
+func LoadSigner(key, password []byte) (signature.Signer, error) {
+	pk, err := cryptoutils.UnmarshalPEMToPrivateKey(key, cryptoutils.StaticPasswordFunc(password))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load private key: %w", err)
+	}
+
+	signer, err := signature.LoadDefaultSignerVerifier(pk, options.WithED25519ph())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load signer: %w", err)
+	}
+	return signer, nil
+}
+
+// Statement returns the in-toto v1 statement of predicate, with subject
+// as its only subject.
+func Statement(subject name.Digest, predicateType string, predicate json.RawMessage) ([]byte, error) {
+	digest, err := v1.NewHash(subject.DigestStr())
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse subject digest: %w", err)
+	}
+	if !json.Valid(predicate) {
+		return nil, fmt.Errorf("%w: predicate is not JSON", ErrNotAttestation)
+	}
+
+	type subjectEntry struct {
+		Name   string            `json:"name"`
+		Digest map[string]string `json:"digest"`
+	}
+	return json.Marshal(struct {
+		Type          string          `json:"_type"`
+		Subject       []subjectEntry  `json:"subject"`
+		PredicateType string          `json:"predicateType"`
+		Predicate     json.RawMessage `json:"predicate"`
+	}{
+		Type: statementType,
+		Subject: []subjectEntry{{
+			Name:   subject.Context().Name(),
+			Digest: map[string]string{digest.Algorithm: digest.Hex},
+		}},
+		PredicateType: predicateType,
+		Predicate:     predicate,
+	})
+}
+
+// Sign returns the DSSE envelope of statement signed by signer.
+func Sign(signer signature.Signer, statement []byte) ([]byte, error) {
+	envelope, err := dsse.WrapSigner(signer, MediaTypeInToto).SignMessage(bytes.NewReader(statement))
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign statement: %w", err)
+	}
+	return envelope, nil
+}
+
+// Attest signs an in-toto statement of predicate, with the digest of the
+// image ref as subject, and attaches it to the image as opts.Source sets.
+// It returns the attested image digest.
+func Attest(ctx context.Context, ref, predicateType string, predicate json.RawMessage, signer signature.Signer, opts AttestOptions) (name.Digest, error) {
+	if opts.Source != "" && opts.Source != SourceCosign && opts.Source != SourceReferrer {
+		return name.Digest{}, fmt.Errorf("%w: %q", ErrUnsupportedSource, opts.Source)
+	}
+
+	r, err := name.ParseReference(ref)
+	if err != nil {
+		return name.Digest{}, fmt.Errorf("failed to parse image reference: %w", err)
+	}
+
+	subject, err := attestSubject(ctx, r, opts)
+	if err != nil {
+		return name.Digest{}, err
+	}
+	digest := r.Context().Digest(subject.Digest.String())
+
+	statement, err := Statement(digest, predicateType, predicate)
+	if err != nil {
+		return name.Digest{}, err
+	}
+	envelope, err := Sign(signer, statement)
+	if err != nil {
+		return name.Digest{}, err
+	}
+
+	if opts.Source == SourceReferrer {
+		err = attachReferrer(ctx, digest, subject, predicateType, envelope, opts.Options)
+	} else {
+		err = attachCosign(ctx, digest, predicateType, envelope, opts.Options)
+	}
+	if err != nil {
+		return name.Digest{}, err
+	}
+	return digest, nil
+}
+
+// attestSubject returns the descriptor of the image r points to or, when
+// opts.Platform is set, of its image for that platform.
+func attestSubject(ctx context.Context, r name.Reference, opts AttestOptions) (v1.Descriptor, error) {
+	desc, err := remote.Get(r, opts.remoteOptions(ctx)...)
+	if err != nil {
+		return v1.Descriptor{}, fmt.Errorf("failed to fetch image descriptor: %w", err)
+	}
+	if opts.Platform == "" || !desc.MediaType.IsIndex() {
+		return desc.Descriptor, nil
+	}
+
+	spec, err := v1.ParsePlatform(opts.Platform)
+	if err != nil {
+		return v1.Descriptor{}, fmt.Errorf("failed to parse platform: %w", err)
+	}
+	idx, err := desc.ImageIndex()
+	if err != nil {
+		return v1.Descriptor{}, fmt.Errorf("failed to get image index: %w", err)
+	}
+	m, err := idx.IndexManifest()
+	if err != nil {
+		return v1.Descriptor{}, fmt.Errorf("failed to get index manifest: %w", err)
+	}
+
+	for _, d := range m.Manifests {
+		if d.Platform != nil && !isAttestationManifest(d) && d.Platform.Satisfies(*spec) {
+			return d, nil
+		}
+	}
+	return v1.Descriptor{}, fmt.Errorf("%w: %s", ErrPlatformNotFound, opts.Platform)
+}
+
+// attachCosign appends envelope to the attestations cosign attaches to the
+// .att tag of digest, as cosign attest does.
+func attachCosign(ctx context.Context, digest name.Digest, predicateType string, envelope []byte, opts Options) error {
+	tag := digest.Context().Tag(strings.Replace(digest.DigestStr(), ":", "-", 1) + ".att")
+
+	base, err := remote.Image(tag, opts.remoteOptions(ctx)...)
+	if err != nil {
+		if !isNotFound(err) {
+			return fmt.Errorf("failed to fetch attestations %q: %w", tag, err)
+		}
+		base = mutate.ConfigMediaType(mutate.MediaType(empty.Image, types.OCIManifestSchema1), types.OCIConfigJSON)
+	}
+
+	img, err := mutate.Append(base, mutate.Addendum{
+		Layer: static.NewLayer(envelope, MediaTypeDSSE),
+		Annotations: map[string]string{
+			cosignSignatureAnnotation: "",
+			predicateTypeAnnotation:   predicateType,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add attestation: %w", err)
+	}
+
+	err = remote.Write(tag, img, opts.remoteOptions(ctx)...)
+	if err != nil {
+		return fmt.Errorf("failed to push attestations %q: %w", tag, err)
+	}
+	return nil
+}
+
+// attachReferrer pushes envelope as a manifest referring to subject. On
+// registries without the referrers API, the fallback tag is updated.
+func attachReferrer(ctx context.Context, digest name.Digest, subject v1.Descriptor, predicateType string, envelope []byte, opts Options) error {
+	img, err := mutate.AppendLayers(mutate.MediaType(empty.Image, types.OCIManifestSchema1),
+		static.NewLayer(envelope, MediaTypeDSSE))
+	if err != nil {
+		return fmt.Errorf("failed to add attestation: %w", err)
+	}
+
+	subject = v1.Descriptor{MediaType: subject.MediaType, Size: subject.Size, Digest: subject.Digest}
+	img = mutate.Annotations(mutate.ConfigMediaType(img, MediaTypeDSSE),
+		map[string]string{predicateTypeAnnotation: predicateType}).(v1.Image)
+	referrer := mutate.Subject(img, subject).(v1.Image)
+
+	d, err := referrer.Digest()
+	if err != nil {
+		return fmt.Errorf("failed to get referrer digest: %w", err)
+	}
+	err = remote.Write(digest.Context().Digest(d.String()), referrer, opts.remoteOptions(ctx)...)
+	if err != nil {
+		return fmt.Errorf("failed to push referrer of %q: %w", digest, err)
+	}
+	return nil
+}
@@ -0,0 +1,247 @@
+package attestation
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/sigstore/cosign/v3/pkg/cosign"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/sigstore/sigstore/pkg/signature"
+	"github.com/sigstore/sigstore/pkg/signature/dsse"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPredicateType(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		kind      string
+		predicate string
+		want      string
+		wantErr   error
+	}{
+		{name: "spdx", kind: KindSBOM, predicate: `{"spdxVersion":"SPDX-2.3"}`, want: PredicateSPDX},
+		{name: "cyclonedx", kind: KindSBOM, predicate: `{"bomFormat":"CycloneDX"}`, want: PredicateCycloneDX},
+		{name: "unknown sbom", kind: KindSBOM, predicate: `{"foo":"bar"}`, wantErr: ErrUnknownPredicate},
+		{name: "slsa v1", kind: KindProvenance, predicate: `{"buildDefinition":{},"runDetails":{}}`, want: PredicateSLSAv1},
+		{name: "slsa v0.2", kind: KindProvenance, predicate: `{"builder":{"id":"x"},"buildType":"y"}`, want: PredicateSLSAv02},
+		{name: "unknown provenance", kind: KindProvenance, predicate: `{"foo":"bar"}`, wantErr: ErrUnknownPredicate},
+		{name: "not json", kind: KindProvenance, predicate: `foo`, wantErr: ErrUnknownPredicate},
+		{name: "unknown kind", kind: KindVEX, predicate: `{}`, wantErr: ErrUnknownPredicate},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := PredicateType(tc.kind, []byte(tc.predicate))
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestLoadSigner(t *testing.T) {
+	t.Parallel()
+
+	pk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	plain, err := cryptoutils.MarshalPrivateKeyToPEM(pk)
+	require.NoError(t, err)
+
+	keys, err := cosign.GenerateKeyPair(func(bool) ([]byte, error) { return []byte("secret"), nil })
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		key      []byte
+		password string
+		wantErr  bool
+	}{
+		{name: "pkcs8", key: plain},
+		{name: "cosign", key: keys.PrivateBytes, password: "secret"},
+		{name: "cosign wrong password", key: keys.PrivateBytes, password: "wrong", wantErr: true},
+		{name: "not pem", key: []byte("foo"), wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			signer, err := LoadSigner(tc.key, []byte(tc.password))
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			envelope, err := Sign(signer, []byte(`{"predicateType":"foo"}`))
+			require.NoError(t, err)
+			verifyEnvelope(t, signer, envelope)
+		})
+	}
+}
+
+func TestAttest(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		source   string
+		platform string
+	}{
+		{name: "cosign", source: SourceCosign},
+		{name: "default source", source: ""},
+		{name: "referrer", source: SourceReferrer},
+		{name: "platform", source: SourceCosign, platform: "linux/arm64"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ref := newTestRegistry(t) + "/rancher/foo:v1"
+			want := pushRandomIndex(t, ref, tc.platform)
+			signer := testSigner(t)
+
+			predicate := json.RawMessage(`{"spdxVersion":"SPDX-2.3","name":"foo"}`)
+			opts := AttestOptions{Source: tc.source, Platform: tc.platform}
+
+			digest, err := Attest(context.Background(), ref, PredicateSPDX, predicate, signer, opts)
+			require.NoError(t, err)
+			assert.Equal(t, want, digest.DigestStr())
+
+			// A second attestation is added along with the first one.
+			_, err = Attest(context.Background(), ref, PredicateSLSAv1, json.RawMessage(`{"buildDefinition":{}}`), signer, opts)
+			require.NoError(t, err)
+
+			atts, err := List(context.Background(), ref, Options{})
+			require.NoError(t, err)
+			require.Len(t, atts, 2)
+
+			source := tc.source
+			if source == "" {
+				source = SourceCosign
+			}
+			for _, a := range atts {
+				assert.Equal(t, source, a.Source)
+				assert.Equal(t, want, a.Subject)
+				verifyEnvelope(t, signer, a.Envelope)
+			}
+
+			sbom := Filter(atts, KindSBOM)
+			require.Len(t, sbom, 1)
+			assert.Equal(t, PredicateSPDX, sbom[0].PredicateType)
+
+			got, err := sbom[0].Predicate()
+			require.NoError(t, err)
+			assert.JSONEq(t, string(predicate), string(got))
+
+			var statement struct {
+				Type    string `json:"_type"`
+				Subject []struct {
+					Name   string            `json:"name"`
+					Digest map[string]string `json:"digest"`
+				} `json:"subject"`
+			}
+			require.NoError(t, json.Unmarshal(sbom[0].Statement, &statement))
+			assert.Equal(t, "https://in-toto.io/Statement/v1", statement.Type)
+			require.Len(t, statement.Subject, 1)
+			assert.Equal(t, digest.Context().Name(), statement.Subject[0].Name)
+			assert.Equal(t, want, "sha256:"+statement.Subject[0].Digest["sha256"])
+		})
+	}
+}
+
+func TestAttestErrors(t *testing.T) {
+	t.Parallel()
+
+	ref := newTestRegistry(t) + "/rancher/foo:v1"
+	pushRandomIndex(t, ref, "")
+	signer := testSigner(t)
+	predicate := json.RawMessage(`{"spdxVersion":"SPDX-2.3"}`)
+
+	_, err := Attest(context.Background(), ref, PredicateSPDX, predicate, signer, AttestOptions{Source: SourceBuildKit})
+	require.ErrorIs(t, err, ErrUnsupportedSource)
+
+	_, err = Attest(context.Background(), ref, PredicateSPDX, predicate, signer, AttestOptions{Platform: "linux/s390x"})
+	require.ErrorIs(t, err, ErrPlatformNotFound)
+
+	_, err = Attest(context.Background(), ref, PredicateSPDX, json.RawMessage(`foo`), signer, AttestOptions{})
+	require.ErrorIs(t, err, ErrNotAttestation)
+}
+
+// pushRandomIndex pushes an index with linux/amd64 and linux/arm64 images
+// to ref, returning the digest of the image for platform, or of the index
+// when platform is empty.
+func pushRandomIndex(t *testing.T, ref, platform string) string {
+	t.Helper()
+
+	var images []mutate.IndexAddendum
+	for _, arch := range []string{"amd64", "arm64"} {
+		img, err := random.Image(256, 1)
+		require.NoError(t, err)
+		images = append(images, mutate.IndexAddendum{
+			Add:        img,
+			Descriptor: v1.Descriptor{Platform: &v1.Platform{OS: "linux", Architecture: arch}},
+		})
+	}
+	idx := mutate.AppendManifests(empty.Index, images...)
+	tag, err := name.NewTag(ref)
+	require.NoError(t, err)
+	require.NoError(t, remote.WriteIndex(tag, idx))
+
+	if platform == "" {
+		d, err := idx.Digest()
+		require.NoError(t, err)
+		return d.String()
+	}
+
+	m, err := idx.IndexManifest()
+	require.NoError(t, err)
+	for _, d := range m.Manifests {
+		if d.Platform.String() == platform {
+			return d.Digest.String()
+		}
+	}
+	t.Fatalf("platform %s not found", platform)
+	return ""
+}
+
+func testSigner(t *testing.T) signature.SignerVerifier {
+	t.Helper()
+
+	pk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	signer, err := signature.LoadDefaultSignerVerifier(pk)
+	require.NoError(t, err)
+	return signer
+}
+
+func verifyEnvelope(t *testing.T, signer signature.Signer, envelope []byte) {
+	t.Helper()
+
+	pub, err := signer.PublicKey()
+	require.NoError(t, err)
+	verifier, err := signature.LoadVerifier(pub, crypto.SHA256)
+	require.NoError(t, err)
+	require.NoError(t, dsse.WrapVerifier(verifier).VerifySignature(bytes.NewReader(envelope), nil))
+}
@@ -0,0 +1,509 @@
+// Package attestation lists and decodes the in-toto attestations attached to
+// container images.
+package attestation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+)
+
+// Kinds of attestations, derived from their predicate type.
+const (
+	KindProvenance = "provenance"
+	KindSBOM       = "sbom"
+	KindVEX        = "vex"
+)
+
+// Sources of attestations.
+const (
+	// SourceBuildKit are attestation manifests within the image index.
+	SourceBuildKit = "buildkit"
+	// SourceCosign are DSSE envelopes attached by cosign to the .att tag.
+	SourceCosign = "cosign"
+	// SourceReferrer are Sigstore bundles or DSSE envelopes attached
+	// through the OCI referrers API.
+	SourceReferrer = "referrer"
+)
+
+// maxStatementSize limits the bytes read from each attestation layer.
+const maxStatementSize = 64 << 20
+
+var (
+	// ErrNoAttestations indicates no attestation of the requested kind was found.
+	ErrNoAttestations = errors.New("no attestations found")
+	// ErrNoPredicate indicates the statement has no predicate.
+	ErrNoPredicate = errors.New("statement has no predicate")
+)
+
+// BuildKit annotations linking attestation manifests to their image.
+const (
+	referenceTypeAnnotation   = "vnd.docker.reference.type"
+	referenceDigestAnnotation = "vnd.docker.reference.digest"
+	attestationManifestType   = "attestation-manifest"
+)
+
+// Attestation is an in-toto statement attached to an image.
+type Attestation struct {
+	// Kind is KindProvenance, KindSBOM, KindVEX or empty for other
+	// predicate types.
+	Kind string `json:"kind,omitempty"`
+	// Source is where the attestation was found: SourceBuildKit,
+	// SourceCosign or SourceReferrer.
+	Source string `json:"source,omitempty"`
+	// Platform of the attested image, such as linux/amd64. Empty when unknown.
+	Platform      string `json:"platform,omitempty"`
+	PredicateType string `json:"predicateType,omitempty"`
+	MediaType     string `json:"mediaType,omitempty"`
+	// Subject is the digest of the attested image manifest.
+	Subject   string          `json:"subject,omitempty"`
+	Statement json.RawMessage `json:"statement"`
+	// Envelope is the DSSE envelope holding the statement, with its
+	// signatures. Empty for unsigned statements.
+	Envelope json.RawMessage `json:"envelope,omitempty"`
+}
+
+// Raw returns the DSSE envelope of the attestation or, for unsigned
+// statements, the statement itself.
+func (a Attestation) Raw() json.RawMessage {
+	if len(a.Envelope) > 0 {
+		return a.Envelope
+	}
+	return a.Statement
+}
+
+// Predicate returns the predicate of the statement.
+func (a Attestation) Predicate() (json.RawMessage, error) {
+	var s struct {
+		Predicate json.RawMessage `json:"predicate"`
+	}
+	err := json.Unmarshal(a.Statement, &s)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode statement: %w", err)
+	}
+
+	if len(s.Predicate) == 0 || string(s.Predicate) == "null" {
+		return nil, ErrNoPredicate
+	}
+	return s.Predicate, nil
+}
+
+// Options tunes how attestations are fetched.
+type Options struct {
+	// Keychain used to authenticate against the registry.
+	// Defaults to authn.DefaultKeychain.
+	Keychain authn.Keychain
+}
+
+func (o Options) remoteOptions(ctx context.Context) []remote.Option {
+	keychain := o.Keychain
+	if keychain == nil {
+		keychain = authn.DefaultKeychain
+	}
+	return []remote.Option{
+		remote.WithContext(ctx),
+		remote.WithAuthFromKeychain(keychain),
+	}
+}
+
+// List returns all the attestations of the image ref: BuildKit attestation
+// manifests, cosign attestations and attestations attached as referrers.
+// Cosign and referrer attestations are looked up for the image and, on
+// multi-platform images, for each of its platform images.
+//
+// Besides registry references, ref may be a LocalRef such as oci:<dir>,
+// docker-archive:<tar> or docker-daemon:<ref>.
+func List(ctx context.Context, ref string, opts Options) ([]Attestation, error) {
+	_, atts, err := list(ctx, ref, opts)
+	return atts, err
+}
+
+// target is the image whose attestations are listed.
+type target struct {
+	desc v1.Descriptor
+	// idx is set for image indexes, img otherwise.
+	idx v1.ImageIndex
+	img v1.Image
+	// attached returns the attestations stored apart from the image for
+	// subject, such as cosign attestations and referrers.
+	attached func(ctx context.Context, subject v1.Descriptor) ([]Attestation, error)
+}
+
+// open returns the target for ref, which is looked up in a registry unless
+// it is a LocalRef.
+func open(ctx context.Context, ref string, opts Options) (*target, error) {
+	if l, ok := ParseLocalRef(ref); ok {
+		return openLocal(ctx, l)
+	}
+
+	r, err := name.ParseReference(ref)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse image reference: %w", err)
+	}
+
+	desc, err := remote.Get(r, opts.remoteOptions(ctx)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch image descriptor: %w", err)
+	}
+
+	t := &target{
+		desc: desc.Descriptor,
+		attached: func(ctx context.Context, subject v1.Descriptor) ([]Attestation, error) {
+			d := r.Context().Digest(subject.Digest.String())
+
+			cosign, err := fromCosign(ctx, d, opts)
+			if err != nil {
+				return nil, err
+			}
+
+			referrers, err := fromReferrers(ctx, d, opts)
+			if err != nil {
+				return nil, err
+			}
+			return append(cosign, referrers...), nil
+		},
+	}
+
+	if desc.MediaType.IsIndex() {
+		t.idx, err = desc.ImageIndex()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get image index: %w", err)
+		}
+	} else {
+		t.img, err = desc.Image()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get image: %w", err)
+		}
+	}
+	return t, nil
+}
+
+// list works as List, also returning the descriptor of ref.
+func list(ctx context.Context, ref string, opts Options) (v1.Descriptor, []Attestation, error) {
+	t, err := open(ctx, ref, opts)
+	if err != nil {
+		return v1.Descriptor{}, nil, err
+	}
+
+	var atts []Attestation
+	subjects := []v1.Descriptor{t.desc}
+
+	if t.idx != nil {
+		atts, err = fromIndex(t.idx)
+		if err != nil {
+			return v1.Descriptor{}, nil, err
+		}
+
+		m, err := t.idx.IndexManifest()
+		if err != nil {
+			return v1.Descriptor{}, nil, fmt.Errorf("failed to get index manifest: %w", err)
+		}
+		for _, d := range m.Manifests {
+			if d.Platform != nil && !isAttestationManifest(d) {
+				subjects = append(subjects, d)
+			}
+		}
+	} else {
+		atts, err = fromImage(t.img)
+		if err != nil {
+			return v1.Descriptor{}, nil, err
+		}
+	}
+
+	for _, subject := range subjects {
+		attached, err := t.attached(ctx, subject)
+		if err != nil {
+			return v1.Descriptor{}, nil, err
+		}
+
+		for _, a := range attached {
+			if subject.Platform != nil {
+				a.Platform = platformString(subject.Platform)
+			}
+			a.Subject = subject.Digest.String()
+			atts = append(atts, a)
+		}
+	}
+
+	return t.desc, atts, nil
+}
+
+// Fetch returns the predicate of the first attestation of kind for platform,
+// matched as Platforms.Lookup does. An empty platform matches any attestation.
+func Fetch(ctx context.Context, ref, kind, platform string, opts Options) (json.RawMessage, error) {
+	atts, err := List(ctx, ref, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	matched := Filter(atts, kind)
+	if len(matched) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNoAttestations, kind)
+	}
+	if platform == "" {
+		return matched[0].Predicate()
+	}
+
+	found, ok := ByPlatform(matched).Lookup(platform)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s for %s", ErrNoAttestations, kind, platform)
+	}
+	return found[0].Predicate()
+}
+
+// ByPlatform groups atts by platform, keeping their order.
+func ByPlatform(atts []Attestation) Platforms[[]Attestation] {
+	p := Platforms[[]Attestation]{}
+	for _, a := range atts {
+		p[a.Platform] = append(p[a.Platform], a)
+	}
+	return p
+}
+
+// Filter returns the attestations of kind.
+func Filter(atts []Attestation, kind string) []Attestation {
+	var matched []Attestation
+	for _, a := range atts {
+		if a.Kind == kind {
+			matched = append(matched, a)
+		}
+	}
+	return matched
+}
+
+// Document returns the predicates of kind keyed by platform, the layout used
+// by the download commands:
+//
+//	{"linux/amd64": {"SLSA": {...}}, "linux/arm64": {"SLSA": {...}}, "SLSA": {...}}
+//
+// SBOMs use the SPDX or CycloneDX key instead, according to their format,
+// and a platform holds both when both are attested. The top level keys hold
+// the last predicate found, for images without platform information.
+func Document(atts []Attestation, kind string) (map[string]any, error) {
+	doc := map[string]any{}
+	for _, a := range Filter(atts, kind) {
+		predicate, err := a.Predicate()
+		if err != nil {
+			continue
+		}
+
+		key := documentKey(a)
+		if a.Platform != "" {
+			entry, ok := doc[a.Platform].(map[string]any)
+			if !ok {
+				entry = map[string]any{}
+				doc[a.Platform] = entry
+			}
+			entry[key] = predicate
+		}
+		doc[key] = predicate
+	}
+
+	if len(doc) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrNoAttestations, kind)
+	}
+	return doc, nil
+}
+
+// WriteInToto writes atts to w in the in-toto JSON Lines format, one DSSE
+// envelope or unsigned statement per line. Unlike Document, it keeps the
+// statement subjects and signatures.
+func WriteInToto(w io.Writer, atts []Attestation) error {
+	for _, a := range atts {
+		var buf bytes.Buffer
+		err := json.Compact(&buf, a.Raw())
+		if err != nil {
+			return fmt.Errorf("failed to encode %s attestation: %w", a.PredicateType, err)
+		}
+		buf.WriteByte('\n')
+
+		_, err = w.Write(buf.Bytes())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func fromIndex(idx v1.ImageIndex) ([]Attestation, error) {
+	manifest, err := idx.IndexManifest()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get index manifest: %w", err)
+	}
+
+	platforms := map[string]string{}
+	for _, desc := range manifest.Manifests {
+		if desc.Platform != nil {
+			platforms[desc.Digest.String()] = platformString(desc.Platform)
+		}
+	}
+
+	var atts []Attestation
+	for _, desc := range manifest.Manifests {
+		if !isAttestationManifest(desc) {
+			continue
+		}
+
+		subject := desc.Annotations[referenceDigestAnnotation]
+		platform, ok := platforms[subject]
+		if !ok && desc.Platform != nil {
+			platform = platformString(desc.Platform)
+		}
+
+		// Attestation manifests are often left behind by partial mirrors.
+		img, err := idx.Image(desc.Digest)
+		if err != nil {
+			continue
+		}
+
+		statements, err := statements(img, SourceBuildKit)
+		if err != nil {
+			continue
+		}
+
+		for _, a := range statements {
+			a.Platform = platform
+			a.Subject = subject
+			atts = append(atts, a)
+		}
+	}
+
+	return atts, nil
+}
+
+// fromImage returns the attestations BuildKit may store in the annotations
+// of a single-platform image.
+func fromImage(img v1.Image) ([]Attestation, error) {
+	manifest, err := img.Manifest()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get manifest: %w", err)
+	}
+
+	var atts []Attestation
+	for _, key := range slices.Sorted(maps.Keys(manifest.Annotations)) {
+		value := manifest.Annotations[key]
+
+		var kind string
+		switch {
+		case strings.Contains(key, KindProvenance):
+			kind = KindProvenance
+		case strings.Contains(key, KindSBOM):
+			kind = KindSBOM
+		default:
+			continue
+		}
+
+		if !json.Valid([]byte(value)) {
+			continue
+		}
+
+		statement, err := json.Marshal(map[string]any{
+			"predicateType": key,
+			"predicate":     json.RawMessage(value),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		atts = append(atts, Attestation{
+			Kind:          kind,
+			Source:        SourceBuildKit,
+			PredicateType: key,
+			Statement:     statement,
+		})
+	}
+
+	return atts, nil
+}
+
+// statements decodes the in-toto statements held by the layers of an
+// attestation manifest. Layers that are not statements are ignored.
+func statements(img v1.Image, source string) ([]Attestation, error) {
+	layers, err := img.Layers()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get layers: %w", err)
+	}
+
+	var atts []Attestation
+	for _, layer := range layers {
+		mt, err := layer.MediaType()
+		if err != nil {
+			continue
+		}
+
+		rc, err := layer.Uncompressed()
+		if err != nil {
+			continue
+		}
+
+		data, err := io.ReadAll(io.LimitReader(rc, maxStatementSize))
+		rc.Close()
+		if err != nil {
+			continue
+		}
+
+		statement, envelope, err := decodeStatement(string(mt), data)
+		if err != nil {
+			continue
+		}
+
+		var s struct {
+			PredicateType string `json:"predicateType"`
+		}
+		if json.Unmarshal(statement, &s) != nil || s.PredicateType == "" {
+			continue
+		}
+
+		atts = append(atts, Attestation{
+			Kind:          KindOf(s.PredicateType),
+			Source:        source,
+			PredicateType: s.PredicateType,
+			MediaType:     string(mt),
+			Statement:     statement,
+			Envelope:      envelope,
+		})
+	}
+
+	return atts, nil
+}
+
+// KindOf returns the kind of attestation for predicateType.
+func KindOf(predicateType string) string {
+	switch {
+	case strings.Contains(predicateType, "slsa.dev/provenance") ||
+		strings.Contains(predicateType, "slsaprovenance"):
+		return KindProvenance
+	case strings.Contains(predicateType, "spdx") ||
+		strings.Contains(predicateType, "cyclonedx") ||
+		strings.Contains(predicateType, KindSBOM):
+		return KindSBOM
+	case strings.Contains(predicateType, "openvex"):
+		return KindVEX
+	}
+	return ""
+}
+
+func isAttestationManifest(desc v1.Descriptor) bool {
+	if desc.MediaType == "application/vnd.oci.image.manifest.v1+json" ||
+		desc.MediaType == "application/vnd.docker.distribution.manifest.v2+json" {
+		return desc.Annotations[referenceTypeAnnotation] == attestationManifestType
+	}
+	return false
+}
+
+// platformString returns p including its variant and OS version, such as
+// linux/arm/v7 or windows/amd64:10.0.20348.2340.
+func platformString(p *v1.Platform) string {
+	return p.String()
+}
@@ -0,0 +1,289 @@
+package attestation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	slsaV1      = "https://slsa.dev/provenance/v1"
+	spdx        = "https://spdx.dev/Document"
+	intotoMedia = "application/vnd.in-toto+json"
+)
+
+func TestList(t *testing.T) {
+	t.Parallel()
+
+	ref := newTestRegistry(t) + "/rancher/foo:v1"
+	idx := pushBuildKitIndex(t, ref, "amd64", "arm64")
+
+	got, err := List(context.Background(), ref, Options{})
+	require.NoError(t, err)
+	require.Len(t, got, 4)
+
+	m, err := idx.IndexManifest()
+	require.NoError(t, err)
+
+	for i, arch := range []string{"amd64", "arm64"} {
+		sbom, prov := got[i*2], got[i*2+1]
+
+		assert.Equal(t, KindSBOM, sbom.Kind)
+		assert.Equal(t, spdx, sbom.PredicateType)
+		assert.Equal(t, KindProvenance, prov.Kind)
+		assert.Equal(t, slsaV1, prov.PredicateType)
+
+		for _, a := range []Attestation{sbom, prov} {
+			assert.Equal(t, "linux/"+arch, a.Platform)
+			assert.Equal(t, intotoMedia, a.MediaType)
+			assert.Equal(t, m.Manifests[i].Digest.String(), a.Subject)
+			assert.NotEmpty(t, a.Statement)
+		}
+	}
+
+	predicate, err := got[1].Predicate()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"arch":"amd64"}`, string(predicate))
+}
+
+func TestFetch(t *testing.T) {
+	t.Parallel()
+
+	ref := newTestRegistry(t) + "/rancher/foo:v1"
+	pushBuildKitIndex(t, ref, "amd64", "arm64")
+
+	tests := []struct {
+		name     string
+		kind     string
+		platform string
+		want     string
+		wantErr  error
+	}{
+		{
+			name:     "provenance for platform",
+			kind:     KindProvenance,
+			platform: "linux/arm64",
+			want:     `{"arch":"arm64"}`,
+		},
+		{
+			name: "any platform",
+			kind: KindSBOM,
+			want: `{"spdxVersion":"SPDX-2.3","arch":"amd64"}`,
+		},
+		{
+			name:     "unknown platform",
+			kind:     KindProvenance,
+			platform: "windows/amd64",
+			wantErr:  ErrNoAttestations,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := Fetch(context.Background(), ref, tc.kind, tc.platform, Options{})
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.JSONEq(t, tc.want, string(got))
+		})
+	}
+}
+
+func TestListImageWithoutAttestations(t *testing.T) {
+	t.Parallel()
+
+	ref := newTestRegistry(t) + "/rancher/foo:v1"
+	img, err := random.Image(256, 1)
+	require.NoError(t, err)
+	require.NoError(t, crane.Push(img, ref))
+
+	got, err := List(context.Background(), ref, Options{})
+	require.NoError(t, err)
+	assert.Empty(t, got)
+
+	_, err = Document(got, KindProvenance)
+	require.ErrorIs(t, err, ErrNoAttestations)
+}
+
+func TestDocument(t *testing.T) {
+	t.Parallel()
+
+	statement := func(predicateType, predicate string) json.RawMessage {
+		return json.RawMessage(`{"predicateType":"` + predicateType + `","predicate":` + predicate + `}`)
+	}
+
+	atts := []Attestation{
+		{Kind: KindProvenance, Platform: "linux/amd64", Statement: statement(slsaV1, `{"a":1}`)},
+		{Kind: KindSBOM, Platform: "linux/amd64", Statement: statement(spdx, `{"s":1}`)},
+		{Kind: KindProvenance, Platform: "linux/arm64", Statement: statement(slsaV1, `{"a":2}`)},
+		{Kind: KindProvenance, Statement: json.RawMessage(`{"predicateType":"` + slsaV1 + `"}`)},
+	}
+
+	got, err := Document(atts, KindProvenance)
+	require.NoError(t, err)
+
+	data, err := json.Marshal(got)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"linux/amd64": {"SLSA": {"a":1}},
+		"linux/arm64": {"SLSA": {"a":2}},
+		"SLSA": {"a":2}
+	}`, string(data))
+
+	got, err = Document(atts, KindSBOM)
+	require.NoError(t, err)
+
+	data, err = json.Marshal(got)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"linux/amd64": {"SPDX": {"s":1}}, "SPDX": {"s":1}}`, string(data))
+
+	atts = append(atts, Attestation{
+		Kind:          KindSBOM,
+		Platform:      "linux/amd64",
+		PredicateType: "https://cyclonedx.org/bom",
+		Statement:     statement("https://cyclonedx.org/bom", `{"c":1}`),
+	})
+
+	got, err = Document(atts, KindSBOM)
+	require.NoError(t, err)
+
+	data, err = json.Marshal(got)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"linux/amd64": {"SPDX": {"s":1}, "CycloneDX": {"c":1}},
+		"SPDX": {"s":1},
+		"CycloneDX": {"c":1}
+	}`, string(data))
+}
+
+func TestWriteInToto(t *testing.T) {
+	t.Parallel()
+
+	atts := []Attestation{
+		{Statement: json.RawMessage(`{
+			"subject": [{"digest": {"sha256": "abc"}}],
+			"predicate": {}
+		}`)},
+		{
+			Statement: json.RawMessage(`{"predicate":{}}`),
+			Envelope:  json.RawMessage(`{"payload":"e30=", "signatures":[{"sig":"c2ln"}]}`),
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteInToto(&buf, atts))
+	assert.Equal(t, `{"subject":[{"digest":{"sha256":"abc"}}],"predicate":{}}`+"\n"+
+		`{"payload":"e30=","signatures":[{"sig":"c2ln"}]}`+"\n", buf.String())
+}
+
+func TestKindOf(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		"https://slsa.dev/provenance/v0.2":                KindProvenance,
+		"https://slsa.dev/provenance/v1":                  KindProvenance,
+		"https://spdx.dev/Document":                       KindSBOM,
+		"https://cyclonedx.org/bom":                       KindSBOM,
+		"https://openvex.dev/ns/v0.2.0":                   KindVEX,
+		"https://cosign.sigstore.dev/attestation/vuln/v1": "",
+	}
+
+	for predicateType, want := range tests {
+		assert.Equal(t, want, KindOf(predicateType), predicateType)
+	}
+}
+
+func newTestRegistry(t *testing.T) string {
+	t.Helper()
+
+	srv := httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", 0))))
+	t.Cleanup(srv.Close)
+
+	return strings.TrimPrefix(srv.URL, "http://")
+}
+
+// pushBuildKitIndex pushes an index to ref laid out as BuildKit does, with
+// an SBOM and a provenance attestation manifest for each linux platform.
+func pushBuildKitIndex(t *testing.T, ref string, archs ...string) v1.ImageIndex {
+	t.Helper()
+
+	idx := buildKitIndex(t, archs...)
+
+	tag, err := name.NewTag(ref)
+	require.NoError(t, err)
+	require.NoError(t, remote.WriteIndex(tag, idx))
+
+	return idx
+}
+
+// buildKitIndex returns an index laid out as BuildKit does.
+func buildKitIndex(t *testing.T, archs ...string) v1.ImageIndex {
+	t.Helper()
+
+	var images, attestations []mutate.IndexAddendum
+	for _, arch := range archs {
+		img, err := random.Image(256, 1)
+		require.NoError(t, err)
+
+		digest, err := img.Digest()
+		require.NoError(t, err)
+
+		images = append(images, mutate.IndexAddendum{
+			Add: img,
+			Descriptor: v1.Descriptor{
+				Platform: &v1.Platform{OS: "linux", Architecture: arch},
+			},
+		})
+
+		att := mutate.MediaType(empty.Image, types.OCIManifestSchema1)
+		att, err = mutate.AppendLayers(att,
+			statementLayer(t, spdx, `{"spdxVersion":"SPDX-2.3","arch":"`+arch+`"}`),
+			statementLayer(t, slsaV1, `{"arch":"`+arch+`"}`))
+		require.NoError(t, err)
+
+		attestations = append(attestations, mutate.IndexAddendum{
+			Add: att,
+			Descriptor: v1.Descriptor{
+				MediaType: types.OCIManifestSchema1,
+				Platform:  &v1.Platform{OS: "unknown", Architecture: "unknown"},
+				Annotations: map[string]string{
+					referenceTypeAnnotation:   attestationManifestType,
+					referenceDigestAnnotation: digest.String(),
+				},
+			},
+		})
+	}
+
+	return mutate.AppendManifests(empty.Index, append(images, attestations...)...)
+}
+
+func statementLayer(t *testing.T, predicateType, predicate string) v1.Layer {
+	t.Helper()
+
+	statement := `{"_type":"https://in-toto.io/Statement/v0.1","predicateType":"` +
+		predicateType + `","subject":[],"predicate":` + predicate + `}`
+	return static.NewLayer([]byte(statement), intotoMedia)
+}
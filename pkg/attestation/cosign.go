@@ -0,0 +1,89 @@
+package attestation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+// fromCosign returns the attestations cosign attached to the .att tag of
+// the image digest.
+func fromCosign(ctx context.Context, digest name.Digest, opts Options) ([]Attestation, error) {
+	tag := digest.Context().Tag(strings.Replace(digest.DigestStr(), ":", "-", 1) + ".att")
+
+	img, err := remote.Image(tag, opts.remoteOptions(ctx)...)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to fetch attestations %q: %w", tag, err)
+	}
+
+	return statements(img, SourceCosign)
+}
+
+// fromReferrers returns the attestations attached to the image digest
+// through the OCI referrers API.
+func fromReferrers(ctx context.Context, digest name.Digest, opts Options) ([]Attestation, error) {
+	referrers, err := Referrers(digest, opts.remoteOptions(ctx)...)
+	if err != nil {
+		return nil, err
+	}
+
+	var atts []Attestation
+	for _, r := range referrers {
+		if r.MediaType.IsIndex() {
+			continue
+		}
+
+		img, err := remote.Image(digest.Context().Digest(r.Digest.String()), opts.remoteOptions(ctx)...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch referrer %s: %w", r.Digest, err)
+		}
+
+		found, err := statements(img, SourceReferrer)
+		if err != nil {
+			return nil, err
+		}
+		atts = append(atts, found...)
+	}
+
+	return atts, nil
+}
+
+// Referrers returns the descriptors of the manifests referring to subject.
+// On registries without the referrers API, the sha256-<hex> fallback tag is
+// used instead, unless it holds a cosign signature.
+func Referrers(subject name.Digest, opts ...remote.Option) ([]v1.Descriptor, error) {
+	idx, err := remote.Referrers(subject, opts...)
+	if err != nil {
+		// Cosign also uses the fallback tag for signatures in the new format.
+		fallback := subject.Context().Tag(strings.Replace(subject.DigestStr(), ":", "-", 1))
+		desc, herr := remote.Head(fallback, opts...)
+		if herr == nil && !desc.MediaType.IsIndex() {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to list referrers of %q: %w", subject, err)
+	}
+
+	m, err := idx.IndexManifest()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read referrers of %q: %w", subject, err)
+	}
+	return m.Manifests, nil
+}
+
+func isNotFound(err error) bool {
+	var terr *transport.Error
+	if errors.As(err, &terr) {
+		return terr.StatusCode == http.StatusNotFound
+	}
+	return false
+}
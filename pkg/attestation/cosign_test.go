@@ -0,0 +1,213 @@
+package attestation
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const bundleMedia = "application/vnd.dev.sigstore.bundle.v0.3+json"
+
+func TestListCosign(t *testing.T) {
+	t.Parallel()
+
+	ref := newTestRegistry(t) + "/rancher/foo:v1"
+	repo, err := name.NewRepository(strings.TrimSuffix(ref, ":v1"))
+	require.NoError(t, err)
+
+	img, err := random.Image(256, 1)
+	require.NoError(t, err)
+	require.NoError(t, crane.Push(img, ref))
+
+	digest, err := img.Digest()
+	require.NoError(t, err)
+
+	// cosign attest: DSSE envelope in the .att tag.
+	att, err := mutate.AppendLayers(empty.Image,
+		static.NewLayer(envelope(t, slsaV1, `{"from":"cosign"}`), MediaTypeDSSE))
+	require.NoError(t, err)
+	require.NoError(t, crane.Push(att, repo.Tag(tagPrefix(digest)+".att").String()))
+
+	// cosign sign in the new format uses the referrers fallback tag.
+	pushRandom(t, repo.Tag(tagPrefix(digest)).String())
+
+	got, err := List(context.Background(), ref, Options{})
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+
+	assert.Equal(t, SourceCosign, got[0].Source)
+	assert.Equal(t, KindProvenance, got[0].Kind)
+	assert.Equal(t, MediaTypeDSSE, got[0].MediaType)
+	assert.Equal(t, digest.String(), got[0].Subject)
+	assert.JSONEq(t, string(envelope(t, slsaV1, `{"from":"cosign"}`)), string(got[0].Raw()))
+
+	predicate, err := got[0].Predicate()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"from":"cosign"}`, string(predicate))
+}
+
+func TestListReferrers(t *testing.T) {
+	t.Parallel()
+
+	ref := newTestRegistry(t) + "/rancher/foo:v1"
+	repo, err := name.NewRepository(strings.TrimSuffix(ref, ":v1"))
+	require.NoError(t, err)
+
+	idx := pushBuildKitIndex(t, ref, "amd64")
+	m, err := idx.IndexManifest()
+	require.NoError(t, err)
+
+	desc, err := remote.Head(repo.Digest(m.Manifests[0].Digest.String()))
+	require.NoError(t, err)
+
+	pushReferrer(t, repo, *desc, bundle(t, spdx, `{"from":"referrer"}`))
+	pushReferrer(t, repo, *desc, []byte(`{"messageSignature":{}}`))
+
+	got, err := List(context.Background(), ref, Options{})
+	require.NoError(t, err)
+
+	sources := map[string]int{}
+	for _, a := range got {
+		sources[a.Source]++
+	}
+	assert.Equal(t, map[string]int{SourceBuildKit: 2, SourceReferrer: 1}, sources)
+
+	a := got[len(got)-1]
+	assert.Equal(t, SourceReferrer, a.Source)
+	assert.Equal(t, KindSBOM, a.Kind)
+	assert.Equal(t, "linux/amd64", a.Platform)
+	assert.Equal(t, m.Manifests[0].Digest.String(), a.Subject)
+
+	predicate, err := a.Predicate()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"from":"referrer"}`, string(predicate))
+}
+
+func TestDecodeStatement(t *testing.T) {
+	t.Parallel()
+
+	statement := `{"predicateType":"` + slsaV1 + `","predicate":{}}`
+
+	tests := []struct {
+		name      string
+		mediaType string
+		data      []byte
+		signed    bool
+		wantErr   error
+	}{
+		{
+			name:      "in-toto statement",
+			mediaType: MediaTypeInToto,
+			data:      []byte(statement),
+		},
+		{
+			name:      "DSSE envelope",
+			mediaType: MediaTypeDSSE,
+			data:      envelope(t, slsaV1, `{}`),
+			signed:    true,
+		},
+		{
+			name:      "Sigstore bundle",
+			mediaType: bundleMedia,
+			data:      bundle(t, slsaV1, `{}`),
+			signed:    true,
+		},
+		{
+			name:      "Sigstore bundle without envelope",
+			mediaType: bundleMedia,
+			data:      []byte(`{"messageSignature":{}}`),
+			wantErr:   ErrNotAttestation,
+		},
+		{
+			name:      "DSSE envelope with other payload",
+			mediaType: MediaTypeDSSE,
+			data:      []byte(`{"payloadType":"text/plain","payload":"Zm9v"}`),
+			wantErr:   ErrUnsupportedPayloadType,
+		},
+		{
+			name:      "not JSON",
+			mediaType: "application/octet-stream",
+			data:      []byte("foo"),
+			wantErr:   ErrNotAttestation,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, env, err := decodeStatement(tc.mediaType, tc.data)
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.JSONEq(t, statement, string(got))
+
+			if !tc.signed {
+				assert.Empty(t, env)
+				return
+			}
+			assert.JSONEq(t, string(envelope(t, slsaV1, `{}`)), string(env))
+		})
+	}
+}
+
+func envelope(t *testing.T, predicateType, predicate string) []byte {
+	t.Helper()
+
+	statement := `{"predicateType":"` + predicateType + `","predicate":` + predicate + `}`
+	data, err := json.Marshal(Envelope{
+		PayloadType: MediaTypeInToto,
+		Payload:     base64.StdEncoding.EncodeToString([]byte(statement)),
+	})
+	require.NoError(t, err)
+	return data
+}
+
+func bundle(t *testing.T, predicateType, predicate string) []byte {
+	t.Helper()
+
+	return []byte(`{"mediaType":"` + bundleMedia + `","dsseEnvelope":` +
+		string(envelope(t, predicateType, predicate)) + `}`)
+}
+
+func pushReferrer(t *testing.T, repo name.Repository, subject v1.Descriptor, content []byte) {
+	t.Helper()
+
+	img, err := mutate.AppendLayers(mutate.MediaType(empty.Image, types.OCIManifestSchema1),
+		static.NewLayer(content, bundleMedia))
+	require.NoError(t, err)
+
+	referrer := mutate.Subject(mutate.ConfigMediaType(img, bundleMedia), subject).(v1.Image)
+	digest, err := referrer.Digest()
+	require.NoError(t, err)
+	require.NoError(t, remote.Write(repo.Digest(digest.String()), referrer))
+}
+
+func pushRandom(t *testing.T, ref string) {
+	t.Helper()
+
+	img, err := random.Image(256, 1)
+	require.NoError(t, err)
+	require.NoError(t, crane.Push(img, ref))
+}
+
+func tagPrefix(digest v1.Hash) string {
+	return strings.Replace(digest.String(), ":", "-", 1)
+}
@@ -0,0 +1,97 @@
+package attestation
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Media types of the layers holding attestations.
+const (
+	MediaTypeInToto         = "application/vnd.in-toto+json"
+	MediaTypeDSSE           = "application/vnd.dsse.envelope.v1+json"
+	MediaTypeSigstoreBundle = "application/vnd.dev.sigstore.bundle"
+)
+
+var (
+	// ErrNotAttestation indicates the content holds no in-toto statement.
+	ErrNotAttestation = errors.New("content is not an attestation")
+	// ErrUnsupportedPayloadType indicates a DSSE envelope not carrying in-toto.
+	ErrUnsupportedPayloadType = errors.New("unsupported DSSE payload type")
+)
+
+// Envelope is a DSSE envelope, as used by cosign and Sigstore bundles.
+type Envelope struct {
+	PayloadType string `json:"payloadType"`
+	Payload     string `json:"payload"`
+	Signatures  []struct {
+		KeyID string `json:"keyid"`
+		Sig   string `json:"sig"`
+	} `json:"signatures"`
+}
+
+// Statement returns the in-toto statement within the envelope. The
+// signatures are not verified.
+func (e Envelope) Statement() (json.RawMessage, error) {
+	if e.PayloadType != MediaTypeInToto {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedPayloadType, e.PayloadType)
+	}
+
+	payload, err := base64.StdEncoding.DecodeString(e.Payload)
+	if err != nil {
+		payload, err = base64.URLEncoding.DecodeString(e.Payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode DSSE payload: %w", err)
+		}
+	}
+
+	if !json.Valid(payload) {
+		return nil, fmt.Errorf("%w: DSSE payload is not JSON", ErrNotAttestation)
+	}
+	return payload, nil
+}
+
+// decodeStatement returns the in-toto statement held by data, which may be a
+// plain statement, a DSSE envelope or a Sigstore bundle. The DSSE envelope
+// is also returned, as is, when there is one.
+func decodeStatement(mediaType string, data []byte) (statement, envelope json.RawMessage, err error) {
+	switch {
+	case strings.HasPrefix(mediaType, MediaTypeSigstoreBundle):
+		var bundle struct {
+			DSSEEnvelope json.RawMessage `json:"dsseEnvelope"`
+		}
+		err = json.Unmarshal(data, &bundle)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to decode Sigstore bundle: %w", err)
+		}
+
+		// Bundles of plain signatures carry no statement.
+		if len(bundle.DSSEEnvelope) == 0 || string(bundle.DSSEEnvelope) == "null" {
+			return nil, nil, ErrNotAttestation
+		}
+		envelope = bundle.DSSEEnvelope
+
+	case mediaType == MediaTypeDSSE:
+		envelope = data
+
+	default:
+		if !json.Valid(data) {
+			return nil, nil, ErrNotAttestation
+		}
+		return data, nil, nil
+	}
+
+	var env Envelope
+	err = json.Unmarshal(envelope, &env)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decode DSSE envelope: %w", err)
+	}
+
+	statement, err = env.Statement()
+	if err != nil {
+		return nil, nil, err
+	}
+	return statement, envelope, nil
+}
@@ -0,0 +1,202 @@
+package attestation
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ManifestFile is the name of the index written by Export.
+const ManifestFile = "manifest.json"
+
+// Contents of the files written by Export.
+const (
+	ContentPredicate = "predicate"
+	ContentStatement = "statement"
+)
+
+// ExportManifest indexes the files written by Export.
+type ExportManifest struct {
+	Image string `json:"image"`
+	// Digest of the image manifest or index.
+	Digest string         `json:"digest"`
+	Files  []ExportedFile `json:"files"`
+}
+
+// ExportedFile describes a file written by Export.
+type ExportedFile struct {
+	// Path relative to the output directory.
+	Path          string `json:"path"`
+	Content       string `json:"content"`
+	Platform      string `json:"platform,omitempty"`
+	Kind          string `json:"kind,omitempty"`
+	PredicateType string `json:"predicateType"`
+	Source        string `json:"source"`
+	// Subject is the digest of the attested image manifest.
+	Subject string `json:"subject,omitempty"`
+	// SHA256 of the file content.
+	SHA256 string `json:"sha256"`
+}
+
+// Export writes all the attestations of ref into dir, split by platform:
+//
+//	<dir>/<platform>/provenance.json
+//	<dir>/<platform>/sbom.spdx.json (or sbom.cyclonedx.json)
+//	<dir>/<platform>/statements/<sha256>.intoto.json
+//	<dir>/manifest.json
+//
+// Attestations without platform information are written to the unknown
+// directory. When several attestations of a kind exist for a platform, the
+// predicate of the first one is written, while all the raw statements are
+// kept.
+func Export(ctx context.Context, ref, dir string, opts Options) (*ExportManifest, error) {
+	desc, atts, err := list(ctx, ref, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(atts) == 0 {
+		return nil, ErrNoAttestations
+	}
+
+	m := &ExportManifest{
+		Image:  ref,
+		Digest: desc.Digest.String(),
+	}
+
+	written := map[string]bool{}
+	for _, a := range atts {
+		platformDir := platformPath(a.Platform)
+
+		// Statements are kept byte for byte, as signed.
+		statement := []byte(a.Statement)
+		sum := sha256.Sum256(statement)
+		path := filepath.Join(platformDir, "statements", hex.EncodeToString(sum[:])+".intoto.json")
+		if !written[path] {
+			err = m.write(dir, path, ContentStatement, statement, a)
+			if err != nil {
+				return nil, err
+			}
+			written[path] = true
+		}
+
+		name := predicateFile(a)
+		if name == "" {
+			continue
+		}
+
+		path = filepath.Join(platformDir, name)
+		if written[path] {
+			continue
+		}
+
+		predicate, err := a.Predicate()
+		if err != nil {
+			continue
+		}
+
+		predicate, err = indentJSON(predicate)
+		if err != nil {
+			return nil, fmt.Errorf("failed to format %s predicate: %w", a.PredicateType, err)
+		}
+
+		err = m.write(dir, path, ContentPredicate, predicate, a)
+		if err != nil {
+			return nil, err
+		}
+		written[path] = true
+	}
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+
+	err = os.WriteFile(filepath.Join(dir, ManifestFile), append(data, '\n'), 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	return m, nil
+}
+
+func (m *ExportManifest) write(dir, path, content string, data []byte, a Attestation) error {
+	full := filepath.Join(dir, path)
+	err := os.MkdirAll(filepath.Dir(full), 0o755)
+	if err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	err = os.WriteFile(full, data, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	sum := sha256.Sum256(data)
+	m.Files = append(m.Files, ExportedFile{
+		Path:          filepath.ToSlash(path),
+		Content:       content,
+		Platform:      a.Platform,
+		Kind:          a.Kind,
+		PredicateType: a.PredicateType,
+		Source:        a.Source,
+		Subject:       a.Subject,
+		SHA256:        hex.EncodeToString(sum[:]),
+	})
+	return nil
+}
+
+// predicateFile returns the file name for the predicate of a, or empty for
+// kinds that are only kept as raw statements.
+func predicateFile(a Attestation) string {
+	switch a.Kind {
+	case KindProvenance:
+		return "provenance.json"
+	case KindSBOM:
+		if a.SBOMFormat() == FormatCycloneDX {
+			return "sbom.cyclonedx.json"
+		}
+		return "sbom.spdx.json"
+	case KindVEX:
+		return "vex.openvex.json"
+	}
+	return ""
+}
+
+var unsafePathChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// platformPath returns the relative directory for platform, such as
+// linux/arm/v7 or windows/amd64-10.0.20348.2340. Platforms come from the
+// registry, so each element is sanitised to stay within the output directory.
+func platformPath(platform string) string {
+	var elems []string
+	for e := range strings.SplitSeq(strings.ReplaceAll(platform, ":", "-"), "/") {
+		e = unsafePathChars.ReplaceAllString(e, "_")
+		if e == "" || strings.Trim(e, ".") == "" {
+			continue
+		}
+		elems = append(elems, e)
+	}
+
+	if len(elems) == 0 {
+		return "unknown"
+	}
+	return filepath.Join(elems...)
+}
+
+func indentJSON(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	err := json.Indent(&buf, data, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
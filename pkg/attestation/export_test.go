@@ -0,0 +1,75 @@
+package attestation
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExport(t *testing.T) {
+	t.Parallel()
+
+	ref := newTestRegistry(t) + "/rancher/foo:v1"
+	idx := pushBuildKitIndex(t, ref, "amd64", "arm64")
+	dir := t.TempDir()
+
+	got, err := Export(context.Background(), ref, dir, Options{})
+	require.NoError(t, err)
+
+	digest, err := idx.Digest()
+	require.NoError(t, err)
+	assert.Equal(t, digest.String(), got.Digest)
+	assert.Equal(t, ref, got.Image)
+
+	// Per platform: two statements, one provenance and one SBOM.
+	require.Len(t, got.Files, 8)
+
+	for _, arch := range []string{"amd64", "arm64"} {
+		data, err := os.ReadFile(filepath.Join(dir, "linux", arch, "provenance.json"))
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"arch":"`+arch+`"}`, string(data))
+
+		data, err = os.ReadFile(filepath.Join(dir, "linux", arch, "sbom.spdx.json"))
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"spdxVersion":"SPDX-2.3","arch":"`+arch+`"}`, string(data))
+
+		statements, err := filepath.Glob(filepath.Join(dir, "linux", arch, "statements", "*.intoto.json"))
+		require.NoError(t, err)
+		assert.Len(t, statements, 2)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	require.NoError(t, err)
+
+	var m ExportManifest
+	require.NoError(t, json.Unmarshal(data, &m))
+	assert.Equal(t, *got, m)
+
+	for _, f := range m.Files {
+		assert.NotEmpty(t, f.SHA256, f.Path)
+		assert.FileExists(t, filepath.Join(dir, filepath.FromSlash(f.Path)))
+	}
+}
+
+func TestPlatformPath(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{
+		"linux/amd64":                   filepath.Join("linux", "amd64"),
+		"linux/arm/v7":                  filepath.Join("linux", "arm", "v7"),
+		"windows/amd64:10.0.20348.3207": filepath.Join("windows", "amd64-10.0.20348.3207"),
+		"":                              "unknown",
+		"../../etc":                     "etc",
+		"linux/..":                      "linux",
+		`linux\..\amd64`:                "linux_.._amd64",
+	}
+
+	for platform, want := range tests {
+		assert.Equal(t, want, platformPath(platform), platform)
+	}
+}
@@ -0,0 +1,322 @@
+package attestation
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/partial"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+)
+
+// maxManifestSize limits the bytes read from index.json, manifests and
+// config files of OCI image layouts.
+const maxManifestSize = 8 << 20
+
+// ErrDigestMismatch indicates a layout blob does not match its digest.
+var ErrDigestMismatch = errors.New("digest mismatch")
+
+// blobOpener opens the files of an OCI image layout, such as index.json or
+// blobs/sha256/<hex>. Missing files return fs.ErrNotExist.
+type blobOpener func(name string) (io.ReadCloser, error)
+
+// dirBlobs opens the files of the layout in dir.
+func dirBlobs(dir string) blobOpener {
+	return func(name string) (io.ReadCloser, error) {
+		return os.Open(filepath.Join(dir, filepath.FromSlash(name)))
+	}
+}
+
+// tarBlobs opens the files of the layout within the tarball at p. The
+// tarball is scanned once, and each file is then read from its offset.
+func tarBlobs(p string) (blobOpener, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer f.Close()
+
+	type entry struct {
+		offset int64
+		size   int64
+	}
+
+	entries := map[string]entry{}
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		offset, err := f.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive: %w", err)
+		}
+		entries[path.Clean(hdr.Name)] = entry{offset: offset, size: hdr.Size}
+	}
+
+	return func(name string) (io.ReadCloser, error) {
+		e, ok := entries[name]
+		if !ok {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+		}
+
+		f, err := os.Open(p)
+		if err != nil {
+			return nil, err
+		}
+		return struct {
+			io.Reader
+			io.Closer
+		}{io.NewSectionReader(f, e.offset, e.size), f}, nil
+	}, nil
+}
+
+// layoutIndex is an image index read from an OCI image layout.
+type layoutIndex struct {
+	blobs     blobOpener
+	raw       []byte
+	mediaType types.MediaType
+	manifest  *v1.IndexManifest
+}
+
+var _ v1.ImageIndex = (*layoutIndex)(nil)
+
+// readLayout reads the index.json of the layout.
+func readLayout(blobs blobOpener) (*layoutIndex, error) {
+	rc, err := blobs("index.json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open OCI layout: %w", err)
+	}
+	defer rc.Close()
+
+	raw, err := io.ReadAll(io.LimitReader(rc, maxManifestSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read OCI layout: %w", err)
+	}
+
+	return newLayoutIndex(blobs, raw, types.OCIImageIndex)
+}
+
+func newLayoutIndex(blobs blobOpener, raw []byte, mediaType types.MediaType) (*layoutIndex, error) {
+	m, err := v1.ParseIndexManifest(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse index: %w", err)
+	}
+
+	if m.MediaType != "" {
+		mediaType = m.MediaType
+	}
+	return &layoutIndex{blobs: blobs, raw: raw, mediaType: mediaType, manifest: m}, nil
+}
+
+func (l *layoutIndex) MediaType() (types.MediaType, error) {
+	return l.mediaType, nil
+}
+
+func (l *layoutIndex) Digest() (v1.Hash, error) {
+	return partial.Digest(l)
+}
+
+func (l *layoutIndex) Size() (int64, error) {
+	return partial.Size(l)
+}
+
+func (l *layoutIndex) RawManifest() ([]byte, error) {
+	return l.raw, nil
+}
+
+func (l *layoutIndex) IndexManifest() (*v1.IndexManifest, error) {
+	return l.manifest.DeepCopy(), nil
+}
+
+func (l *layoutIndex) Image(h v1.Hash) (v1.Image, error) {
+	desc, err := l.descriptor(h)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := readBlob(l.blobs, h)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := v1.ParseManifest(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+
+	return partial.CompressedToImage(&layoutImage{
+		blobs:     l.blobs,
+		raw:       raw,
+		mediaType: desc.MediaType,
+		manifest:  m,
+	})
+}
+
+func (l *layoutIndex) ImageIndex(h v1.Hash) (v1.ImageIndex, error) {
+	desc, err := l.descriptor(h)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := readBlob(l.blobs, h)
+	if err != nil {
+		return nil, err
+	}
+	return newLayoutIndex(l.blobs, raw, desc.MediaType)
+}
+
+func (l *layoutIndex) descriptor(h v1.Hash) (v1.Descriptor, error) {
+	for _, d := range l.manifest.Manifests {
+		if d.Digest == h {
+			return d, nil
+		}
+	}
+	return v1.Descriptor{}, fmt.Errorf("%w: %s", ErrImageNotFound, h)
+}
+
+// attachments returns the attestations of subject saved in the layout: the
+// cosign attestations of the image saved by cosign save, those named after
+// the .att tag of subject and referrers of subject.
+func (l *layoutIndex) attachments(image, subject v1.Descriptor) ([]Attestation, error) {
+	att := strings.Replace(subject.Digest.String(), ":", "-", 1) + ".att"
+
+	var atts []Attestation
+	for _, d := range l.manifest.Manifests {
+		if d.MediaType.IsIndex() || d.Digest == image.Digest {
+			continue
+		}
+
+		source := SourceReferrer
+		if d.Annotations[refNameAnnotation] == att ||
+			(d.Annotations[cosignKindAnnotation] == cosignAttsKind && subject.Digest == image.Digest) {
+			source = SourceCosign
+		}
+
+		img, err := l.Image(d.Digest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", d.Digest, err)
+		}
+
+		if source == SourceReferrer {
+			m, err := img.Manifest()
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", d.Digest, err)
+			}
+			if m.Subject == nil || m.Subject.Digest != subject.Digest {
+				continue
+			}
+		}
+
+		found, err := statements(img, source)
+		if err != nil {
+			return nil, err
+		}
+		atts = append(atts, found...)
+	}
+
+	return atts, nil
+}
+
+// layoutImage is an image read from an OCI image layout.
+type layoutImage struct {
+	blobs     blobOpener
+	raw       []byte
+	mediaType types.MediaType
+	manifest  *v1.Manifest
+}
+
+var _ partial.CompressedImageCore = (*layoutImage)(nil)
+
+func (i *layoutImage) MediaType() (types.MediaType, error) {
+	if i.manifest.MediaType != "" {
+		return i.manifest.MediaType, nil
+	}
+	return i.mediaType, nil
+}
+
+func (i *layoutImage) RawManifest() ([]byte, error) {
+	return i.raw, nil
+}
+
+func (i *layoutImage) RawConfigFile() ([]byte, error) {
+	return readBlob(i.blobs, i.manifest.Config.Digest)
+}
+
+func (i *layoutImage) LayerByDigest(h v1.Hash) (partial.CompressedLayer, error) {
+	if i.manifest.Config.Digest == h {
+		return &layoutBlob{blobs: i.blobs, desc: i.manifest.Config}, nil
+	}
+
+	for _, d := range i.manifest.Layers {
+		if d.Digest == h {
+			return &layoutBlob{blobs: i.blobs, desc: d}, nil
+		}
+	}
+	return nil, fmt.Errorf("layer %s not found", h)
+}
+
+// layoutBlob is a layer read from an OCI image layout.
+type layoutBlob struct {
+	blobs blobOpener
+	desc  v1.Descriptor
+}
+
+func (b *layoutBlob) Digest() (v1.Hash, error) {
+	return b.desc.Digest, nil
+}
+
+func (b *layoutBlob) Size() (int64, error) {
+	return b.desc.Size, nil
+}
+
+func (b *layoutBlob) MediaType() (types.MediaType, error) {
+	return b.desc.MediaType, nil
+}
+
+func (b *layoutBlob) Compressed() (io.ReadCloser, error) {
+	return b.blobs(blobPath(b.desc.Digest))
+}
+
+// readBlob reads and verifies a manifest or config blob.
+func readBlob(blobs blobOpener, h v1.Hash) ([]byte, error) {
+	rc, err := blobs(blobPath(h))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open blob: %w", err)
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(io.LimitReader(rc, maxManifestSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read blob %s: %w", h, err)
+	}
+
+	got, _, err := v1.SHA256(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if h.Algorithm == got.Algorithm && got != h {
+		return nil, fmt.Errorf("%w: %s", ErrDigestMismatch, h)
+	}
+	return data, nil
+}
+
+func blobPath(h v1.Hash) string {
+	return path.Join("blobs", h.Algorithm, h.Hex)
+}
@@ -0,0 +1,243 @@
+package attestation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/daemon"
+	"github.com/google/go-containerregistry/pkg/v1/partial"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+)
+
+// Transports of images outside registries.
+const (
+	// TransportOCI is an OCI image layout directory: oci:<dir>[:<ref>].
+	TransportOCI = "oci"
+	// TransportDockerArchive is a docker save tarball:
+	// docker-archive:<tar>[:<ref>].
+	TransportDockerArchive = "docker-archive"
+	// TransportDockerDaemon is an image in the Docker daemon:
+	// docker-daemon:<ref>.
+	TransportDockerDaemon = "docker-daemon"
+)
+
+var (
+	// ErrImageNotFound indicates no image in the layout matches the reference.
+	ErrImageNotFound = errors.New("image not found")
+	// ErrAmbiguousImage indicates the layout holds several images and the
+	// reference does not select one of them.
+	ErrAmbiguousImage = errors.New("multiple images found, select one with <transport>:<path>:<ref>")
+)
+
+// Annotations naming the images of an OCI image layout.
+const (
+	refNameAnnotation        = "org.opencontainers.image.ref.name"
+	containerdNameAnnotation = "io.containerd.image.name"
+)
+
+// Annotations set by cosign save on the manifests of a layout.
+const (
+	cosignKindAnnotation = "kind"
+	cosignSigsKind       = "dev.cosignproject.cosign/sigs"
+	cosignAttsKind       = "dev.cosignproject.cosign/atts"
+)
+
+// LocalRef references an image outside registries.
+type LocalRef struct {
+	// Transport is TransportOCI, TransportDockerArchive or TransportDockerDaemon.
+	Transport string
+	// Path to the OCI layout or docker archive. Empty for the Docker daemon.
+	Path string
+	// Reference selects the image within the layout or archive, such as
+	// rancher/foo:v1 or v1, and names the image in the Docker daemon.
+	Reference string
+}
+
+// ParseLocalRef parses references to images outside registries, such as
+// oci:<dir>[:<ref>], docker-archive:<tar>[:<ref>] and docker-daemon:<ref>.
+// It returns false for any other reference.
+func ParseLocalRef(ref string) (LocalRef, bool) {
+	transport, rest, ok := strings.Cut(ref, ":")
+	if !ok {
+		return LocalRef{}, false
+	}
+
+	switch transport {
+	case TransportOCI, TransportDockerArchive:
+		path, reference, _ := strings.Cut(rest, ":")
+		return LocalRef{Transport: transport, Path: path, Reference: reference}, path != ""
+	case TransportDockerDaemon:
+		return LocalRef{Transport: transport, Reference: rest}, rest != ""
+	}
+	return LocalRef{}, false
+}
+
+func openLocal(ctx context.Context, l LocalRef) (*target, error) {
+	switch l.Transport {
+	case TransportOCI:
+		return openLayout(dirBlobs(l.Path), l.Reference)
+
+	case TransportDockerArchive:
+		blobs, err := tarBlobs(l.Path)
+		if err != nil {
+			return nil, err
+		}
+
+		// Since Docker 25, docker save writes an OCI image layout, which
+		// keeps the image index and its attestation manifests.
+		t, err := openLayout(blobs, l.Reference)
+		if !errors.Is(err, fs.ErrNotExist) {
+			return t, err
+		}
+
+		var tag *name.Tag
+		if l.Reference != "" {
+			t, err := name.NewTag(l.Reference, name.WeakValidation)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse image reference: %w", err)
+			}
+			tag = &t
+		}
+
+		img, err := tarball.ImageFromPath(l.Path, tag)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read docker archive: %w", err)
+		}
+		return imageTarget(img)
+
+	case TransportDockerDaemon:
+		r, err := name.ParseReference(l.Reference)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse image reference: %w", err)
+		}
+
+		img, err := daemon.Image(r, daemon.WithContext(ctx))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read image from Docker daemon: %w", err)
+		}
+		return imageTarget(img)
+	}
+
+	return nil, fmt.Errorf("unsupported transport %q", l.Transport)
+}
+
+// imageTarget returns a target for images that cannot hold attachments.
+func imageTarget(img v1.Image) (*target, error) {
+	desc, err := partial.Descriptor(img)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get image descriptor: %w", err)
+	}
+
+	return &target{
+		desc: *desc,
+		img:  img,
+		attached: func(context.Context, v1.Descriptor) ([]Attestation, error) {
+			return nil, nil
+		},
+	}, nil
+}
+
+func openLayout(blobs blobOpener, ref string) (*target, error) {
+	idx, err := readLayout(blobs)
+	if err != nil {
+		return nil, err
+	}
+
+	desc, err := selectImage(idx.manifest.Manifests, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &target{
+		desc: desc,
+		attached: func(_ context.Context, subject v1.Descriptor) ([]Attestation, error) {
+			return idx.attachments(desc, subject)
+		},
+	}
+
+	if desc.MediaType.IsIndex() {
+		t.idx, err = idx.ImageIndex(desc.Digest)
+	} else {
+		t.img, err = idx.Image(desc.Digest)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", desc.Digest, err)
+	}
+	return t, nil
+}
+
+// selectImage returns the image of the layout named ref. An empty ref
+// selects the only image of the layout.
+func selectImage(manifests []v1.Descriptor, ref string) (v1.Descriptor, error) {
+	var named, unnamed []v1.Descriptor
+	seen := map[v1.Hash]bool{}
+	for _, d := range manifests {
+		if seen[d.Digest] || isLayoutAttachment(d) {
+			continue
+		}
+		if ref != "" && !matchesRef(d, ref) {
+			continue
+		}
+
+		seen[d.Digest] = true
+		if d.Annotations[refNameAnnotation] != "" || d.Annotations[containerdNameAnnotation] != "" {
+			named = append(named, d)
+		} else {
+			unnamed = append(unnamed, d)
+		}
+	}
+
+	// Unnamed manifests are often referrers of the named ones.
+	candidates := named
+	if len(candidates) == 0 {
+		candidates = unnamed
+	}
+
+	switch len(candidates) {
+	case 0:
+		if ref == "" {
+			return v1.Descriptor{}, ErrImageNotFound
+		}
+		return v1.Descriptor{}, fmt.Errorf("%w: %q", ErrImageNotFound, ref)
+	case 1:
+		return candidates[0], nil
+	}
+	return v1.Descriptor{}, fmt.Errorf("%w: %d images", ErrAmbiguousImage, len(candidates))
+}
+
+// matchesRef returns whether d is named ref, ignoring the registry.
+func matchesRef(d v1.Descriptor, ref string) bool {
+	want, werr := name.ParseReference(ref, name.WeakValidation)
+
+	for _, n := range []string{d.Annotations[refNameAnnotation], d.Annotations[containerdNameAnnotation]} {
+		if n == "" {
+			continue
+		}
+		if n == ref {
+			return true
+		}
+
+		have, err := name.ParseReference(n, name.WeakValidation)
+		if err == nil && werr == nil &&
+			have.Context().RepositoryStr() == want.Context().RepositoryStr() &&
+			have.Identifier() == want.Identifier() {
+			return true
+		}
+	}
+	return false
+}
+
+// isLayoutAttachment returns whether d holds signatures or attestations
+// saved by cosign, rather than an image.
+func isLayoutAttachment(d v1.Descriptor) bool {
+	switch d.Annotations[cosignKindAnnotation] {
+	case cosignSigsKind, cosignAttsKind:
+		return true
+	}
+	return strings.HasPrefix(d.Annotations[refNameAnnotation], "sha256-")
+}
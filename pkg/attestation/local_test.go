@@ -0,0 +1,181 @@
+package attestation
+
+import (
+	"archive/tar"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLocalRef(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		ref    string
+		want   LocalRef
+		wantOK bool
+	}{
+		{
+			ref:    "oci:/tmp/layout",
+			want:   LocalRef{Transport: TransportOCI, Path: "/tmp/layout"},
+			wantOK: true,
+		},
+		{
+			ref:    "oci:layout:rancher/foo:v1",
+			want:   LocalRef{Transport: TransportOCI, Path: "layout", Reference: "rancher/foo:v1"},
+			wantOK: true,
+		},
+		{
+			ref:    "docker-archive:foo.tar:v1",
+			want:   LocalRef{Transport: TransportDockerArchive, Path: "foo.tar", Reference: "v1"},
+			wantOK: true,
+		},
+		{
+			ref:    "docker-daemon:rancher/foo:v1",
+			want:   LocalRef{Transport: TransportDockerDaemon, Reference: "rancher/foo:v1"},
+			wantOK: true,
+		},
+		{ref: "oci:"},
+		{ref: "docker-daemon:"},
+		{ref: "rancher/foo:v1"},
+		{ref: "localhost:5000/rancher/foo:v1"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.ref, func(t *testing.T) {
+			t.Parallel()
+
+			got, ok := ParseLocalRef(tc.ref)
+			assert.Equal(t, tc.wantOK, ok)
+			if tc.wantOK {
+				assert.Equal(t, tc.want, got)
+			}
+		})
+	}
+}
+
+func TestListLocal(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	idx := writeLayout(t, dir)
+
+	archive := filepath.Join(t.TempDir(), "images.tar")
+	writeTar(t, dir, archive)
+
+	digest, err := idx.Digest()
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		ref     string
+		wantErr error
+	}{
+		{name: "layout", ref: "oci:" + dir + ":rancher/foo:v1"},
+		{name: "layout by full name", ref: "oci:" + dir + ":registry.example.com/rancher/foo:v1"},
+		{name: "archive", ref: "docker-archive:" + archive + ":rancher/foo:v1"},
+		{name: "ambiguous", ref: "oci:" + dir, wantErr: ErrAmbiguousImage},
+		{name: "not found", ref: "oci:" + dir + ":rancher/baz:v1", wantErr: ErrImageNotFound},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			desc, got, err := list(context.Background(), tc.ref, Options{})
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, digest, desc.Digest)
+
+			sources := map[string]int{}
+			for _, a := range got {
+				sources[a.Source]++
+			}
+			assert.Equal(t, map[string]int{SourceBuildKit: 2, SourceCosign: 1}, sources)
+
+			cosign := got[len(got)-1]
+			assert.Equal(t, KindProvenance, cosign.Kind)
+			assert.Equal(t, digest.String(), cosign.Subject)
+		})
+	}
+}
+
+func TestListDockerArchive(t *testing.T) {
+	t.Parallel()
+
+	img, err := random.Image(256, 1)
+	require.NoError(t, err)
+
+	tag, err := name.NewTag("rancher/foo:v1")
+	require.NoError(t, err)
+
+	archive := filepath.Join(t.TempDir(), "foo.tar")
+	require.NoError(t, tarball.WriteToFile(archive, tag, img))
+
+	desc, got, err := list(context.Background(), "docker-archive:"+archive, Options{})
+	require.NoError(t, err)
+	assert.Empty(t, got)
+
+	digest, err := img.Digest()
+	require.NoError(t, err)
+	assert.Equal(t, digest, desc.Digest)
+}
+
+// writeLayout writes an OCI layout to dir, as cosign save does, holding a
+// BuildKit index named rancher/foo:v1 and its cosign attestations, as well
+// as a rancher/bar:v1 image.
+func writeLayout(t *testing.T, dir string) v1.ImageIndex {
+	t.Helper()
+
+	p, err := layout.Write(dir, empty.Index)
+	require.NoError(t, err)
+
+	idx := buildKitIndex(t, "amd64")
+	require.NoError(t, p.AppendIndex(idx, layout.WithAnnotations(map[string]string{
+		refNameAnnotation:        "v1",
+		containerdNameAnnotation: "docker.io/rancher/foo:v1",
+	})))
+
+	att, err := mutate.AppendLayers(empty.Image,
+		static.NewLayer(envelope(t, slsaV1, `{"from":"cosign"}`), MediaTypeDSSE))
+	require.NoError(t, err)
+	require.NoError(t, p.AppendImage(att, layout.WithAnnotations(map[string]string{
+		cosignKindAnnotation: cosignAttsKind,
+	})))
+
+	img, err := random.Image(256, 1)
+	require.NoError(t, err)
+	require.NoError(t, p.AppendImage(img, layout.WithAnnotations(map[string]string{
+		refNameAnnotation: "rancher/bar:v1",
+	})))
+
+	return idx
+}
+
+func writeTar(t *testing.T, dir, archive string) {
+	t.Helper()
+
+	f, err := os.Create(archive)
+	require.NoError(t, err)
+	defer f.Close()
+
+	tw := tar.NewWriter(f)
+	require.NoError(t, tw.AddFS(os.DirFS(dir)))
+	require.NoError(t, tw.Close())
+}
@@ -0,0 +1,166 @@
+package attestation
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+// PlatformAll selects every platform.
+const PlatformAll = "all"
+
+// Platforms holds values keyed by platform, such as linux/amd64, linux/arm/v7
+// or windows/amd64:10.0.20348.2340. The empty key holds the value for images
+// without platform information.
+//
+// It decodes the layout produced by Document, where each platform holds its
+// predicate under a single SLSA or SPDX key. The empty key is only set from
+// the top level keys when they hold a predicate of no platform.
+type Platforms[T any] map[string]T
+
+// Keys returns the platforms, sorted and without the empty key.
+func (p Platforms[T]) Keys() []string {
+	var keys []string
+	for _, k := range slices.Sorted(maps.Keys(p)) {
+		if k != "" {
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// Lookup returns the value for platform. Platforms that only partially
+// match, as PlatformSatisfies does, such as linux/arm64 for linux/arm64/v8
+// or windows/amd64 and windows/amd64:10.0.20348 for
+// windows/amd64:10.0.20348.2340, are used when there is no exact match, and
+// the value without platform information as a last resort, so that unknown
+// platforms are not found unless such a value exists.
+func (p Platforms[T]) Lookup(platform string) (T, bool) {
+	for _, k := range p.Keys() {
+		if strings.EqualFold(k, platform) {
+			return p[k], true
+		}
+	}
+
+	if spec, err := v1.ParsePlatform(strings.ToLower(platform)); err == nil {
+		for _, k := range p.Keys() {
+			have, err := v1.ParsePlatform(strings.ToLower(k))
+			if err == nil && PlatformSatisfies(*have, *spec) {
+				return p[k], true
+			}
+		}
+	}
+
+	v, ok := p[""]
+	return v, ok
+}
+
+// PlatformSatisfies reports whether have satisfies the want spec, as
+// v1.Platform.Satisfies does, except that an OS version such as 10.0.20348
+// matches any of its revisions, as in 10.0.20348.3328.
+func PlatformSatisfies(have, want v1.Platform) bool {
+	version := want.OSVersion
+	want.OSVersion = ""
+	if !have.Satisfies(want) {
+		return false
+	}
+	return version == "" || have.OSVersion == version || strings.HasPrefix(have.OSVersion, version+".")
+}
+
+// UnmarshalJSON decodes the layout produced by Document. Platforms holding
+// predicates under several keys, such as SPDX and CycloneDX, keep the first
+// of SLSA, SPDX and CycloneDX.
+//
+// As Document sets the top level keys to the last predicate found, whatever
+// its platform, they are only kept under the empty key when the document has
+// no platform keys, or when their predicate is not held by any platform.
+func (p *Platforms[T]) UnmarshalJSON(data []byte) error {
+	var doc map[string]json.RawMessage
+	err := json.Unmarshal(data, &doc)
+	if err != nil {
+		return err
+	}
+
+	out := Platforms[T]{}
+	held := map[string]bool{}
+	for k, raw := range doc {
+		// Platform keys always hold a slash, while the value without
+		// platform information is held by the predicate keys themselves.
+		if !strings.Contains(k, "/") {
+			continue
+		}
+
+		var entry map[string]json.RawMessage
+		err = json.Unmarshal(raw, &entry)
+		if err != nil {
+			return fmt.Errorf("failed to decode %s: %w", k, err)
+		}
+
+		v, ok, err := firstKey[T](entry)
+		if err != nil {
+			return fmt.Errorf("failed to decode %s: %w", k, err)
+		}
+		if ok {
+			out[k] = v
+		}
+		for _, key := range documentKeys {
+			if raw, ok := entry[key]; ok {
+				held[key+compactJSON(raw)] = true
+			}
+		}
+	}
+
+	v, ok, err := firstKey[T](doc)
+	if err != nil {
+		return err
+	}
+	if ok && (len(out) == 0 || !heldByPlatform(doc, held)) {
+		out[""] = v
+	}
+
+	*p = out
+	return nil
+}
+
+// heldByPlatform returns whether the first Document key of doc holds a
+// predicate also held by a platform, as recorded in held.
+func heldByPlatform(doc map[string]json.RawMessage, held map[string]bool) bool {
+	for _, key := range documentKeys {
+		if raw, ok := doc[key]; ok {
+			return held[key+compactJSON(raw)]
+		}
+	}
+	return false
+}
+
+// compactJSON returns raw without insignificant space, for comparison.
+func compactJSON(raw json.RawMessage) string {
+	var buf bytes.Buffer
+	if json.Compact(&buf, raw) != nil {
+		return string(raw)
+	}
+	return buf.String()
+}
+
+// firstKey decodes the value of the first Document key held by m.
+func firstKey[T any](m map[string]json.RawMessage) (T, bool, error) {
+	var v T
+	for _, key := range documentKeys {
+		raw, ok := m[key]
+		if !ok {
+			continue
+		}
+
+		err := json.Unmarshal(raw, &v)
+		if err != nil {
+			return v, false, fmt.Errorf("failed to decode %s: %w", key, err)
+		}
+		return v, true, nil
+	}
+	return v, false, nil
+}
@@ -0,0 +1,111 @@
+package attestation
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlatformsLookup(t *testing.T) {
+	t.Parallel()
+
+	p := Platforms[string]{
+		"linux/amd64":                   "amd64",
+		"linux/arm64/v8":                "arm64",
+		"linux/arm/v7":                  "armv7",
+		"linux/s390x":                   "s390x",
+		"windows/amd64:10.0.17763.6893": "ltsc2019",
+		"windows/amd64:10.0.20348.3207": "ltsc2022",
+		"":                              "default",
+	}
+
+	tests := []struct {
+		platform string
+		want     string
+	}{
+		{platform: "linux/amd64", want: "amd64"},
+		{platform: "LINUX/AMD64", want: "amd64"},
+		{platform: "linux/arm64", want: "arm64"},
+		{platform: "linux/arm64/v8", want: "arm64"},
+		{platform: "linux/arm/v7", want: "armv7"},
+		{platform: "linux/s390x", want: "s390x"},
+		{platform: "windows/amd64:10.0.20348.3207", want: "ltsc2022"},
+		{platform: "windows/amd64", want: "ltsc2019"},
+		{platform: "windows/amd64:10.0.20348", want: "ltsc2022"},
+		{platform: "windows/amd64:10.0.17763", want: "ltsc2019"},
+		{platform: "windows/amd64:10.0.2034", want: "default"},
+		{platform: "linux/ppc64le", want: "default"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.platform, func(t *testing.T) {
+			t.Parallel()
+
+			got, ok := p.Lookup(tc.platform)
+			assert.True(t, ok)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+
+	_, ok := Platforms[string]{"linux/amd64": "amd64"}.Lookup("linux/ppc64le")
+	assert.False(t, ok)
+}
+
+func TestPlatformsUnmarshalJSON(t *testing.T) {
+	t.Parallel()
+
+	atts := []Attestation{
+		{Kind: KindSBOM, Platform: "linux/arm/v7", Statement: json.RawMessage(`{"predicate":{"arch":"arm"}}`)},
+		{Kind: KindSBOM, Platform: "windows/amd64:10.0.20348.3207", Statement: json.RawMessage(`{"predicate":{"arch":"win"}}`)},
+	}
+
+	doc, err := Document(atts, KindSBOM)
+	require.NoError(t, err)
+
+	data, err := json.Marshal(doc)
+	require.NoError(t, err)
+
+	var got Platforms[map[string]string]
+	require.NoError(t, json.Unmarshal(data, &got))
+
+	assert.Equal(t, []string{"linux/arm/v7", "windows/amd64:10.0.20348.3207"}, got.Keys())
+	assert.Equal(t, map[string]string{"arch": "arm"}, got["linux/arm/v7"])
+	assert.Equal(t, map[string]string{"arch": "win"}, got["windows/amd64:10.0.20348.3207"])
+
+	// Unknown platforms do not get the predicate of another platform.
+	assert.NotContains(t, got, "")
+	_, ok := got.Lookup("linux/ppc64le")
+	assert.False(t, ok)
+
+	// The predicate of an attestation without platform is kept.
+	atts = append(atts, Attestation{Kind: KindSBOM, Statement: json.RawMessage(`{"predicate":{"arch":"none"}}`)})
+	doc, err = Document(atts, KindSBOM)
+	require.NoError(t, err)
+	data, err = json.Marshal(doc)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &got))
+
+	found, ok := got.Lookup("linux/ppc64le")
+	assert.True(t, ok)
+	assert.Equal(t, map[string]string{"arch": "none"}, found)
+
+	// Documents without platforms only hold the top level keys.
+	var single Platforms[map[string]string]
+	require.NoError(t, json.Unmarshal([]byte(`{"SPDX": {"arch":"amd64"}}`), &single))
+	assert.Equal(t, Platforms[map[string]string]{"": {"arch": "amd64"}}, single)
+
+	// SPDX is preferred over CycloneDX.
+	var both Platforms[map[string]string]
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"linux/amd64": {"CycloneDX": {"f":"cdx"}, "SPDX": {"f":"spdx"}},
+		"linux/arm64": {"CycloneDX": {"f":"cdx"}},
+		"CycloneDX": {"f":"cdx"},
+		"SPDX": {"f":"spdx"}
+	}`), &both))
+	assert.Equal(t, Platforms[map[string]string]{
+		"linux/amd64": {"f": "spdx"},
+		"linux/arm64": {"f": "cdx"},
+	}, both)
+}
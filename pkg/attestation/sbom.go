@@ -0,0 +1,77 @@
+package attestation
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Formats of SBOM attestations.
+const (
+	FormatSPDX      = "spdx"
+	FormatCycloneDX = "cyclonedx"
+)
+
+// Keys holding the predicates in the layout produced by Document.
+const (
+	KeySLSA      = "SLSA"
+	KeySPDX      = "SPDX"
+	KeyCycloneDX = "CycloneDX"
+)
+
+// documentKeys are the Document keys, by order of preference.
+var documentKeys = []string{KeySLSA, KeySPDX, KeyCycloneDX}
+
+// SBOMFormat returns the format of an SBOM attestation, FormatSPDX or
+// FormatCycloneDX, from its predicate type or, for generic predicate types,
+// from its content. It returns empty for other attestations.
+func (a Attestation) SBOMFormat() string {
+	if a.Kind != KindSBOM {
+		return ""
+	}
+
+	pt := strings.ToLower(a.PredicateType)
+	switch {
+	case strings.Contains(pt, FormatCycloneDX):
+		return FormatCycloneDX
+	case strings.Contains(pt, FormatSPDX):
+		return FormatSPDX
+	}
+
+	predicate, err := a.Predicate()
+	if err != nil {
+		return ""
+	}
+	return DetectSBOMFormat(predicate)
+}
+
+// DetectSBOMFormat returns the format of the JSON SBOM document doc,
+// FormatSPDX or FormatCycloneDX, from its content. It returns empty for
+// other documents.
+func DetectSBOMFormat(doc []byte) string {
+	var d struct {
+		BOMFormat   string `json:"bomFormat"`
+		SPDXVersion string `json:"spdxVersion"`
+	}
+	if json.Unmarshal(doc, &d) != nil {
+		return ""
+	}
+
+	switch {
+	case strings.EqualFold(d.BOMFormat, "CycloneDX"):
+		return FormatCycloneDX
+	case d.SPDXVersion != "":
+		return FormatSPDX
+	}
+	return ""
+}
+
+// documentKey returns the Document key holding the predicate of a.
+func documentKey(a Attestation) string {
+	if a.Kind != KindSBOM {
+		return KeySLSA
+	}
+	if a.SBOMFormat() == FormatCycloneDX {
+		return KeyCycloneDX
+	}
+	return KeySPDX
+}
@@ -0,0 +1,52 @@
+package attestation
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSBOMFormat(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		kind          string
+		predicateType string
+		predicate     string
+		want          string
+	}{
+		{name: "SPDX", kind: KindSBOM, predicateType: "https://spdx.dev/Document", want: FormatSPDX},
+		{name: "CycloneDX", kind: KindSBOM, predicateType: "https://cyclonedx.org/bom", want: FormatCycloneDX},
+		{name: "CycloneDX versioned", kind: KindSBOM, predicateType: "https://cyclonedx.org/bom/v1.6", want: FormatCycloneDX},
+		{
+			name: "generic CycloneDX", kind: KindSBOM, predicateType: "https://example.com/sbom",
+			predicate: `{"bomFormat":"CycloneDX","specVersion":"1.6"}`, want: FormatCycloneDX,
+		},
+		{
+			name: "generic SPDX", kind: KindSBOM, predicateType: "https://example.com/sbom",
+			predicate: `{"spdxVersion":"SPDX-2.3"}`, want: FormatSPDX,
+		},
+		{name: "generic unknown", kind: KindSBOM, predicateType: "https://example.com/sbom", predicate: `{}`},
+		{name: "provenance", kind: KindProvenance, predicateType: "https://slsa.dev/provenance/v1"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			predicate := tc.predicate
+			if predicate == "" {
+				predicate = "{}"
+			}
+
+			a := Attestation{
+				Kind:          tc.kind,
+				PredicateType: tc.predicateType,
+				Statement:     json.RawMessage(`{"predicate":` + predicate + `}`),
+			}
+			assert.Equal(t, tc.want, a.SBOMFormat())
+		})
+	}
+}
@@ -2,12 +2,193 @@ package imagecopy
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
 
+	"github.com/google/go-containerregistry/pkg/authn"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/rancherlabs/slsactl/internal/imagelist"
 )
 
-// ErrNoSignaturesFound indicates no signature was found for the image.
-var ErrNoSignaturesFound = imagelist.ErrNoSignaturesFound
+var (
+	// ErrNoSignaturesFound indicates no signature was found for the image.
+	ErrNoSignaturesFound = imagelist.ErrNoSignaturesFound
+	// ErrTagConflict indicates the destination tag points to a different digest.
+	ErrTagConflict = imagelist.ErrTagConflict
+	// ErrNoMatchingPlatform indicates none of the images of an index match
+	// the requested platforms.
+	ErrNoMatchingPlatform = imagelist.ErrNoMatchingPlatform
+)
+
+// ConflictPolicy defines how to handle destination tags that point to a
+// different digest than their source.
+type ConflictPolicy = imagelist.ConflictPolicy
+
+const (
+	// ConflictSkip leaves the destination tag untouched and reports the conflict.
+	ConflictSkip = imagelist.ConflictSkip
+	// ConflictFail reports the conflict as an error.
+	ConflictFail = imagelist.ConflictFail
+	// ConflictOverwrite replaces the destination tag with the source artifact.
+	ConflictOverwrite = imagelist.ConflictOverwrite
+)
+
+// Kinds of artifacts reported in a Result.
+const (
+	KindImage       = imagelist.ArtifactImage
+	KindSignature   = imagelist.ArtifactSignature
+	KindAttestation = imagelist.ArtifactAttestation
+	KindSBOM        = imagelist.ArtifactSBOM
+	KindReferrer    = imagelist.ArtifactReferrer
+)
+
+// Status of each artifact reported in a Result.
+const (
+	StatusExists      = imagelist.StatusExists
+	StatusConflict    = imagelist.StatusConflict
+	StatusCopied      = imagelist.StatusCopied
+	StatusOverwritten = imagelist.StatusOverwritten
+)
+
+// Options tunes how images are copied.
+type Options struct {
+	// Keychain used to authenticate against the source and target registries.
+	// Defaults to authn.DefaultKeychain.
+	Keychain authn.Keychain
+	// Platforms restricts the copy of multi-platform images to the matching
+	// platforms. As the copied index differs from its source, the signatures
+	// of each platform image are copied instead of the index signature.
+	Platforms []v1.Platform
+	// SignaturesOnly copies the signatures without the image.
+	SignaturesOnly bool
+	// Attestations copies the cosign attestations, attached SBOMs and OCI
+	// referrers of the image.
+	Attestations bool
+	// OnConflict defines how to handle destination tags that point to a
+	// different digest. Defaults to ConflictSkip.
+	OnConflict ConflictPolicy
+	// Jobs limits the concurrent blob transfers of each copy.
+	Jobs int
+	// Concurrency limits the images copied at the same time by CopyBatch.
+	// Defaults to 1.
+	Concurrency int
+}
+
+// Request is a single copy within a CopyBatch.
+type Request struct {
+	Source string
+	Target string
+}
+
+// Result describes the outcome of copying an image.
+type Result struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	// Digest of the source image.
+	Digest    string     `json:"digest,omitempty"`
+	Artifacts []Artifact `json:"artifacts,omitempty"`
+	// Error is set by CopyBatch when the copy failed.
+	Error error `json:"error,omitempty"`
+}
+
+// MarshalJSON encodes r with its Error as the error message, which would
+// otherwise be encoded as an empty object.
+func (r Result) MarshalJSON() ([]byte, error) {
+	type result Result
+	var msg string
+	if r.Error != nil {
+		msg = r.Error.Error()
+	}
+
+	return json.Marshal(struct {
+		result
+		Error string `json:"error,omitempty"`
+	}{result(r), msg})
+}
+
+// Artifact is an image, signature, attestation, SBOM or referrer handled
+// by a copy.
+type Artifact struct {
+	Kind   string `json:"kind"`
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Digest string `json:"digest,omitempty"`
+	Status string `json:"status,omitempty"`
+}
+
+// Copy copies the source image with its cosign signature, and optionally its
+// attestations, to target. The source and target must be fully qualified image
+// references with the same tag or digest. The returned result is never nil and
+// lists the artifacts handled before any error.
+//
+// Example:
+//
+//	res, err := imagecopy.Copy(ctx,
+//	    "stgregistry.suse.com/rancher/fleet-agent:v0.13.0",
+//	    "localhost:5000/rancher/fleet-agent:v0.13.0",
+//	    imagecopy.Options{Attestations: true},
+//	)
+func Copy(ctx context.Context, source, target string, opts Options) (*Result, error) {
+	report, err := imagelist.CopySignatureWithOptions(ctx, source, target, imagelist.CopyOptions{
+		CopyImage:    !opts.SignaturesOnly,
+		OnConflict:   opts.OnConflict,
+		Jobs:         opts.Jobs,
+		Keychain:     opts.Keychain,
+		Platforms:    opts.Platforms,
+		Attestations: opts.Attestations,
+	})
+
+	res := &Result{
+		Source: source,
+		Target: target,
+		Digest: report.SourceDigest,
+	}
+	for _, a := range report.Artifacts {
+		res.Artifacts = append(res.Artifacts, Artifact(a))
+	}
+
+	if err != nil {
+		return res, fmt.Errorf("failed to copy %q: %w", source, err)
+	}
+	return res, nil
+}
+
+// CopyBatch copies each request as Copy would, running up to opts.Concurrency
+// copies at the same time. Results are returned in the order of reqs, and
+// the errors of all failed copies are joined into the returned error.
+func CopyBatch(ctx context.Context, reqs []Request, opts Options) ([]Result, error) {
+	concurrency := max(opts.Concurrency, 1)
+
+	results := make([]Result, len(reqs))
+	sem := make(chan struct{}, concurrency)
+
+	var wg sync.WaitGroup
+	for i, req := range reqs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			res, err := Copy(ctx, req.Source, req.Target, opts)
+			res.Error = err
+			results[i] = *res
+		}()
+	}
+	wg.Wait()
+
+	var errs []error
+	for _, res := range results {
+		if res.Error != nil {
+			errs = append(errs, res.Error)
+		}
+	}
+
+	return results, errors.Join(errs...)
+}
 
 // ImageAndSignature copies a single container image with its cosign signature from source
 // to target registry. The source and target must be fully qualified image references
@@ -0,0 +1,266 @@
+package imagecopy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-containerregistry/pkg/crane"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCopy(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		opts      Options
+		wantKinds []string
+		platform  bool
+		wantImage bool
+	}{
+		{
+			name:      "image and signature",
+			wantKinds: []string{KindImage, KindSignature},
+			wantImage: true,
+		},
+		{
+			name:      "signatures only",
+			opts:      Options{SignaturesOnly: true},
+			wantKinds: []string{KindSignature},
+		},
+		{
+			name:      "with attestations",
+			opts:      Options{Attestations: true},
+			wantKinds: []string{KindImage, KindSignature, KindReferrer},
+			wantImage: true,
+		},
+		{
+			name: "single platform with attestations",
+			opts: Options{
+				Attestations: true,
+				Platforms:    []v1.Platform{{OS: "linux", Architecture: "amd64"}},
+			},
+			wantKinds: []string{KindImage, KindSignature, KindAttestation},
+			platform:  true,
+			wantImage: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			src := newTestRegistry(t) + "/rancher/mirrored-foo:v1"
+			dst := newTestRegistry(t) + "/rancher/mirrored-foo:v1"
+			idx := pushSignedIndex(t, src)
+
+			got, err := Copy(context.Background(), src, dst, tc.opts)
+			require.NoError(t, err)
+
+			idxDigest, err := idx.Digest()
+			require.NoError(t, err)
+			assert.Equal(t, idxDigest.String(), got.Digest)
+
+			var kinds []string
+			for _, a := range got.Artifacts {
+				kinds = append(kinds, a.Kind)
+				assert.Equal(t, StatusCopied, a.Status, a.Source)
+			}
+			assert.Equal(t, tc.wantKinds, kinds)
+
+			dstDigest, err := crane.Digest(dst)
+			if !tc.wantImage {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+
+			m, err := crane.Manifest(dst)
+			require.NoError(t, err)
+			idxManifest, err := v1.ParseIndexManifest(strings.NewReader(string(m)))
+			require.NoError(t, err)
+
+			if tc.platform {
+				assert.NotEqual(t, idxDigest.String(), dstDigest)
+				require.Len(t, idxManifest.Manifests, 1)
+				assert.Equal(t, "amd64", idxManifest.Manifests[0].Platform.Architecture)
+			} else {
+				assert.Equal(t, idxDigest.String(), dstDigest)
+				assert.Len(t, idxManifest.Manifests, 2)
+			}
+
+			// A second copy has nothing left to do.
+			again, err := Copy(context.Background(), src, dst, tc.opts)
+			require.NoError(t, err)
+			for _, a := range again.Artifacts {
+				assert.Equal(t, StatusExists, a.Status, a.Source)
+			}
+		})
+	}
+}
+
+func TestCopyNoMatchingPlatform(t *testing.T) {
+	t.Parallel()
+
+	src := newTestRegistry(t) + "/rancher/mirrored-foo:v1"
+	dst := newTestRegistry(t) + "/rancher/mirrored-foo:v1"
+	pushSignedIndex(t, src)
+
+	_, err := Copy(context.Background(), src, dst, Options{
+		Platforms: []v1.Platform{{OS: "windows", Architecture: "amd64"}},
+	})
+	require.ErrorIs(t, err, ErrNoMatchingPlatform)
+}
+
+func TestCopyBatch(t *testing.T) {
+	t.Parallel()
+
+	srcRegistry := newTestRegistry(t)
+	dstRegistry := newTestRegistry(t)
+
+	signed := srcRegistry + "/rancher/mirrored-foo:v1"
+	pushSignedIndex(t, signed)
+
+	unsigned := srcRegistry + "/rancher/mirrored-bar:v1"
+	img, err := random.Image(1024, 1)
+	require.NoError(t, err)
+	require.NoError(t, crane.Push(img, unsigned))
+
+	reqs := []Request{
+		{Source: unsigned, Target: dstRegistry + "/rancher/mirrored-bar:v1"},
+		{Source: signed, Target: dstRegistry + "/rancher/mirrored-foo:v1"},
+	}
+
+	got, err := CopyBatch(context.Background(), reqs, Options{Concurrency: 2})
+	require.ErrorIs(t, err, ErrNoSignaturesFound)
+	require.Len(t, got, 2)
+
+	assert.Equal(t, unsigned, got[0].Source)
+	require.ErrorIs(t, got[0].Error, ErrNoSignaturesFound)
+
+	assert.Equal(t, signed, got[1].Source)
+	require.NoError(t, got[1].Error)
+	assert.Len(t, got[1].Artifacts, 2)
+}
+
+func newTestRegistry(t *testing.T) string {
+	t.Helper()
+
+	srv := httptest.NewServer(registry.New(
+		registry.Logger(log.New(io.Discard, "", 0)),
+		registry.WithReferrersSupport(true)))
+	t.Cleanup(srv.Close)
+
+	return strings.TrimPrefix(srv.URL, "http://")
+}
+
+// pushSignedIndex pushes a linux/amd64 and linux/arm64 index to ref with
+// signatures for the index and each platform image, an attestation for the
+// amd64 image and a referrer of the index.
+func pushSignedIndex(t *testing.T, ref string) v1.ImageIndex {
+	t.Helper()
+
+	repo, err := name.NewRepository(strings.Split(ref, ":v")[0])
+	require.NoError(t, err)
+
+	var adds []mutate.IndexAddendum
+	for _, arch := range []string{"amd64", "arm64"} {
+		img, err := random.Image(1024, 1)
+		require.NoError(t, err)
+
+		adds = append(adds, mutate.IndexAddendum{
+			Add: img,
+			Descriptor: v1.Descriptor{
+				Platform: &v1.Platform{OS: "linux", Architecture: arch},
+			},
+		})
+	}
+
+	idx := mutate.AppendManifests(empty.Index, adds...)
+	require.NoError(t, remote.WriteIndex(mustTag(t, ref), idx))
+
+	tag := func(d v1.Hash, suffix string) string {
+		return repo.Tag(strings.Replace(d.String(), ":", "-", 1) + suffix).String()
+	}
+
+	idxDigest, err := idx.Digest()
+	require.NoError(t, err)
+	pushRandom(t, tag(idxDigest, ".sig"))
+
+	m, err := idx.IndexManifest()
+	require.NoError(t, err)
+	for _, d := range m.Manifests {
+		pushRandom(t, tag(d.Digest, ".sig"))
+		if d.Platform.Architecture == "amd64" {
+			pushRandom(t, tag(d.Digest, ".att"))
+		}
+	}
+
+	desc, err := remote.Head(repo.Digest(idxDigest.String()))
+	require.NoError(t, err)
+
+	img, err := random.Image(256, 1)
+	require.NoError(t, err)
+	referrer := mutate.Subject(img, *desc).(v1.Image)
+	referrerDigest, err := referrer.Digest()
+	require.NoError(t, err)
+	require.NoError(t, remote.Write(repo.Digest(referrerDigest.String()), referrer))
+
+	return idx
+}
+
+func pushRandom(t *testing.T, ref string) {
+	t.Helper()
+
+	img, err := random.Image(256, 1)
+	require.NoError(t, err)
+	require.NoError(t, crane.Push(img, ref))
+}
+
+func mustTag(t *testing.T, ref string) name.Tag {
+	t.Helper()
+
+	tag, err := name.NewTag(ref)
+	require.NoError(t, err)
+	return tag
+}
+
+func TestResultMarshalJSON(t *testing.T) {
+	t.Parallel()
+
+	res := Result{
+		Source: "registry.example.com/rancher/foo:v1",
+		Target: "localhost:5000/rancher/foo:v1",
+		Error:  errors.New("failed to copy"),
+	}
+
+	got, err := json.Marshal(res)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"source": "registry.example.com/rancher/foo:v1",
+		"target": "localhost:5000/rancher/foo:v1",
+		"error": "failed to copy"
+	}`, string(got))
+
+	res.Error = nil
+	got, err = json.Marshal([]Result{res})
+	require.NoError(t, err)
+	assert.JSONEq(t, `[{
+		"source": "registry.example.com/rancher/foo:v1",
+		"target": "localhost:5000/rancher/foo:v1"
+	}]`, string(got))
+}
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/google/go-containerregistry/pkg/logs"
+	"github.com/rancherlabs/slsactl/pkg/attestation"
 	"github.com/rancherlabs/slsactl/pkg/internal"
 	"github.com/rancherlabs/slsactl/pkg/internal/appco"
 	"github.com/rancherlabs/slsactl/pkg/internal/gcp"
@@ -21,26 +22,32 @@ import (
 var (
 	// ErrNoVerifierFound will be returned by Verify when no verifiers match the provided image.
 	ErrNoVerifierFound = errors.New("no verifier found for image")
+	// ErrLocalSignaturesUnsupported will be returned by Verify for local images that cannot hold signatures.
+	ErrLocalSignaturesUnsupported = errors.New("signatures are only supported for OCI layouts")
+	// ErrLocalImageNameRequired will be returned by Verify for OCI layouts referenced without the image name,
+	// which is needed to select the verifier.
+	ErrLocalImageNameRequired = errors.New("image name required: use oci:<dir>:<image>")
 
-	cosignVerifier = &cosignImplementation{}
-
-	verifiers = []internal.Verifier{
-		&obs.Verifier{
-			HashAlgorithm:    hashAlgo,
-			UpstreamVerifier: cosignVerifier,
-		},
-		&appco.Verifier{
-			HashAlgorithm:    hashAlgo,
-			UpstreamVerifier: cosignVerifier,
-		},
-		&gcp.Verifier{
-			HashAlgorithm:    hashAlgo,
-			UpstreamVerifier: cosignVerifier,
-		},
-		&gha.Verifier{
-			HashAlgorithm:    hashAlgo,
-			UpstreamVerifier: cosignVerifier,
-		},
+	// newVerifiers returns the verifiers, which verify signatures through upstream.
+	newVerifiers = func(upstream internal.UpstreamVerifier) []internal.Verifier {
+		return []internal.Verifier{
+			&obs.Verifier{
+				HashAlgorithm:    hashAlgo,
+				UpstreamVerifier: upstream,
+			},
+			&appco.Verifier{
+				HashAlgorithm:    hashAlgo,
+				UpstreamVerifier: upstream,
+			},
+			&gcp.Verifier{
+				HashAlgorithm:    hashAlgo,
+				UpstreamVerifier: upstream,
+			},
+			&gha.Verifier{
+				HashAlgorithm:    hashAlgo,
+				UpstreamVerifier: upstream,
+			},
+		}
 	}
 
 	timeout  = 45 * time.Second
@@ -50,6 +57,9 @@ var (
 // Verify checks whether a given Rancher Prime image is signed based on the Cosign Signature spec.
 // The same extents to CNCF images within the Rancher ecosystem.
 //
+// OCI layouts saved by cosign save are verified locally, when referenced
+// as oci:<dir>:<image>. The image name selects the verifier.
+//
 // Upstream documentation:
 // https://github.com/sigstore/cosign/blob/main/specs/SIGNATURE_SPEC.md
 func Verify(image string) error {
@@ -60,9 +70,22 @@ func Verify(image string) error {
 		logs.Debug.SetOutput(os.Stderr)
 	}
 
+	upstream := &cosignImplementation{}
+	if l, ok := attestation.ParseLocalRef(image); ok {
+		if l.Transport != attestation.TransportOCI {
+			return fmt.Errorf("%w: %q", ErrLocalSignaturesUnsupported, image)
+		}
+		if l.Reference == "" {
+			return fmt.Errorf("%w: %q", ErrLocalImageNameRequired, image)
+		}
+
+		upstream.localPath = l.Path
+		image = l.Reference
+	}
+
 	var matched []internal.Verifier
 
-	for _, v := range verifiers {
+	for _, v := range newVerifiers(upstream) {
 		if v.Matches(image) {
 			matched = append(matched, v)
 		}
@@ -84,8 +107,15 @@ func Verify(image string) error {
 	return lastErr
 }
 
-type cosignImplementation struct{}
+type cosignImplementation struct {
+	// localPath is the OCI layout holding the image, when verified locally.
+	localPath string
+}
 
-func (*cosignImplementation) Verify(ctx context.Context, vc cosignCmd.VerifyCommand, image string) error {
+func (c *cosignImplementation) Verify(ctx context.Context, vc cosignCmd.VerifyCommand, image string) error {
+	if c.localPath != "" {
+		vc.LocalImage = true
+		image = c.localPath
+	}
 	return vc.Exec(ctx, []string{image})
 }
@@ -18,6 +18,7 @@ func TestVerify(t *testing.T) {
 		name      string
 		image     string
 		verifiers func() ([]internal.Verifier, func(t *testing.T))
+		wantPath  string
 		wantErr   error
 	}{
 		{
@@ -58,16 +59,53 @@ func TestVerify(t *testing.T) {
 				}
 			},
 		},
+		{
+			name:  "Matching verifier for OCI layout",
+			image: "oci:/tmp/layout:suse/sles",
+			verifiers: func() ([]internal.Verifier, func(t *testing.T)) {
+				m1 := &verifierMock{}
+
+				m1.On("Matches", "suse/sles").Return(true)
+				m1.On("Verify", mock.Anything, "suse/sles").Return(nil)
+
+				return []internal.Verifier{m1}, func(t *testing.T) {
+					t.Helper()
+					m1.AssertExpectations(t)
+				}
+			},
+			wantPath: "/tmp/layout",
+		},
+		{
+			name:  "OCI layout without image name",
+			image: "oci:/tmp/layout",
+			verifiers: func() ([]internal.Verifier, func(t *testing.T)) {
+				return nil, empty
+			},
+			wantErr: ErrLocalImageNameRequired,
+		},
+		{
+			name:  "Docker archive",
+			image: "docker-archive:/tmp/foo.tar:suse/sles",
+			verifiers: func() ([]internal.Verifier, func(t *testing.T)) {
+				return nil, empty
+			},
+			wantErr: ErrLocalSignaturesUnsupported,
+		},
 	}
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
 			v, a := tc.verifiers()
-			verifiers = v
+			var upstream internal.UpstreamVerifier
+			newVerifiers = func(u internal.UpstreamVerifier) []internal.Verifier {
+				upstream = u
+				return v
+			}
 
 			err := Verify(tc.image)
 			if tc.wantErr == nil {
 				require.NoError(t, err)
+				require.Equal(t, &cosignImplementation{localPath: tc.wantPath}, upstream)
 			} else {
 				require.ErrorContains(t, err, tc.wantErr.Error())
 			}